@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Bash completion script (adapted from urfave/cli's autocomplete scripts) - completions are requested from the app via its hidden '--generate-bash-completion' flag.
+// 'PROG' is replaced with the app's name.
+var bashCompletion = `_PROG_bash_autocomplete() {
+  if [[ "${COMP_WORDS[0]}" != "source" ]]; then
+    local cur opts words cword
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    words=("${COMP_WORDS[@]:0:$COMP_CWORD}")
+    if [[ "$cur" == "-"* ]]; then
+      opts=$("${words[@]}" "${cur}" --generate-bash-completion 2>/dev/null)
+    else
+      opts=$("${words[@]}" --generate-bash-completion 2>/dev/null)
+    fi
+    COMPREPLY=($(compgen -W "${opts}" -- "${cur}"))
+    return 0
+  fi
+}
+
+complete -o bashdefault -o default -o nospace -F _PROG_bash_autocomplete PROG
+`
+
+// Zsh completion script (adapted from urfave/cli's autocomplete scripts) - see [bashCompletion]
+var zshCompletion = `#compdef PROG
+
+_PROG_zsh_autocomplete() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion)}")
+  else
+    opts=("${(@f)$(${words[@]:0:#words[@]-1} --generate-bash-completion)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _PROG_zsh_autocomplete PROG
+`
+
+// Shells supported by [completionAction]
+var completionShells = []string{"bash", "fish", "zsh"}
+
+// Prints the completion script for the shell given as the command's argument.
+// Bash and zsh scripts query the app for completions at runtime - the fish script is generated from the app's commands and flags.
+func completionAction(c *cli.Context) error {
+	sh := c.Args().First()
+	n := c.App.Name
+	s := ""
+	switch sh {
+	case "bash":
+		s = strings.ReplaceAll(bashCompletion, "PROG", n)
+	case "fish":
+		fs, err := c.App.ToFishCompletion()
+		if err != nil {
+			return err
+		}
+		s = fs
+	case "zsh":
+		s = strings.ReplaceAll(zshCompletion, "PROG", n)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (expected one of %s)", sh, strings.Join(completionShells, ", "))
+	}
+	fmt.Fprint(c.App.Writer, s)
+	return nil
+}
+
+// Completes the completion command's argument with the supported shells
+func completionComplete(c *cli.Context) {
+	if c.NArg() > 0 {
+		return
+	}
+	for _, sh := range completionShells {
+		fmt.Fprintln(c.App.Writer, sh)
+	}
+}
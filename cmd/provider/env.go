@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// The prefix of the environment variables read by the flags within [runFlags]
+const defaultEnvPrefix = "EXTERNAL_DNS_ROUTEROS_PROVIDER_"
+
+// Matches valid environment variable prefixes
+var envPrefixRegex = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// Additionally reads the flags within [runFlags] from environment variables with the given prefix (e.g., 'ROUTEROS_' reads 'ROUTEROS_ROUTEROS_ADDRESS') - which take precedence over the default environment variables (see [defaultEnvPrefix]).
+// A trailing underscore is appended to the prefix if missing.
+// Must be called before command flags are parsed (e.g., within [cli.App.Before]).
+// Returns an error if the prefix is invalid.
+func applyEnvPrefix(p string) error {
+	if !envPrefixRegex.MatchString(p) {
+		return fmt.Errorf("env prefix %s invalid (expected uppercase letters, digits and underscores)", p)
+	}
+	if !strings.HasSuffix(p, "_") {
+		p += "_"
+	}
+	if p == defaultEnvPrefix {
+		return nil
+	}
+	for _, f := range runFlags {
+		var evs *[]string
+		switch tf := f.(type) {
+		case *cli.BoolFlag:
+			evs = &tf.EnvVars
+		case *cli.DurationFlag:
+			evs = &tf.EnvVars
+		case *cli.StringFlag:
+			evs = &tf.EnvVars
+		case *cli.StringSliceFlag:
+			evs = &tf.EnvVars
+		case *cli.UintFlag:
+			evs = &tf.EnvVars
+		default:
+			return fmt.Errorf("flag %s type %T unsupported", f.Names()[0], f)
+		}
+		aevs := []string{}
+		for _, ev := range *evs {
+			n, ok := strings.CutPrefix(ev, defaultEnvPrefix)
+			if ok {
+				aevs = append(aevs, p+n)
+			}
+		}
+		*evs = append(aevs, *evs...)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats supported by commands accepting the [outputFlag].
+// 'human' is colorized (when writing to a terminal) - the remaining formats are intended for scripting.
+var outputFormats = []string{"human", "json", "table", "yaml"}
+
+// Returns the flag selecting a command's output format (see [outputFormats])
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "output",
+		Usage: fmt.Sprintf("output format ('%s')", strings.Join(outputFormats, "' | '")),
+		Value: "human",
+	}
+}
+
+// ANSI escape codes used by [outputWriter.colorize]
+const (
+	colorBold  = "\033[1m"
+	colorGreen = "\033[32m"
+	colorRed   = "\033[31m"
+	colorReset = "\033[0m"
+	colorCyan  = "\033[36m"
+)
+
+// Writes command output using the format selected via the [outputFlag]
+type outputWriter struct {
+	color  bool
+	format string
+	writer io.Writer
+}
+
+// Creates an [outputWriter] for the given command.
+// Colors are only used by the 'human' format when writing to a terminal - and never when the NO_COLOR environment variable is set.
+// Returns an error if the output format is unrecognized.
+func newOutputWriter(c *cli.Context) (*outputWriter, error) {
+	f := c.String("output")
+	if !slices.Contains(outputFormats, f) {
+		return nil, fmt.Errorf("unrecognized output format %s", f)
+	}
+	ow := &outputWriter{format: f, writer: c.App.Writer}
+	if f == "human" && os.Getenv("NO_COLOR") == "" {
+		fi, ok := c.App.Writer.(*os.File)
+		if ok {
+			st, err := fi.Stat()
+			ow.color = err == nil && st.Mode()&os.ModeCharDevice != 0
+		}
+	}
+	return ow, nil
+}
+
+// Wraps the string with the given ANSI escape code - if colors are enabled
+func (ow *outputWriter) colorize(cl string, s string) string {
+	if !ow.color {
+		return s
+	}
+	return cl + s + colorReset
+}
+
+// Writes the output.
+// The 'json' and 'yaml' formats encode the data - yaml keys match the data's json keys.
+// The 'table' format writes the rows (aligned in columns) beneath the header - the 'human' format additionally colorizes cells (via the optional callback, receiving the row and column index and the padded value).
+func (ow *outputWriter) write(data any, h []string, rs [][]string, cb func(r int, c int, v string) string) error {
+	switch ow.format {
+	case "json":
+		e := json.NewEncoder(ow.writer)
+		e.SetIndent("", "  ")
+		return e.Encode(data)
+	case "yaml":
+		bs, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		var v any
+		err = yaml.Unmarshal(bs, &v)
+		if err != nil {
+			return err
+		}
+		bs, err = yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = ow.writer.Write(bs)
+		return err
+	}
+	ws := make([]int, len(h))
+	for _, r := range append([][]string{h}, rs...) {
+		for ci, v := range r {
+			ws[ci] = max(ws[ci], len(v))
+		}
+	}
+	// cells are padded before being colorized - as escape codes would otherwise misalign columns
+	pad := func(r []string, cb func(c int, v string) string) string {
+		vs := []string{}
+		for ci, v := range r {
+			if ci < len(r)-1 {
+				v += strings.Repeat(" ", ws[ci]-len(v))
+			}
+			vs = append(vs, cb(ci, v))
+		}
+		return strings.Join(vs, "  ")
+	}
+	hm := ow.format == "human"
+	_, err := fmt.Fprintln(ow.writer, pad(h, func(c int, v string) string {
+		if hm {
+			return ow.colorize(colorBold, v)
+		}
+		return v
+	}))
+	if err != nil {
+		return err
+	}
+	for ri, r := range rs {
+		_, err := fmt.Fprintln(ow.writer, pad(r, func(c int, v string) string {
+			if hm && cb != nil {
+				return cb(ri, c, v)
+			}
+			return v
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
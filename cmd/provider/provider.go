@@ -2,58 +2,929 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"regexp"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/benfiola/external-dns-routeros-provider/internal/provider"
 	"github.com/urfave/cli/v2"
+	"sigs.k8s.io/external-dns/endpoint"
 )
 
 // Configures logging for the application.
-// Accepts a logging level 'error' | 'warn' | 'info' | 'debug'
-func configureLogging(ls string) (*slog.Logger, error) {
-	if ls == "" {
-		ls = "info"
-	}
-	var l slog.Level
-	switch ls {
-	case "error":
-		l = slog.LevelError
-	case "warn":
-		l = slog.LevelWarn
-	case "info":
-		l = slog.LevelInfo
-	case "debug":
-		l = slog.LevelDebug
-	default:
-		return nil, fmt.Errorf("unrecognized log level %s", ls)
+// Accepts a logging level 'error' | 'warn' | 'info' | 'debug'.
+// Returns the [provider.LogLevel] used by the logger - allowing the level to be changed at runtime.
+func configureLogging(ls string) (*slog.Logger, *provider.LogLevel, error) {
+	l, err := provider.ParseLogLevel(ls)
+	if err != nil {
+		return nil, nil, err
 	}
+	ll := provider.NewLogLevel(l)
 
 	opts := &slog.HandlerOptions{
-		Level: l,
+		Level: ll,
 	}
 	handler := slog.NewTextHandler(os.Stderr, opts)
 	logger := slog.New(handler)
-	return logger, nil
+	return logger, ll, nil
 }
 
 // Used as a key to the urfave/cli context to store the application-level logger.
 type ContextLogger struct{}
 
-func main() {
-	err := (&cli.App{
+// Used as a key to the urfave/cli context to store the application-level [provider.LogLevel].
+type ContextLogLevel struct{}
+
+// Flags shared by commands that start the provider webhook server
+var runFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:    "adopt-existing",
+		Usage:   "adopt existing unmanaged records matching records being created - rather than creating duplicates (requires the 'comment' metadata store)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ADOPT_EXISTING"},
+	},
+	&cli.DurationFlag{
+		Name:    "apply-batch-delay",
+		Usage:   "delay between the batches of a sync split by --apply-batch-size",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_APPLY_BATCH_DELAY"},
+	},
+	&cli.UintFlag{
+		Name:    "apply-batch-size",
+		Usage:   "when non-zero, splits syncs into batches of at most this many changes - a sync stops at the first batch that fails",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_APPLY_BATCH_SIZE"},
+	},
+	&cli.UintFlag{
+		Name:    "apply-concurrency",
+		Usage:   "maximum number of dns names updated concurrently during a sync",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_APPLY_CONCURRENCY"},
+		Value:   1,
+	},
+	&cli.DurationFlag{
+		Name:    "apply-debounce",
+		Usage:   "when non-zero, queues and coalesces changes received within this window before applying them serially",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_APPLY_DEBOUNCE"},
+	},
+	&cli.StringFlag{
+		Name:    "backend",
+		Usage:   "routeros record backend ('static')",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_BACKEND"},
+		Value:   "static",
+	},
+	&cli.StringFlag{
+		Name:    "config-file",
+		Aliases: []string{"config"},
+		Usage:   "path to a yaml config file (e.g. a mounted configmap) overriding options - reloaded on change",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_CONFIG_FILE"},
+	},
+	&cli.DurationFlag{
+		Name:    "config-file-interval",
+		Usage:   "interval at which the config file is checked for changes",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_CONFIG_FILE_INTERVAL"},
+		Value:   10 * time.Second,
+	},
+	&cli.StringFlag{
+		Name:    "conflict-policy",
+		Usage:   "how unmanaged records sharing a created record's name are handled (ignore, overwrite, fail, skip) - policies other than 'ignore' require the 'comment' metadata store",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_CONFLICT_POLICY"},
+		Value:   "ignore",
+	},
+	&cli.BoolFlag{
+		Name:    "enable-pprof",
+		Usage:   "serve go runtime profiles (net/http/pprof) under /debug/pprof on the internal server (requires --internal-server-port)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ENABLE_PPROF"},
+	},
+	&cli.StringFlag{
+		Name:    "feature-gates",
+		Usage:   "comma-separated '<feature>=<bool>' pairs enabling or disabling subsystems (AdoptExisting, RollbackOnFailure) - overriding their flags",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FEATURE_GATES"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "filter-exclude",
+		Usage:   "dns string exclusion filter",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_EXCLUDE"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "filter-include",
+		Usage:   "dns string inclusion filter",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_INCLUDE"},
+	},
+	&cli.StringFlag{
+		Name:    "filter-regex-exclude",
+		Usage:   "dns regex exclusion filter",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_REGEX_EXCLUDE"},
+	},
+	&cli.StringFlag{
+		Name:    "filter-regex-include",
+		Usage:   "dns regex inclusion filter",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_REGEX_INCLUDE"},
+	},
+	&cli.StringFlag{
+		Name:    "heartbeat-file",
+		Usage:   "path to a file rewritten after each successful health check - for exec-based liveness probes",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_HEARTBEAT_FILE"},
+	},
+	&cli.DurationFlag{
+		Name:    "heartbeat-interval",
+		Usage:   "interval between the health checks rewriting the heartbeat file",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_HEARTBEAT_INTERVAL"},
+		Value:   30 * time.Second,
+	},
+	&cli.DurationFlag{
+		Name:    "integrity-check-interval",
+		Usage:   "when non-zero, interval at which managed records are checked for external modifications",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_INTEGRITY_CHECK_INTERVAL"},
+	},
+	&cli.StringFlag{
+		Name:    "instances-file",
+		Usage:   "path to a yaml file defining several named provider instances (e.g., per router) to run within this process",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_INSTANCES_FILE"},
+	},
+	&cli.BoolFlag{
+		Name:    "integrity-repair",
+		Usage:   "delete externally modified records so that external-dns recreates them",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_INTEGRITY_REPAIR"},
+	},
+	&cli.StringFlag{
+		Name:    "intent-log",
+		Usage:   "path to a file persisting in-progress syncs - interrupted syncs are completed on restart",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_INTENT_LOG"},
+	},
+	&cli.StringFlag{
+		Name:    "internal-server-host",
+		Usage:   "host the internal server (serving health checks, metrics, administrative endpoints, record searches and profiles) binds to",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_INTERNAL_SERVER_HOST"},
+		Value:   "127.0.0.1",
+	},
+	&cli.UintFlag{
+		Name:    "internal-server-port",
+		Usage:   "port the internal server (serving health checks, metrics, administrative endpoints, record searches and profiles) binds to - disabled when 0",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_INTERNAL_SERVER_PORT"},
+	},
+	&cli.BoolFlag{
+		Name:    "kubernetes-events",
+		Usage:   "emit kubernetes events on the resources producing records (requires in-cluster rbac to create events)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_KUBERNETES_EVENTS"},
+	},
+	&cli.DurationFlag{
+		Name:    "lock-lease",
+		Usage:   "how long the routeros lock is held before it expires (e.g., if its owner crashes)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_LOCK_LEASE"},
+		Value:   5 * time.Minute,
+	},
+	&cli.StringFlag{
+		Name:    "lock-record",
+		Usage:   "name of a TXT record used as a lock - serializing the changes of providers sharing a router",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_LOCK_RECORD"},
+	},
+	&cli.DurationFlag{
+		Name:    "lock-timeout",
+		Usage:   "how long to wait for the routeros lock before failing a sync",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_LOCK_TIMEOUT"},
+		Value:   time.Minute,
+	},
+	&cli.DurationFlag{
+		Name:    "log-debug-duration",
+		Usage:   "how long debug logging enabled via SIGUSR2 lasts before reverting to the configured log level (0 = until the next SIGUSR2)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_LOG_DEBUG_DURATION"},
+		Value:   15 * time.Minute,
+	},
+	&cli.UintFlag{
+		Name:    "log-sample-limit",
+		Usage:   "maximum number of per-record log lines (per record type) logged at info level during a sync (0 = unlimited)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_LOG_SAMPLE_LIMIT"},
+	},
+	&cli.StringFlag{
+		Name:    "metadata-store",
+		Usage:   "where record metadata is stored ('comment' | 'txt')",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_METADATA_STORE"},
+		Value:   "comment",
+	},
+	&cli.DurationFlag{
+		Name:    "metrics-interval",
+		Usage:   "interval between metrics exports",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_METRICS_INTERVAL"},
+		Value:   15 * time.Second,
+	},
+	&cli.StringFlag{
+		Name:    "metrics-otlp-endpoint",
+		Usage:   "OTLP/HTTP endpoint (e.g., 'http://otel-collector:4318') metrics are exported to",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_METRICS_OTLP_ENDPOINT"},
+	},
+	&cli.StringFlag{
+		Name:    "metrics-otlp-proxy",
+		Usage:   "proxy url (e.g., 'http://proxy:3128') OTLP requests are sent via - defaults to the proxy configured within the environment",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_METRICS_OTLP_PROXY"},
+	},
+	&cli.StringFlag{
+		Name:    "metrics-statsd-address",
+		Usage:   "statsd server ('<host>:<port>') metrics are exported to",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_METRICS_STATSD_ADDRESS"},
+	},
+	&cli.BoolFlag{
+		Name:    "migrate-records",
+		Usage:   "on startup, upgrade managed records written by older provider versions in place",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_MIGRATE_RECORDS"},
+	},
+	&cli.StringFlag{
+		Name:    "name-policy",
+		Usage:   "how endpoint dns names are validated (permissive, strict) - 'strict' drops endpoints with uppercase, malformed or misplaced underscore names",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_NAME_POLICY"},
+		Value:   "permissive",
+	},
+	&cli.StringSliceFlag{
+		Name:    "protected-names",
+		Usage:   "dns name that the provider will never change - can be used multiple times",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_PROTECTED_NAMES"},
+	},
+	&cli.StringFlag{
+		Name:    "protected-regex",
+		Usage:   "dns name regex that the provider will never change",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_PROTECTED_REGEX"},
+	},
+	&cli.StringFlag{
+		Name:    "quota-label",
+		Usage:   "endpoint label whose values are subject to the --quota-label-max record quota",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_QUOTA_LABEL"},
+	},
+	&cli.UintFlag{
+		Name:    "quota-label-max",
+		Usage:   "maximum number of records sharing a --quota-label value (0 = unlimited)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_QUOTA_LABEL_MAX"},
+	},
+	&cli.UintFlag{
+		Name:    "quota-namespace",
+		Usage:   "maximum number of records produced by resources within a single kubernetes namespace (0 = unlimited)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_QUOTA_NAMESPACE"},
+	},
+	&cli.StringFlag{
+		Name:    "ready-file",
+		Usage:   "path to a file created once the server accepts connections - for exec-based readiness probes",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_READY_FILE"},
+	},
+	&cli.DurationFlag{
+		Name:    "records-cache-ttl",
+		Usage:   "when non-zero, caches listed records for this duration (until records are changed) - keeping frequent listings cheap",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_RECORDS_CACHE_TTL"},
+	},
+	&cli.StringSliceFlag{
+		Name:    "rewrite",
+		Usage:   "rewrites dns names matching a regex (<regex>=<replacement>) before publishing - can be used multiple times",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_REWRITE"},
+	},
+	&cli.BoolFlag{
+		Name:    "rollback-on-failure",
+		Usage:   "roll back all changes of a sync when any of its changes fail - performed by the webhook, so changes are not rolled back if the webhook crashes",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROLLBACK_ON_FAILURE"},
+	},
+	&cli.StringFlag{
+		Name:    "routeros-address",
+		Usage:   "routeros address (<host>:<port>)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_ADDRESS"},
+	},
+	&cli.StringFlag{
+		Name:    "routeros-cert-fingerprint",
+		Usage:   "when set, connect via api-ssl - trusting only the routeros certificate with this sha256 fingerprint (e.g., for self-signed certificates)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_CERT_FINGERPRINT"},
+	},
+	&cli.StringFlag{
+		Name:    "routeros-fallback-ip",
+		Usage:   "ip address of the routeros device used when the hostname within --routeros-address fails to resolve",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_FALLBACK_IP"},
+	},
+	&cli.StringFlag{
+		Name:    "routeros-menu",
+		Usage:   "routeros api menu path holding static dns records",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_MENU"},
+		Value:   "/ip/dns/static",
+	},
+	&cli.StringFlag{
+		Name:    "routeros-password",
+		Usage:   "routeros password",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_PASSWORD"},
+	},
+	&cli.StringFlag{
+		Name:    "routeros-resolver",
+		Usage:   "dns server (<ip>:<port>) resolving the hostname within --routeros-address - defaults to the system resolver",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_RESOLVER"},
+	},
+	&cli.StringFlag{
+		Name:    "routeros-username",
+		Usage:   "routeros username",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_USERNAME"},
+	},
+	&cli.StringFlag{
+		Name:    "routes-file",
+		Usage:   "path to a yaml file defining routes that send records matching their filters to other routers",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTES_FILE"},
+	},
+	&cli.StringFlag{
+		Name:    "serial-record",
+		Usage:   "name of a TXT record holding a zone serial that is bumped whenever a sync changes records",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_SERIAL_RECORD"},
+	},
+	&cli.StringFlag{
+		Name:    "serial-script",
+		Usage:   "name of a routeros script run whenever a sync changes records",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_SERIAL_SCRIPT"},
+	},
+	&cli.StringFlag{
+		Name:    "server-host",
+		Usage:   "host to bind to",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_SERVER_HOST"},
+		Value:   "127.0.0.1",
+	},
+	&cli.UintFlag{
+		Name:    "server-port",
+		Usage:   "port to bind to",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_SERVER_PORT"},
+		Value:   8888,
+	},
+	&cli.BoolFlag{
+		Name:    "trace-routeros",
+		Usage:   "log every raw api sentence sent to and received from routeros (with credentials redacted)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_TRACE_ROUTEROS"},
+	},
+	&cli.DurationFlag{
+		Name:    "ttl-warn-high",
+		Usage:   "when non-zero, warns about records requesting a ttl above this duration",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_TTL_WARN_HIGH"},
+	},
+	&cli.DurationFlag{
+		Name:    "ttl-warn-low",
+		Usage:   "when non-zero, warns about records requesting a ttl below this duration",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_TTL_WARN_LOW"},
+		Value:   5 * time.Second,
+	},
+	&cli.UintFlag{
+		Name:    "txt-max-length",
+		Usage:   "when non-zero, TXT values longer than this are split across multiple records (requires the 'comment' metadata store)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_TXT_MAX_LENGTH"},
+	},
+	&cli.StringFlag{
+		Name:    "verify-address",
+		Usage:   "dns server (<host>:<port>) queried when verifying records (default: port 53 of the routeros device)",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_VERIFY_ADDRESS"},
+	},
+	&cli.DurationFlag{
+		Name:    "verify-window",
+		Usage:   "when non-zero, how long created records are retried until they resolve via the routeros dns server",
+		EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_VERIFY_WINDOW"},
+	},
+}
+
+// Returns the flags within [runFlags] with the given names
+func pickFlags(ns ...string) []cli.Flag {
+	fs := []cli.Flag{}
+	for _, f := range runFlags {
+		if slices.Contains(ns, f.Names()[0]) {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// Reads the routes within the routes file flag - returning nil if the flag is unset
+func readRoutesFlag(c *cli.Context) ([]provider.RouteConfig, error) {
+	p := c.String("routes-file")
+	if p == "" {
+		return nil, nil
+	}
+	return provider.ReadRoutesFile(p)
+}
+
+// Compiles the regex held by the given flag - returning nil if the flag is unset
+func compileRegexFlag(c *cli.Context, n string) (*regexp.Regexp, error) {
+	v := c.String(n)
+	if v == "" {
+		return nil, nil
+	}
+	return regexp.Compile(v)
+}
+
+// A webhook server (or set of servers) started by [runAction]
+type runnable interface {
+	Run() error
+	ToggleMaintenance()
+}
+
+// Reads the [provider.Opts] from the flags within [runFlags].
+// Rather than failing on the first invalid flag, returns all errors encountered (see [validateConfigAction]).
+func readRunOpts(c *cli.Context, l *slog.Logger, standby bool) (*provider.Opts, []error) {
+	ll, _ := c.Context.Value(ContextLogLevel{}).(*provider.LogLevel)
+	errs := []error{}
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	fre, err := compileRegexFlag(c, "filter-regex-exclude")
+	add(err)
+	fri, err := compileRegexFlag(c, "filter-regex-include")
+	add(err)
+
+	pr, err := compileRegexFlag(c, "protected-regex")
+	add(err)
+
+	rrs := []provider.RewriteRule{}
+	for _, rrst := range c.StringSlice("rewrite") {
+		rr, err := provider.ParseRewriteRule(rrst)
+		add(err)
+		if err == nil {
+			rrs = append(rrs, rr)
+		}
+	}
+
+	rcs, err := readRoutesFlag(c)
+	add(err)
+
+	fg, err := provider.ParseFeatureGates(c.String("feature-gates"))
+	add(err)
+
+	return &provider.Opts{
+		AdoptExisting:           c.Bool("adopt-existing"),
+		ApplyBatchDelay:         c.Duration("apply-batch-delay"),
+		ApplyBatchSize:          c.Uint("apply-batch-size"),
+		ApplyConcurrency:        c.Uint("apply-concurrency"),
+		ApplyDebounce:           c.Duration("apply-debounce"),
+		Backend:                 c.String("backend"),
+		ConfigFile:              c.String("config-file"),
+		ConfigFileInterval:      c.Duration("config-file-interval"),
+		ConflictPolicy:          c.String("conflict-policy"),
+		EnablePprof:             c.Bool("enable-pprof"),
+		FeatureGates:            fg,
+		FilterExclude:           c.StringSlice("filter-exclude"),
+		FilterInclude:           c.StringSlice("filter-include"),
+		FilterRegexExclude:      fre,
+		FilterRegexInclude:      fri,
+		HeartbeatFile:           c.String("heartbeat-file"),
+		HeartbeatInterval:       c.Duration("heartbeat-interval"),
+		IntegrityInterval:       c.Duration("integrity-check-interval"),
+		IntegrityRepair:         c.Bool("integrity-repair"),
+		IntentLog:               c.String("intent-log"),
+		InternalServerHost:      c.String("internal-server-host"),
+		InternalServerPort:      c.Uint("internal-server-port"),
+		KubernetesEvents:        c.Bool("kubernetes-events"),
+		LockLease:               c.Duration("lock-lease"),
+		LockRecord:              c.String("lock-record"),
+		LockTimeout:             c.Duration("lock-timeout"),
+		Logger:                  l,
+		LogLevel:                ll,
+		LogSampleLimit:          c.Uint("log-sample-limit"),
+		MetadataStore:           c.String("metadata-store"),
+		MetricsInterval:         c.Duration("metrics-interval"),
+		MetricsOtlpEndpoint:     c.String("metrics-otlp-endpoint"),
+		MetricsOtlpProxy:        c.String("metrics-otlp-proxy"),
+		MetricsStatsdAddress:    c.String("metrics-statsd-address"),
+		MigrateRecords:          c.Bool("migrate-records"),
+		NamePolicy:              c.String("name-policy"),
+		ProtectedNames:          c.StringSlice("protected-names"),
+		ProtectedRegex:          pr,
+		QuotaLabel:              c.String("quota-label"),
+		QuotaLabelMax:           c.Uint("quota-label-max"),
+		QuotaNamespace:          c.Uint("quota-namespace"),
+		ReadyFile:               c.String("ready-file"),
+		RecordsCacheTtl:         c.Duration("records-cache-ttl"),
+		RewriteRules:            rrs,
+		RollbackOnFailure:       c.Bool("rollback-on-failure"),
+		RouterOSAddress:         c.String("routeros-address"),
+		RouterOSCertFingerprint: c.String("routeros-cert-fingerprint"),
+		RouterOSFallbackIP:      c.String("routeros-fallback-ip"),
+		RouterOSMenu:            c.String("routeros-menu"),
+		RouterOSPassword:        c.String("routeros-password"),
+		RouterOSResolver:        c.String("routeros-resolver"),
+		RouterOSUsername:        c.String("routeros-username"),
+		Routes:                  rcs,
+		SerialRecord:            c.String("serial-record"),
+		SerialScript:            c.String("serial-script"),
+		ServerHost:              c.String("server-host"),
+		ServerPort:              c.Uint("server-port"),
+		Standby:                 standby,
+		TraceRouterOS:           c.Bool("trace-routeros"),
+		TtlHigh:                 c.Duration("ttl-warn-high"),
+		TtlLow:                  c.Duration("ttl-warn-low"),
+		TxtMaxLength:            c.Uint("txt-max-length"),
+		VerifyAddress:           c.String("verify-address"),
+		VerifyWindow:            c.Duration("verify-window"),
+	}, errs
+}
+
+// Checks the provider configuration (flags, config file, routes file and instances file) without connecting to routeros - printing all problems found
+func validateConfigAction(c *cli.Context) error {
+	l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
+	if !ok {
+		return fmt.Errorf("logger not attached to context")
+	}
+
+	o, errs := readRunOpts(c, l, false)
+	ics := []provider.InstanceConfig{}
+	if ifp := c.String("instances-file"); ifp != "" {
+		var err error
+		ics, err = provider.ReadInstancesFile(ifp)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, provider.ValidateConfig(*o, ics)...)
+	for _, err := range errs {
+		fmt.Fprintf(c.App.Writer, "%s\n", err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d configuration problems found", len(errs))
+	}
+	fmt.Fprintf(c.App.Writer, "configuration valid\n")
+	return nil
+}
+
+// Creates an action that starts the provider webhook server.
+// When standby is true, the server serves only health checks and metrics.
+func runAction(standby bool) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
+		if !ok {
+			return fmt.Errorf("logger not attached to context")
+		}
+
+		o, errs := readRunOpts(c, l, standby)
+		if len(errs) > 0 {
+			return errs[0]
+		}
+		var err error
+		var s runnable
+		if ifp := c.String("instances-file"); ifp != "" {
+			ics, err := provider.ReadInstancesFile(ifp)
+			if err != nil {
+				return err
+			}
+			s, err = provider.NewInstances(o, ics)
+			if err != nil {
+				return err
+			}
+		} else {
+			s, err = provider.New(o)
+			if err != nil {
+				return err
+			}
+		}
+
+		sc := make(chan os.Signal, 1)
+		signal.Notify(sc, syscall.SIGUSR1, syscall.SIGUSR2)
+		go func() {
+			for sig := range sc {
+				switch sig {
+				case syscall.SIGUSR1:
+					s.ToggleMaintenance()
+				case syscall.SIGUSR2:
+					if o.LogLevel == nil {
+						continue
+					}
+					o.LogLevel.ToggleDebug(c.Duration("log-debug-duration"))
+					l.Info(fmt.Sprintf("log level: %s", o.LogLevel.Status().Level))
+				}
+			}
+		}()
+
+		return s.Run()
+	}
+}
+
+// Adopts existing unmanaged routeros records - printing each adopted record
+func adoptAction(c *cli.Context) error {
+	l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
+	if !ok {
+		return fmt.Errorf("logger not attached to context")
+	}
+
+	fre, err := compileRegexFlag(c, "filter-regex-exclude")
+	if err != nil {
+		return err
+	}
+	fri, err := compileRegexFlag(c, "filter-regex-include")
+	if err != nil {
+		return err
+	}
+
+	rcs, err := readRoutesFlag(c)
+	if err != nil {
+		return err
+	}
+
+	dr := c.Bool("dry-run")
+	es, err := provider.Adopt(&provider.Opts{
+		Backend:                 c.String("backend"),
+		FilterExclude:           c.StringSlice("filter-exclude"),
+		FilterInclude:           c.StringSlice("filter-include"),
+		FilterRegexExclude:      fre,
+		FilterRegexInclude:      fri,
+		Logger:                  l,
+		MetadataStore:           c.String("metadata-store"),
+		RouterOSAddress:         c.String("routeros-address"),
+		RouterOSCertFingerprint: c.String("routeros-cert-fingerprint"),
+		RouterOSFallbackIP:      c.String("routeros-fallback-ip"),
+		RouterOSMenu:            c.String("routeros-menu"),
+		RouterOSPassword:        c.String("routeros-password"),
+		RouterOSResolver:        c.String("routeros-resolver"),
+		RouterOSUsername:        c.String("routeros-username"),
+		Routes:                  rcs,
+		TraceRouterOS:           c.Bool("trace-routeros"),
+	}, &provider.AdoptOpts{
+		AllMatchingFilter: c.Bool("all-matching-filter"),
+		DryRun:            dr,
+		Names:             c.StringSlice("name"),
+	})
+	if err != nil {
+		return err
+	}
+	op := "adopted"
+	if dr {
+		op = "would adopt"
+	}
+	for _, e := range es {
+		fmt.Fprintf(c.App.Writer, "%s %s %s %s\n", op, e.RecordType, e.DNSName, strings.Join(e.Targets, ","))
+	}
+	return nil
+}
+
+// Upgrades managed records written by older provider versions - printing each migrated record
+func migrateAction(c *cli.Context) error {
+	l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
+	if !ok {
+		return fmt.Errorf("logger not attached to context")
+	}
+
+	rcs, err := readRoutesFlag(c)
+	if err != nil {
+		return err
+	}
+
+	dr := c.Bool("dry-run")
+	mrs, err := provider.Migrate(&provider.Opts{
+		Backend:                 c.String("backend"),
+		Logger:                  l,
+		MetadataStore:           c.String("metadata-store"),
+		RouterOSAddress:         c.String("routeros-address"),
+		RouterOSCertFingerprint: c.String("routeros-cert-fingerprint"),
+		RouterOSFallbackIP:      c.String("routeros-fallback-ip"),
+		RouterOSMenu:            c.String("routeros-menu"),
+		RouterOSPassword:        c.String("routeros-password"),
+		RouterOSResolver:        c.String("routeros-resolver"),
+		RouterOSUsername:        c.String("routeros-username"),
+		Routes:                  rcs,
+		TraceRouterOS:           c.Bool("trace-routeros"),
+	}, &provider.MigrateOpts{
+		DryRun: dr,
+	})
+	op := "migrated"
+	if dr {
+		op = "would migrate"
+	}
+	for _, mr := range mrs {
+		fmt.Fprintf(c.App.Writer, "%s %s %s (%s): %s\n", op, mr.RecordType, mr.DNSName, mr.Id, strings.Join(mr.Migrations, ", "))
+	}
+	return err
+}
+
+// The columns of the rows returned by [recordRow]
+var recordHeader = []string{"TYPE", "NAME", "TARGETS", "TTL", "OWNER", "RESOURCE"}
+
+// Returns a row (see [recordHeader]) describing the given record
+func recordRow(e *endpoint.Endpoint) []string {
+	return []string{e.RecordType, e.DNSName, strings.Join(e.Targets, ","), fmt.Sprintf("%d", e.RecordTTL), e.Labels[endpoint.OwnerLabelKey], e.Labels[endpoint.ResourceLabelKey]}
+}
+
+// Lists managed routeros records - printing them in the selected output format (see [outputFlag])
+func recordsListAction(c *cli.Context) error {
+	l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
+	if !ok {
+		return fmt.Errorf("logger not attached to context")
+	}
+
+	ow, err := newOutputWriter(c)
+	if err != nil {
+		return err
+	}
+
+	rcs, err := readRoutesFlag(c)
+	if err != nil {
+		return err
+	}
+
+	es, err := provider.ListRecords(&provider.Opts{
+		Backend:                 c.String("backend"),
+		Logger:                  l,
+		MetadataStore:           c.String("metadata-store"),
+		RouterOSAddress:         c.String("routeros-address"),
+		RouterOSCertFingerprint: c.String("routeros-cert-fingerprint"),
+		RouterOSFallbackIP:      c.String("routeros-fallback-ip"),
+		RouterOSMenu:            c.String("routeros-menu"),
+		RouterOSPassword:        c.String("routeros-password"),
+		RouterOSResolver:        c.String("routeros-resolver"),
+		RouterOSUsername:        c.String("routeros-username"),
+		Routes:                  rcs,
+		TraceRouterOS:           c.Bool("trace-routeros"),
+	}, &provider.ListRecordsOpts{
+		Filter: provider.RecordFilter{
+			Labels: c.StringSlice("label"),
+			Name:   c.String("name"),
+			Type:   c.String("type"),
+		},
+		Owner:                  c.String("owner"),
+		TxtPrefix:              c.String("txt-prefix"),
+		TxtSuffix:              c.String("txt-suffix"),
+		TxtWildcardReplacement: c.String("txt-wildcard-replacement"),
+	})
+	if err != nil {
+		return err
+	}
+	rs := [][]string{}
+	for _, e := range es {
+		rs = append(rs, recordRow(e))
+	}
+	return ow.write(es, recordHeader, rs, func(r int, c int, v string) string {
+		if c == 0 {
+			return ow.colorize(colorCyan, v)
+		}
+		return v
+	})
+}
+
+// Deletes a managed routeros record - printing the deleted (or, in dry-run mode, deletable) record in the selected output format (see [outputFlag])
+func recordsDeleteAction(c *cli.Context) error {
+	l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
+	if !ok {
+		return fmt.Errorf("logger not attached to context")
+	}
+
+	ow, err := newOutputWriter(c)
+	if err != nil {
+		return err
+	}
+
+	pre, err := compileRegexFlag(c, "protected-regex")
+	if err != nil {
+		return err
+	}
+
+	rcs, err := readRoutesFlag(c)
+	if err != nil {
+		return err
+	}
+
+	dr := c.Bool("dry-run")
+	e, err := provider.DeleteRecord(&provider.Opts{
+		Backend:                 c.String("backend"),
+		Logger:                  l,
+		MetadataStore:           c.String("metadata-store"),
+		ProtectedNames:          c.StringSlice("protected-names"),
+		ProtectedRegex:          pre,
+		RouterOSAddress:         c.String("routeros-address"),
+		RouterOSCertFingerprint: c.String("routeros-cert-fingerprint"),
+		RouterOSFallbackIP:      c.String("routeros-fallback-ip"),
+		RouterOSMenu:            c.String("routeros-menu"),
+		RouterOSPassword:        c.String("routeros-password"),
+		RouterOSResolver:        c.String("routeros-resolver"),
+		RouterOSUsername:        c.String("routeros-username"),
+		Routes:                  rcs,
+		TraceRouterOS:           c.Bool("trace-routeros"),
+	}, &provider.DeleteRecordOpts{
+		DryRun:  dr,
+		Name:    c.String("name"),
+		Targets: c.StringSlice("target"),
+		Type:    c.String("type"),
+	})
+	if err != nil {
+		return err
+	}
+	return ow.write(e, recordHeader, [][]string{recordRow(e)}, func(r int, c int, v string) string {
+		if dr {
+			return v
+		}
+		return ow.colorize(colorRed, v)
+	})
+}
+
+// The outcome of a self-test step printed by [selfTestAction]
+type selfTestResult struct {
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+	Name     string `json:"name"`
+	Result   string `json:"result"`
+}
+
+// Runs a self-test against the routeros device - printing each step performed in the selected output format (see [outputFlag])
+func selfTestAction(c *cli.Context) error {
+	l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
+	if !ok {
+		return fmt.Errorf("logger not attached to context")
+	}
+
+	ow, err := newOutputWriter(c)
+	if err != nil {
+		return err
+	}
+
+	sts, err := provider.SelfTest(&provider.Opts{
+		Backend:                 c.String("backend"),
+		Logger:                  l,
+		MetadataStore:           c.String("metadata-store"),
+		RouterOSAddress:         c.String("routeros-address"),
+		RouterOSCertFingerprint: c.String("routeros-cert-fingerprint"),
+		RouterOSFallbackIP:      c.String("routeros-fallback-ip"),
+		RouterOSMenu:            c.String("routeros-menu"),
+		RouterOSPassword:        c.String("routeros-password"),
+		RouterOSResolver:        c.String("routeros-resolver"),
+		RouterOSUsername:        c.String("routeros-username"),
+		TraceRouterOS:           c.Bool("trace-routeros"),
+		VerifyAddress:           c.String("verify-address"),
+	}, &provider.SelfTestOpts{
+		Domain:  c.String("domain"),
+		Timeout: c.Duration("timeout"),
+	})
+	strs := []selfTestResult{}
+	rs := [][]string{}
+	for _, st := range sts {
+		str := selfTestResult{Duration: st.Duration.String(), Name: st.Name, Result: "ok"}
+		if st.Error != nil {
+			str.Error = st.Error.Error()
+			str.Result = "failed"
+		}
+		strs = append(strs, str)
+		rs = append(rs, []string{str.Name, str.Result, str.Duration, str.Error})
+	}
+	oerr := ow.write(strs, []string{"STEP", "RESULT", "DURATION", "ERROR"}, rs, func(r int, c int, v string) string {
+		if c != 1 {
+			return v
+		}
+		if strs[r].Error != "" {
+			return ow.colorize(colorRed, v)
+		}
+		return ow.colorize(colorGreen, v)
+	})
+	return errors.Join(err, oerr)
+}
+
+// Prints the provider version - the 'human' output format prints the bare version, other formats (see [outputFlag]) additionally print the commit, build date, go version and supported webhook protocol versions
+func versionAction(c *cli.Context) error {
+	ow, err := newOutputWriter(c)
+	if err != nil {
+		return err
+	}
+	vi := provider.GetVersionInfo()
+	if ow.format == "human" {
+		fmt.Fprintf(c.App.Writer, "%s", vi.Version)
+		return nil
+	}
+	return ow.write(vi, []string{"VERSION", "COMMIT", "BUILD DATE", "GO VERSION", "WEBHOOK PROTOCOL VERSIONS"}, [][]string{{vi.Version, vi.Commit, vi.BuildDate, vi.GoVersion, strings.Join(vi.WebhookProtocolVersions, ",")}}, nil)
+}
+
+// Prints the routeros commands creating a least-privilege user for the provider
+func generateRouterConfigAction(c *cli.Context) error {
+	cs, err := provider.GenerateRouterConfig(&provider.RouterConfigOpts{
+		Address:  c.String("address"),
+		Group:    c.String("group"),
+		Password: c.String("password"),
+		Username: c.String("username"),
+	})
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cs {
+		fmt.Fprintf(c.App.Writer, "%s\n", cmd)
+	}
+	return nil
+}
+
+// Creates the provider's command line application
+func newApp() *cli.App {
+	return &cli.App{
+		EnableBashCompletion: true,
 		Before: func(c *cli.Context) error {
-			logger, err := configureLogging(c.String("log-level"))
+			logger, ll, err := configureLogging(c.String("log-level"))
 			if err != nil {
 				return err
 			}
+			if ep := c.String("env-prefix"); ep != "" {
+				err = applyEnvPrefix(ep)
+				if err != nil {
+					return err
+				}
+			}
 			c.Context = context.WithValue(c.Context, ContextLogger{}, logger)
+			c.Context = context.WithValue(c.Context, ContextLogLevel{}, ll)
 			return nil
 		},
 		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "env-prefix",
+				Usage:   "additionally read command flags from environment variables with this prefix (e.g., 'ROUTEROS_') - taking precedence over the default environment variables",
+				EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ENV_PREFIX"},
+			},
 			&cli.StringFlag{
 				Name:  "log-level",
 				Usage: "logging verbosity level",
@@ -61,106 +932,251 @@ func main() {
 		},
 		Commands: []*cli.Command{
 			{
-				Name:  "run",
-				Usage: "start provider webhook server",
-				Flags: []cli.Flag{
-					&cli.StringSliceFlag{
-						Name:    "filter-exclude",
-						Usage:   "dns string exclusion filter",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_EXCLUDE"},
+				Name:   "run",
+				Usage:  "start provider webhook server",
+				Flags:  runFlags,
+				Action: runAction(false),
+			},
+			{
+				Name:   "serve-metrics-only",
+				Usage:  "start provider webhook server in standby mode - serving only health checks and metrics",
+				Flags:  runFlags,
+				Action: runAction(true),
+			},
+			{
+				Name:   "validate-config",
+				Usage:  "checks the provider configuration (flags, environment variables and files) without connecting to routeros - printing all problems found",
+				Flags:  runFlags,
+				Action: validateConfigAction,
+			},
+			{
+				Name:  "adopt",
+				Usage: "tags existing unmanaged routeros records as managed - without deleting and recreating them",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:  "all-matching-filter",
+						Usage: "adopt all unmanaged records matching the domain filter",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "print the records that would be adopted without adopting them",
 					},
 					&cli.StringSliceFlag{
-						Name:    "filter-include",
-						Usage:   "dns string inclusion filter",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_INCLUDE"},
+						Name:  "name",
+						Usage: "dns name of records to adopt - can be used multiple times",
 					},
-					&cli.StringFlag{
-						Name:    "filter-regex-exclude",
-						Usage:   "dns regex exclusion filter",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_REGEX_EXCLUDE"},
+				}, pickFlags(
+					"backend",
+					"filter-exclude",
+					"filter-include",
+					"filter-regex-exclude",
+					"filter-regex-include",
+					"metadata-store",
+					"routeros-address",
+					"routeros-cert-fingerprint",
+					"routeros-fallback-ip",
+					"routeros-menu",
+					"routeros-password",
+					"routeros-resolver",
+					"routeros-username",
+					"routes-file",
+					"trace-routeros",
+				)...),
+				Action: adoptAction,
+			},
+			{
+				Name:  "migrate",
+				Usage: "upgrades managed routeros records written by older provider versions in place",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "print the records that would be migrated without migrating them",
 					},
-					&cli.StringFlag{
-						Name:    "filter-regex-include",
-						Usage:   "dns regex inclusion filter",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_FILTER_REGEX_INCLUDE"},
+				}, pickFlags(
+					"backend",
+					"metadata-store",
+					"routeros-address",
+					"routeros-cert-fingerprint",
+					"routeros-fallback-ip",
+					"routeros-menu",
+					"routeros-password",
+					"routeros-resolver",
+					"routeros-username",
+					"routes-file",
+					"trace-routeros",
+				)...),
+				Action: migrateAction,
+			},
+			{
+				Name:  "records",
+				Usage: "inspects managed routeros records",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "lists managed routeros records (and their owners)",
+						Flags: append([]cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "label",
+								Usage: "label key ('key') or key and value ('key=value') of records to list - can be used multiple times",
+							},
+							&cli.StringFlag{
+								Name:  "name",
+								Usage: "dns name or glob (e.g., '*.example.com') of records to list",
+							},
+							outputFlag(),
+							&cli.StringFlag{
+								Name:  "owner",
+								Usage: "owner id (external-dns' --txt-owner-id) of records to list",
+							},
+							&cli.StringFlag{
+								Name:  "txt-prefix",
+								Usage: "external-dns' --txt-prefix - used to determine record owners",
+							},
+							&cli.StringFlag{
+								Name:  "txt-suffix",
+								Usage: "external-dns' --txt-suffix - used to determine record owners",
+							},
+							&cli.StringFlag{
+								Name:  "txt-wildcard-replacement",
+								Usage: "external-dns' --txt-wildcard-replacement - used to determine record owners",
+							},
+							&cli.StringFlag{
+								Name:  "type",
+								Usage: "record type of records to list",
+							},
+						}, pickFlags(
+							"backend",
+							"metadata-store",
+							"routeros-address",
+							"routeros-cert-fingerprint",
+							"routeros-fallback-ip",
+							"routeros-menu",
+							"routeros-password",
+							"routeros-resolver",
+							"routeros-username",
+							"routes-file",
+							"trace-routeros",
+						)...),
+						Action: recordsListAction,
 					},
+					{
+						Name:  "delete",
+						Usage: "deletes a managed routeros record - refusing unmanaged and protected records",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "print the record that would be deleted without deleting it",
+							},
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "dns name of the record to delete",
+								Required: true,
+							},
+							outputFlag(),
+							&cli.StringSliceFlag{
+								Name:  "target",
+								Usage: "only delete this target of the record - can be used multiple times",
+							},
+							&cli.StringFlag{
+								Name:     "type",
+								Usage:    "record type of the record to delete",
+								Required: true,
+							},
+						}, pickFlags(
+							"backend",
+							"metadata-store",
+							"protected-names",
+							"protected-regex",
+							"routeros-address",
+							"routeros-cert-fingerprint",
+							"routeros-fallback-ip",
+							"routeros-menu",
+							"routeros-password",
+							"routeros-resolver",
+							"routeros-username",
+							"routes-file",
+							"trace-routeros",
+						)...),
+						Action: recordsDeleteAction,
+					},
+				},
+			},
+			{
+				Name:  "selftest",
+				Usage: "creates, lists, resolves and deletes a uniquely named test record - verifying the full write/read/resolve path",
+				Flags: append([]cli.Flag{
 					&cli.StringFlag{
-						Name:    "routeros-address",
-						Usage:   "routeros address (<host>:<port>)",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_ADDRESS"},
+						Name:  "domain",
+						Usage: "domain beneath which the test record is created",
+						Value: "local",
+					},
+					outputFlag(),
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "how long the test record is retried until it resolves",
+						Value: 10 * time.Second,
 					},
+				}, pickFlags(
+					"backend",
+					"metadata-store",
+					"routeros-address",
+					"routeros-cert-fingerprint",
+					"routeros-fallback-ip",
+					"routeros-menu",
+					"routeros-password",
+					"routeros-resolver",
+					"routeros-username",
+					"trace-routeros",
+					"verify-address",
+				)...),
+				Action: selfTestAction,
+			},
+			{
+				Name:  "generate-router-config",
+				Usage: "prints the routeros commands creating a user (and user group) with only the permissions required by the provider",
+				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:    "routeros-password",
-						Usage:   "routeros password",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_PASSWORD"},
+						Name:  "address",
+						Usage: "restrict the user to connections from this ip address or cidr",
 					},
 					&cli.StringFlag{
-						Name:    "routeros-username",
-						Usage:   "routeros username",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_ROUTEROS_USERNAME"},
+						Name:  "group",
+						Usage: "name of the user group",
+						Value: "external-dns",
 					},
 					&cli.StringFlag{
-						Name:    "server-host",
-						Usage:   "host to bind to",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_SERVER_HOST"},
-						Value:   "127.0.0.1",
+						Name:  "password",
+						Usage: "password of the user - generated randomly when unset",
 					},
-					&cli.UintFlag{
-						Name:    "server-port",
-						Usage:   "port to bind to",
-						EnvVars: []string{"EXTERNAL_DNS_ROUTEROS_PROVIDER_SERVER_PORT"},
-						Value:   8888,
+					&cli.StringFlag{
+						Name:  "username",
+						Usage: "name of the user",
+						Value: "external-dns",
 					},
 				},
-				Action: func(c *cli.Context) error {
-					var err error
-					l, ok := c.Context.Value(ContextLogger{}).(*slog.Logger)
-					if !ok {
-						return fmt.Errorf("logger not attached to context")
-					}
-
-					var fre *regexp.Regexp
-					if fres := c.String("filter-regex-exclude"); fres != "" {
-						fre, err = regexp.Compile(fres)
-						if err != nil {
-							return err
-						}
-					}
-					var fri *regexp.Regexp
-					if fris := c.String("filter-regex-include"); fris != "" {
-						fri, err = regexp.Compile(fris)
-						if err != nil {
-							return err
-						}
-					}
-
-					s, err := provider.New(&provider.Opts{
-						FilterExclude:      c.StringSlice("filter-exclude"),
-						FilterInclude:      c.StringSlice("filter-include"),
-						FilterRegexExclude: fre,
-						FilterRegexInclude: fri,
-						Logger:             l,
-						RouterOSAddress:    c.String("routeros-address"),
-						RouterOSPassword:   c.String("routeros-password"),
-						RouterOSUsername:   c.String("routeros-username"),
-						ServerHost:         c.String("server-host"),
-						ServerPort:         c.Uint("server-port"),
-					})
-
-					return s.Run()
-				},
+				Action: generateRouterConfigAction,
+			},
+			{
+				Name:         "completion",
+				Usage:        "prints a shell completion script (bash, fish, zsh) - e.g., 'source <(provider completion bash)'",
+				ArgsUsage:    "<shell>",
+				Action:       completionAction,
+				BashComplete: completionComplete,
 			},
 			{
 				Name:  "version",
 				Usage: "prints the provider version",
-				Action: func(c *cli.Context) error {
-					v := strings.TrimSpace(provider.ProviderVersion)
-					fmt.Fprintf(c.App.Writer, "%s", v)
-					return nil
+				Flags: []cli.Flag{
+					outputFlag(),
 				},
+				Action: versionAction,
 			},
 		},
-	}).Run(os.Args)
+	}
+}
+
+func main() {
+	err := newApp().Run(os.Args)
 	code := 0
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
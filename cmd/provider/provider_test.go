@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Returns the commands (and subcommands) of the application that connect to routeros - those accepting '--routeros-address' - keyed by their full name
+func routerosCommands(p string, cs []*cli.Command) map[string]*cli.Command {
+	rcs := map[string]*cli.Command{}
+	for _, c := range cs {
+		n := strings.TrimSpace(p + " " + c.Name)
+		for scn, sc := range routerosCommands(n, c.Subcommands) {
+			rcs[scn] = sc
+		}
+		for _, f := range c.Flags {
+			if f.Names()[0] == "routeros-address" {
+				rcs[n] = c
+			}
+		}
+	}
+	return rcs
+}
+
+func TestRouterosCommandFlags(t *testing.T) {
+	tests := []struct {
+		flag  string
+		value string
+	}{
+		{flag: "routeros-cert-fingerprint", value: "aa"},
+		{flag: "trace-routeros", value: "true"},
+	}
+	rcs := routerosCommands("", newApp().Commands)
+	if len(rcs) == 0 {
+		t.Fatal("no commands accept --routeros-address")
+	}
+	for n := range rcs {
+		for _, test := range tests {
+			t.Run(n+" "+test.flag, func(t *testing.T) {
+				app := newApp()
+				c := routerosCommands("", app.Commands)[n]
+				args := append([]string{"provider"}, strings.Split(n, " ")...)
+				for _, f := range c.Flags {
+					rf, ok := f.(cli.RequiredFlag)
+					if ok && rf.IsRequired() {
+						args = append(args, "--"+f.Names()[0], "value")
+					}
+				}
+				args = append(args, "--"+test.flag+"="+test.value)
+				actual := ""
+				c.Action = func(c *cli.Context) error {
+					actual = fmt.Sprint(c.Value(test.flag))
+					return nil
+				}
+				err := app.Run(args)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if actual != test.value {
+					t.Errorf("read %q, expected %q", actual, test.value)
+				}
+			})
+		}
+	}
+}
@@ -8,6 +8,7 @@ require (
 	github.com/samber/slog-echo v1.14.6
 	github.com/urfave/cli/v2 v2.27.4
 	golang.org/x/sync v0.7.0
+	gopkg.in/yaml.v2 v2.4.0
 	k8s.io/apimachinery v0.30.1
 	sigs.k8s.io/external-dns v0.14.2
 )
@@ -78,7 +79,6 @@ require (
 	google.golang.org/genproto/googleapis/api v0.0.0-20240415180920-8c6c420018be // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	istio.io/api v1.22.0 // indirect
 	istio.io/client-go v1.22.0 // indirect
@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/go-routeros/routeros/v3"
+	"github.com/go-routeros/routeros/v3/proto"
+)
+
+// Executes a routeros api command over a [client]'s connection
+type commandRunner func(cmd []string) (*routeros.Reply, error)
+
+// A backend storing dns records within routeros.
+// Backends translate between [dnsRecord] and a routeros api surface - the [client] handles connections, metadata and endpoint mapping.
+type recordBackend interface {
+	// Creates a record from a [map[string]string] that has the same shape as a routeros ip dns record - returning the id of the created record
+	Create(run commandRunner, v map[string]string) (string, error)
+	// Deletes the records with the given ids
+	Delete(run commandRunner, ids ...string) error
+	// Updates the record with the given id using a [map[string]string] that has the same shape as a routeros ip dns record
+	Update(run commandRunner, id string, v map[string]string) error
+	// Lists all records (managed or not)
+	List(run commandRunner) ([]dnsRecord, error)
+	// Checks that routeros supports the backend - returning a [MenuUnavailableError] if it does not
+	Check(run commandRunner) error
+}
+
+// Function that creates a [recordBackend] using the provided [ClientOpts]
+type recordBackendFactory func(o *ClientOpts) (recordBackend, error)
+
+// Stores records within routeros' static dns menu ('/ip/dns/static' by default)
+const BackendStatic = "static"
+
+// Known backends, keyed by name - see [ClientOpts.Backend]
+var recordBackends = map[string]recordBackendFactory{
+	BackendStatic: newStaticBackend,
+}
+
+// A [recordBackend] storing records within a routeros static dns menu
+type staticBackend struct {
+	menu string
+}
+
+// Creates a new [staticBackend] - using the menu path from [ClientOpts] (if provided)
+func newStaticBackend(o *ClientOpts) (recordBackend, error) {
+	m := strings.TrimSuffix(o.Menu, "/")
+	if m == "" {
+		m = "/ip/dns/static"
+	}
+	if !strings.HasPrefix(m, "/") {
+		return nil, fmt.Errorf("menu %s not an absolute path", m)
+	}
+	return &staticBackend{menu: m}, nil
+}
+
+// Returned when the routeros menu used by a [recordBackend] is unavailable.
+// For example, when dns is served by a container (rather than routeros' resolver) or when the menu path is misconfigured.
+type MenuUnavailableError struct {
+	Menu   string
+	Reason string
+}
+
+func (e MenuUnavailableError) Error() string {
+	return fmt.Sprintf("routeros menu %s unavailable (%s) - ensure the device serves dns via routeros' static dns menu, or set the menu holding dns records (--routeros-menu)", e.Menu, e.Reason)
+}
+
+// Converts errors returned by routeros for unknown menus (e.g., 'no such command prefix') into a [MenuUnavailableError]
+func (b *staticBackend) wrapError(err error) error {
+	de := &routeros.DeviceError{}
+	if errors.As(err, &de) && strings.HasPrefix(de.Sentence.Map["message"], "no such command") {
+		return MenuUnavailableError{Menu: b.menu, Reason: de.Sentence.Map["message"]}
+	}
+	return err
+}
+
+// Produces a routeros api attribute word ('=<key>=<value>').
+// Api words are length-prefixed and routeros splits attribute words at the first '=' following the key.
+// As a result, values are sent verbatim - quotes, '=', whitespace and non-ascii characters (e.g., within TXT records) require no escaping.
+// Returns an error if the key is empty or contains '=' (which would shift the key/value boundary).
+func makeAttributeWord(k string, v string) (string, error) {
+	if k == "" || strings.Contains(k, "=") {
+		return "", fmt.Errorf("invalid attribute key %q", k)
+	}
+	return fmt.Sprintf("=%s=%s", k, v), nil
+}
+
+// Produces the attribute words for a [map[string]string] that has the same shape as a routeros ip dns record.
+// Attributes are sorted - producing stable commands.
+func makeAttributeWords(v map[string]string) ([]string, error) {
+	ks := []string{}
+	for k := range v {
+		ks = append(ks, k)
+	}
+	slices.Sort(ks)
+	attrs := []string{}
+	for _, k := range ks {
+		attr, err := makeAttributeWord(k, v[k])
+		if err != nil {
+			return []string{}, err
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// Calls routeros '<menu>/add'
+func (b *staticBackend) Create(run commandRunner, v map[string]string) (string, error) {
+	cmd := []string{fmt.Sprintf("%s/add", b.menu)}
+	attrs, err := makeAttributeWords(v)
+	if err != nil {
+		return "", err
+	}
+	cmd = append(cmd, attrs...)
+	rep, err := run(cmd)
+	if err != nil {
+		return "", b.wrapError(err)
+	}
+	if rep.Done == nil {
+		// dry-run replies are empty
+		return "", nil
+	}
+	return rep.Done.Map["ret"], nil
+}
+
+// Calls routeros '<menu>/set'
+func (b *staticBackend) Update(run commandRunner, id string, v map[string]string) error {
+	cmd := []string{fmt.Sprintf("%s/set", b.menu)}
+	attr, err := makeAttributeWord(".id", id)
+	if err != nil {
+		return err
+	}
+	cmd = append(cmd, attr)
+	attrs, err := makeAttributeWords(v)
+	if err != nil {
+		return err
+	}
+	cmd = append(cmd, attrs...)
+	_, err = run(cmd)
+	return b.wrapError(err)
+}
+
+// Calls routeros '<menu>/remove'.
+// Multiple records are removed via a single call - routeros accepts a comma-separated list of ids.
+func (b *staticBackend) Delete(run commandRunner, ids ...string) error {
+	cmd := []string{fmt.Sprintf("%s/remove", b.menu)}
+	attr, err := makeAttributeWord(".id", strings.Join(ids, ","))
+	if err != nil {
+		return err
+	}
+	cmd = append(cmd, attr)
+	_, err = run(cmd)
+	return b.wrapError(err)
+}
+
+// Calls routeros '<menu>/print'.
+// Only the attributes within [dnsRecordProplist] are requested.
+func (b *staticBackend) List(run commandRunner) ([]dnsRecord, error) {
+	rep, err := run([]string{fmt.Sprintf("%s/print", b.menu), fmt.Sprintf("=.proplist=%s", dnsRecordProplist)})
+	if err != nil {
+		return []dnsRecord{}, b.wrapError(err)
+	}
+	rs := make([]dnsRecord, 0, len(rep.Re))
+	for i, s := range rep.Re {
+		// release the sentence once parsed - allowing it to be garbage collected
+		rep.Re[i] = nil
+		rs = append(rs, parseDnsRecord(s))
+	}
+	return rs, nil
+}
+
+// Calls routeros '<menu>/print' - counting (rather than listing) records
+func (b *staticBackend) Check(run commandRunner) error {
+	_, err := run([]string{fmt.Sprintf("%s/print", b.menu), "=count-only="})
+	return b.wrapError(err)
+}
+
+// The attributes requested when listing routeros ip dns records - see [dnsRecord]
+var dnsRecordProplist = strings.Join([]string{
+	".id",
+	"address",
+	"address-list",
+	"cname",
+	"comment",
+	"forward-to",
+	"match-subdomain",
+	"mx-exchange",
+	"mx-preference",
+	"name",
+	"ns",
+	"srv-port",
+	"srv-priority",
+	"srv-target",
+	"srv-weight",
+	"text",
+	"ttl",
+	"type",
+}, ",")
+
+// Parses a [proto.Sentence] returned by routeros into a [dnsRecord].
+// Unknown attributes are ignored.
+// Records lacking a type (e.g., those created manually - as routeros omits the default type) are normalized to 'A' - or 'AAAA' for ipv6 addresses.
+// As both listed records and records being written (see [client.setRecordComment]) are parsed, records are matched (and hashed) consistently regardless of how their type is stored.
+func parseDnsRecord(s *proto.Sentence) dnsRecord {
+	r := dnsRecord{}
+	for _, p := range s.List {
+		switch p.Key {
+		case ".id":
+			r.Id = p.Value
+		case "address":
+			r.Address = p.Value
+		case "address-list":
+			r.AddressList = p.Value
+		case "cname":
+			r.CName = p.Value
+		case "comment":
+			r.Comment = p.Value
+		case "forward-to":
+			r.ForwardTo = p.Value
+		case "match-subdomain":
+			r.MatchSubdomain = p.Value
+		case "mx-exchange":
+			r.MxExchange = p.Value
+		case "mx-preference":
+			r.MxPreference = p.Value
+		case "name":
+			r.Name = p.Value
+		case "ns":
+			r.Ns = p.Value
+		case "srv-port":
+			r.SrvPort = p.Value
+		case "srv-priority":
+			r.SrvPriority = p.Value
+		case "srv-target":
+			r.SrvTarget = p.Value
+		case "srv-weight":
+			r.SrvWeight = p.Value
+		case "text":
+			r.Text = p.Value
+		case "ttl":
+			r.Ttl = p.Value
+		case "type":
+			r.Type = p.Value
+		}
+	}
+	if r.Type == "" {
+		r.Type = "A"
+		ip := net.ParseIP(r.Address)
+		if ip != nil && ip.To4() == nil {
+			r.Type = "AAAA"
+		}
+	}
+	return r
+}
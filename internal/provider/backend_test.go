@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-routeros/routeros/v3"
+	"github.com/go-routeros/routeros/v3/proto"
+)
+
+// Returns a [commandRunner] that encodes commands as routeros api sentences (and decodes them) before running them against the [stubRouter]
+func newWireRunner(t *testing.T, sr *stubRouter) commandRunner {
+	return func(cmd []string) (*routeros.Reply, error) {
+		b := &bytes.Buffer{}
+		w := proto.NewWriter(b)
+		w.BeginSentence()
+		for _, word := range cmd {
+			w.WriteWord(word)
+		}
+		err := w.EndSentence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := proto.NewReader(b).ReadSentence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ws := []string{s.Word}
+		for _, p := range s.List {
+			ws = append(ws, "="+p.Key+"="+p.Value)
+		}
+		return sr.run(ws)
+	}
+}
+
+func TestMakeAttributeWord(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		expected string
+		err      bool
+	}{
+		{name: "plain", key: "name", value: "example.com", expected: "=name=example.com"},
+		{name: "empty value", key: "comment", value: "", expected: "=comment="},
+		{name: "equals in value", key: "text", value: "v=spf1 -all", expected: "=text=v=spf1 -all"},
+		{name: "empty key", key: "", value: "v", err: true},
+		{name: "equals in key", key: "te=xt", value: "v", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := makeAttributeWord(test.key, test.value)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected error, got %q", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != test.expected {
+				t.Errorf("got %q, expected %q", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestStaticBackendRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "spf", text: "v=spf1 ip4:192.0.2.0/24 include:_spf.example.com ~all"},
+		{name: "dkim", text: "v=DKIM1; k=rsa; p=MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAu5fGjbTW+2hvAH/7c6Vt0j3K9Ltj6j0yMZW/PjWzWv7ZQ4Ew8t1QR1sP+x1RsGmL3vO8C0zF3qx6k5f0j5vQpw=="},
+		{name: "quotes", text: `"quoted" and 'single' and \"escaped\"`},
+		{name: "equals", text: "a=b==c="},
+		{name: "leading equals", text: "=value"},
+		{name: "non-ascii", text: "héllo wörld – ✓ 日本語"},
+		{name: "whitespace", text: "  leading and trailing\ttabs  "},
+		{name: "long", text: strings.Repeat("x", 1000)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := newStaticBackend(&ClientOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			run := newWireRunner(t, newStubRouter())
+			v := map[string]string{"name": "example.com", "text": test.text, "type": "TXT"}
+			id, err := b.Create(run, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs, err := b.List(run)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rs) != 1 || rs[0].Id != id {
+				t.Fatalf("listed %v, expected record %s", rs, id)
+			}
+			if rs[0].Text != test.text {
+				t.Errorf("created text %q, listed %q", test.text, rs[0].Text)
+			}
+
+			v["text"] = test.text + "=updated"
+			err = b.Update(run, id, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs, err = b.List(run)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rs[0].Text != v["text"] {
+				t.Errorf("updated text %q, listed %q", v["text"], rs[0].Text)
+			}
+		})
+	}
+}
+
+func TestParseDnsRecordType(t *testing.T) {
+	tests := []struct {
+		name     string
+		pairs    []proto.Pair
+		expected string
+	}{
+		{name: "typeless ipv4", pairs: []proto.Pair{{Key: "address", Value: "10.0.0.1"}}, expected: "A"},
+		{name: "typeless ipv6", pairs: []proto.Pair{{Key: "address", Value: "2001:db8::1"}}, expected: "AAAA"},
+		{name: "typeless ipv4-mapped ipv6", pairs: []proto.Pair{{Key: "address", Value: "::ffff:10.0.0.1"}}, expected: "A"},
+		{name: "typeless without address", pairs: []proto.Pair{{Key: "name", Value: "example.com"}}, expected: "A"},
+		{name: "typed", pairs: []proto.Pair{{Key: "address", Value: "2001:db8::1"}, {Key: "type", Value: "A"}}, expected: "A"},
+		{name: "cname", pairs: []proto.Pair{{Key: "cname", Value: "example.com"}, {Key: "type", Value: "CNAME"}}, expected: "CNAME"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &proto.Sentence{Word: "!re", List: append([]proto.Pair{{Key: ".id", Value: "*1"}, {Key: "name", Value: "example.com"}}, test.pairs...)}
+			r := parseDnsRecord(s)
+			if r.Type != test.expected {
+				t.Errorf("type %q, expected %q", r.Type, test.expected)
+			}
+			if r.Id != "*1" {
+				t.Errorf("id %q, expected *1", r.Id)
+			}
+		})
+	}
+}
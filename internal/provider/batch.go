@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Options controlling how large syncs are split into batches (see [provider.applyBatches])
+type BatchOpts struct {
+	// The delay between batches
+	Delay time.Duration
+	// The maximum number of changes within a batch - batching is disabled when 0
+	Size uint
+}
+
+// Splits changes into batches of at most the given number of changes (see [BatchOpts]).
+// Changes to the same dns name are kept within the same batch (preserving the order in which they're applied) - as a result, a batch can exceed the size if a single name has more changes.
+// An update (a pair of endpoints) counts as a single change.
+// Names are ordered such that records are created before the records depending upon them (see [orderNames]).
+func splitChanges(ch *plan.Changes, s uint) []*plan.Changes {
+	ns := []string{}
+	nchs := map[string]*plan.Changes{}
+	getNameChanges := func(e *endpoint.Endpoint) *plan.Changes {
+		n := normalizeDnsName(e.DNSName)
+		nch, ok := nchs[n]
+		if !ok {
+			nch = &plan.Changes{}
+			nchs[n] = nch
+			ns = append(ns, n)
+		}
+		return nch
+	}
+	for _, e := range ch.Create {
+		nch := getNameChanges(e)
+		nch.Create = append(nch.Create, e)
+	}
+	for _, e := range ch.Delete {
+		nch := getNameChanges(e)
+		nch.Delete = append(nch.Delete, e)
+	}
+	for _, e := range ch.UpdateNew {
+		nch := getNameChanges(e)
+		nch.UpdateNew = append(nch.UpdateNew, e)
+	}
+	for _, e := range ch.UpdateOld {
+		nch := getNameChanges(e)
+		nch.UpdateOld = append(nch.UpdateOld, e)
+	}
+
+	// dependencies (see [endpointDependencies]) are placed within the same or an earlier batch than their dependents
+	ns = orderNames(ns, func(n string) []string {
+		ds := []string{}
+		for _, e := range append(slices.Clone(nchs[n].Create), nchs[n].UpdateNew...) {
+			for _, d := range endpointDependencies(e) {
+				dch, ok := nchs[d]
+				if ok && len(dch.Create)+len(dch.UpdateNew) > 0 {
+					ds = append(ds, d)
+				}
+			}
+		}
+		return ds
+	})
+
+	bs := []*plan.Changes{}
+	var b *plan.Changes
+	bn := 0
+	for _, n := range ns {
+		nch := nchs[n]
+		nn := len(nch.Create) + len(nch.Delete) + max(len(nch.UpdateNew), len(nch.UpdateOld))
+		if b == nil || bn+nn > int(s) {
+			b = &plan.Changes{}
+			bs = append(bs, b)
+			bn = 0
+		}
+		b.Create = append(b.Create, nch.Create...)
+		b.Delete = append(b.Delete, nch.Delete...)
+		b.UpdateNew = append(b.UpdateNew, nch.UpdateNew...)
+		b.UpdateOld = append(b.UpdateOld, nch.UpdateOld...)
+		bn += nn
+	}
+	return bs
+}
+
+// Combines the outcomes of two batches of a sync - see [provider.applyBatches]
+func mergeApplyStatus(a ApplyStatus, b ApplyStatus) ApplyStatus {
+	a.CnameLoops += b.CnameLoops
+	a.Created += b.Created
+	a.Deleted += b.Deleted
+	a.Failures = append(a.Failures, b.Failures...)
+	a.Failed = len(a.Failures)
+	a.Protected += b.Protected
+	a.QuotaExceeded += b.QuotaExceeded
+	a.Results = append(a.Results, b.Results...)
+	a.RolledBack = a.RolledBack || b.RolledBack
+	a.Updated += b.Updated
+	if b.Serial != "" {
+		a.Serial = b.Serial
+	}
+	return a
+}
+
+// Applies changes in batches (see [BatchOpts]) - bounding the number of changes applied by each call to [provider.applyChanges].
+// Batches are applied sequentially (waiting for the configured delay between them) - stopping at the first batch that fails.
+// Retries are naturally incremental - external-dns plans its retry against the records that exist, so changes applied by earlier batches aren't repeated.
+// Changes are applied as a single batch when batching is disabled.
+func (p *provider) applyBatches(co context.Context, ch *plan.Changes) (ApplyStatus, error) {
+	if p.batch.Size == 0 {
+		return p.applyChanges(co, ch)
+	}
+	bs := splitChanges(ch, p.batch.Size)
+	if len(bs) <= 1 {
+		return p.applyChanges(co, ch)
+	}
+	l := p.contextLogger(co)
+
+	as := ApplyStatus{Failures: []ApplyFailure{}, Results: []ApplyResult{}, Time: time.Now()}
+	finish := func(err error) (ApplyStatus, error) {
+		as.Duration = time.Since(as.Time).String()
+		// verification is performed (and reported) per batch
+		ss := as
+		ss.Results = nil
+		p.statusMutex.Lock()
+		p.applyStatus = ss
+		p.statusMutex.Unlock()
+		return as, err
+	}
+	for i := 0; i < len(bs); i++ {
+		if i > 0 && p.batch.Delay > 0 {
+			select {
+			case <-co.Done():
+				return finish(fmt.Errorf("batch %d of %d: %w", i+1, len(bs), co.Err()))
+			case <-time.After(p.batch.Delay):
+			}
+		}
+		b := bs[i]
+		l.Info(fmt.Sprintf("applying batch %d of %d (%d changes)", i+1, len(bs), len(b.Create)+len(b.Delete)+len(b.UpdateNew)))
+		bas, err := p.applyChanges(co, b)
+		as = mergeApplyStatus(as, bas)
+		if err != nil {
+			return finish(fmt.Errorf("batch %d of %d: %w", i+1, len(bs), err))
+		}
+	}
+	return finish(nil)
+}
@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Returns n synthetic endpoints - a mix of A, CNAME and TXT records
+func benchEndpoints(n int) []*endpoint.Endpoint {
+	es := make([]*endpoint.Endpoint, 0, n)
+	for i := 0; i < n; i++ {
+		dn := fmt.Sprintf("record-%d.example.com", i)
+		var e *endpoint.Endpoint
+		switch i % 4 {
+		case 1:
+			e = endpoint.NewEndpointWithTTL(dn, endpoint.RecordTypeCNAME, 300, fmt.Sprintf("record-%d.example.com", i-1))
+		case 3:
+			e = endpoint.NewEndpointWithTTL(dn, endpoint.RecordTypeTXT, 300, fmt.Sprintf("v=spf1 ip4:10.0.0.%d -all", i%256))
+		default:
+			e = endpoint.NewEndpointWithTTL(dn, endpoint.RecordTypeA, 300, fmt.Sprintf("10.%d.%d.%d", i>>16&255, i>>8&255, i&255))
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+// Returns deep copies of the given endpoints - applying changes normalizes (and so modifies) their endpoints
+func cloneEndpoints(es []*endpoint.Endpoint) []*endpoint.Endpoint {
+	ces := make([]*endpoint.Endpoint, 0, len(es))
+	for _, e := range es {
+		ces = append(ces, e.DeepCopy())
+	}
+	return ces
+}
+
+// Creates a [client] (see [newStubClient]) whose router holds n managed records (see [benchEndpoints])
+func newBenchClient(b *testing.B, n int) (*client, *stubRouter) {
+	sr := newStubRouter()
+	c := newStubClient(b, ClientOpts{}, sr)
+	err := c.withClient(func() error {
+		for _, e := range benchEndpoints(n) {
+			err := c.CreateEndpoint(e)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return c, sr
+}
+
+func BenchmarkListEndpoints(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("records=%d", n), func(b *testing.B) {
+			c, _ := newBenchClient(b, n)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				es, err := c.ListEndpoints()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(es) != n {
+					b.Fatalf("listed %d endpoints, expected %d", len(es), n)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkApplyChanges(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("records=%d", n), func(b *testing.B) {
+			c, sr := newBenchClient(b, n)
+			es, err := c.ListEndpoints()
+			if err != nil {
+				b.Fatal(err)
+			}
+			// a tenth of the records are each created, updated and deleted
+			ch := &plan.Changes{}
+			for i, e := range es {
+				switch i % 10 {
+				case 0:
+					ch.Delete = append(ch.Delete, e)
+				case 1:
+					ue := e.DeepCopy()
+					ue.RecordTTL = 600
+					ch.UpdateOld = append(ch.UpdateOld, e)
+					ch.UpdateNew = append(ch.UpdateNew, ue)
+				}
+			}
+			for _, e := range benchEndpoints(n + n/10)[n:] {
+				ch.Create = append(ch.Create, e)
+			}
+			p, err := NewProvider(&ProviderOpts{Client: c})
+			if err != nil {
+				b.Fatal(err)
+			}
+			sb := c.backend.(*stubBackend)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				sb.router = sr.clone()
+				ich := &plan.Changes{Create: cloneEndpoints(ch.Create), UpdateOld: cloneEndpoints(ch.UpdateOld), UpdateNew: cloneEndpoints(ch.UpdateNew), Delete: cloneEndpoints(ch.Delete)}
+				b.StartTimer()
+				err := p.ApplyChanges(context.Background(), ich)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Lists the records of a 10k-record table - see [parseDnsRecord] and [staticBackend.List]
+func BenchmarkListDnsRecords(b *testing.B) {
+	n := 10000
+	c, _ := newBenchClient(b, n)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rs, err := c.listDnsRecords()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(rs) != n {
+			b.Fatalf("listed %d records, expected %d", len(rs), n)
+		}
+	}
+}
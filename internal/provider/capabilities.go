@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"encoding/json"
+	"slices"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Describes the provider to external-dns during webhook negotiation (see [provider.Capabilities]).
+// Serialized as the provider's domain filter extended with additional fields - clients that only understand the domain filter ignore the rest.
+type Capabilities struct {
+	DomainFilter     endpoint.DomainFilter
+	ProviderSpecific []string
+	RecordTypes      []string
+}
+
+// Serializes the [Capabilities] - flattening the domain filter's fields into the top-level object
+func (c Capabilities) MarshalJSON() ([]byte, error) {
+	d, err := json.Marshal(c.DomainFilter)
+	if err != nil {
+		return nil, err
+	}
+	v := map[string]any{}
+	err = json.Unmarshal(d, &v)
+	if err != nil {
+		return nil, err
+	}
+	v["providerSpecific"] = c.ProviderSpecific
+	v["supportedRecordTypes"] = c.RecordTypes
+	return json.Marshal(v)
+}
+
+// Returns the [Capabilities] of the provider - its domain filter, supported record types and supported provider-specific properties
+func (p *provider) Capabilities() Capabilities {
+	rts := []string{}
+	for rt := range recordTypes {
+		rts = append(rts, rt)
+	}
+	slices.Sort(rts)
+	return Capabilities{
+		DomainFilter: p.GetDomainFilter(),
+		ProviderSpecific: []string{
+			providerSpecificAddressList,
+			providerSpecificComment,
+			providerSpecificMatchSubdomain,
+			providerSpecificRoute,
+			providerSpecificWebhookComment,
+			providerSpecificWebhookRoute,
+		},
+		RecordTypes: rts,
+	}
+}
@@ -1,41 +1,89 @@
 package provider
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"strconv"
+	"maps"
+	"net"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-routeros/routeros/v3"
+	"github.com/go-routeros/routeros/v3/proto"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
 // The public interface for the routeros client
 type Client interface {
-	Health() error
+	Health(c context.Context) (HealthReport, error)
+	CheckBackend() error
+	Stats() []ClientStats
+	Info() (RouterInfo, error)
+	MigrateRecords() ([]MigratedRecord, error)
 	ListEndpoints() ([]*endpoint.Endpoint, error)
 	CreateEndpoint(e *endpoint.Endpoint) error
+	CreateOrUpdateEndpoint(e *endpoint.Endpoint) error
 	DeleteEndpoint(e *endpoint.Endpoint) error
+	DeleteEndpoints(es []*endpoint.Endpoint) error
+	AcquireLock(n string, owner string, lease time.Duration) error
+	AdoptRecords(match AdoptMatchFunc) ([]*endpoint.Endpoint, error)
+	BumpSerial(o SerialOpts) (string, error)
+	CheckIntegrity(repair bool) ([]*endpoint.Endpoint, error)
+	ReleaseLock(n string, owner string) error
+	Rollback(j *Journal) error
+	WithCommandStats(cs *CommandStats) Client
+	WithJournal(j *Journal) Client
+	WithDryRun(cb DryRunCallback) Client
+	WithLogAttrs(args ...any) Client
+	WithLogger(l *slog.Logger) Client
 }
 
 // The internal struct for a routeros client holding state and configuration.
 type client struct {
-	address  string
-	client   *routeros.Client
-	logger   *slog.Logger
-	password string
-	username string
+	adopt           bool
+	address         string
+	backend         recordBackend
+	certFingerprint []byte
+	client          *routeros.Client
+	commandStats    *CommandStats
+	conflictPolicy  string
+	dryRun          DryRunCallback
+	fallbackIP      string
+	identity        *routerIdentity
+	opsStats        *OpsStats
+	journal         *Journal
+	logger          *slog.Logger
+	metadataStore   string
+	password        string
+	resolver        *net.Resolver
+	trace           bool
+	txtMaxLength    int
+	username        string
 }
 
 // Options passed to [NewClient] when creating a new [client].
 type ClientOpts struct {
-	Address  string
-	Logger   *slog.Logger
-	Password string
-	Username string
+	Address         string
+	AdoptExisting   bool
+	Backend         string
+	CertFingerprint string
+	ConflictPolicy  string
+	FallbackIP      string
+	Logger          *slog.Logger
+	Menu            string
+	MetadataStore   string
+	Password        string
+	Resolver        string
+	Trace           bool
+	TxtMaxLength    uint
+	Username        string
 }
 
 // Creates a new [client] struct using the provided [ClientOpts] arguments.
@@ -45,35 +93,200 @@ func NewClient(o *ClientOpts) (*client, error) {
 	if l == nil {
 		l = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
-	cs := strings.Split(o.Address, ":")
-	if len(cs) != 2 {
-		return &client{}, fmt.Errorf("address not <host>:<port> format")
+	_, _, err := parseRouterAddress(o.Address)
+	if err != nil {
+		return &client{}, err
+	}
+	if o.FallbackIP != "" && net.ParseIP(o.FallbackIP) == nil {
+		return &client{}, fmt.Errorf("fallback ip %s not an ip address", o.FallbackIP)
+	}
+	r, err := newResolver(o.Resolver)
+	if err != nil {
+		return &client{}, err
+	}
+	bn := o.Backend
+	if bn == "" {
+		bn = BackendStatic
+	}
+	nb, ok := recordBackends[bn]
+	if !ok {
+		return &client{}, fmt.Errorf("unrecognized backend %s", bn)
 	}
-	_, err := strconv.ParseUint(cs[1], 0, 0)
+	b, err := nb(o)
 	if err != nil {
-		return &client{}, fmt.Errorf("port invalid: %w", err)
+		return &client{}, err
+	}
+	ms := o.MetadataStore
+	if ms == "" {
+		ms = MetadataStoreComment
+	}
+	if ms != MetadataStoreComment && ms != MetadataStoreTxt {
+		return &client{}, fmt.Errorf("unrecognized metadata store %s", ms)
+	}
+	if o.AdoptExisting && ms != MetadataStoreComment {
+		// adopted records are tagged via their comments
+		return &client{}, fmt.Errorf("adopting existing records requires metadata store %s", MetadataStoreComment)
+	}
+	cp := o.ConflictPolicy
+	if cp == "" {
+		cp = ConflictPolicyIgnore
+	}
+	if !slices.Contains(conflictPolicies, cp) {
+		return &client{}, fmt.Errorf("unrecognized conflict policy %s", cp)
+	}
+	if cp != ConflictPolicyIgnore && ms != MetadataStoreComment {
+		// unmanaged records are identified by their comments
+		return &client{}, fmt.Errorf("conflict policy %s requires metadata store %s", cp, MetadataStoreComment)
+	}
+	if o.TxtMaxLength > 0 && ms != MetadataStoreComment {
+		// split values are reassembled using per-record metadata
+		return &client{}, fmt.Errorf("txt max length requires metadata store %s", MetadataStoreComment)
+	}
+	var fp []byte
+	if o.CertFingerprint != "" {
+		fp, err = parseCertFingerprint(o.CertFingerprint)
+		if err != nil {
+			return &client{}, err
+		}
 	}
 	return &client{
-		address:  o.Address,
-		logger:   l,
-		password: o.Password,
-		username: o.Username,
+		adopt:           o.AdoptExisting,
+		address:         o.Address,
+		backend:         b,
+		certFingerprint: fp,
+		conflictPolicy:  cp,
+		fallbackIP:      o.FallbackIP,
+		identity:        &routerIdentity{},
+		opsStats:        &OpsStats{},
+		logger:          l,
+		metadataStore:   ms,
+		password:        o.Password,
+		resolver:        r,
+		trace:           o.Trace,
+		txtMaxLength:    int(o.TxtMaxLength),
+		username:        o.Username,
 	}, nil
 }
 
+// Returns a copy of the [client] whose logger includes the provided attributes.
+// Used to attach request-scoped attributes (e.g., a request id) to client logs.
+// The copy does not share a routeros connection with the original [client].
+func (c *client) WithLogAttrs(args ...any) Client {
+	cc := *c
+	cc.client = nil
+	cc.logger = c.logger.With(args...)
+	return &cc
+}
+
+// Returns a copy of the [client] logging via the given logger
+func (c *client) WithLogger(l *slog.Logger) Client {
+	cc := *c
+	cc.client = nil
+	cc.logger = l
+	return &cc
+}
+
+// Callback receiving routeros api commands that a dry-run [client] would have executed
+type DryRunCallback func(cmd []string)
+
+// Returns a copy of the [client] in dry-run mode.
+// Read-only ('print') commands are executed - all other commands are passed to the callback instead of being executed.
+// The copy does not share a routeros connection with the original [client].
+func (c *client) WithDryRun(cb DryRunCallback) Client {
+	cc := *c
+	cc.client = nil
+	cc.dryRun = cb
+	return &cc
+}
+
+// Runs a routeros api command using the connection opened by [client.withClient].
+// In dry-run mode (see [client.WithDryRun]), only read-only commands are executed.
+// Executed commands are recorded within the client's [CommandStats] (if present).
+func (c *client) run(cmd []string) (*routeros.Reply, error) {
+	if c.dryRun != nil && !strings.HasSuffix(cmd[0], "/print") {
+		c.dryRun(cmd)
+		return &routeros.Reply{}, nil
+	}
+	st := time.Now()
+	rep, err := c.client.RunArgs(cmd)
+	d := time.Since(st)
+	c.opsStats.add(d)
+	if c.commandStats != nil {
+		c.commandStats.add(c.identity.get(), d)
+	}
+	return rep, err
+}
+
+// Opens a routeros api connection - logging in with the [client]'s credentials.
+// Traffic is counted within the client's [OpsStats].
+// Hostnames are resolved as described by [client.dialRouter].
+// Connects via api-ssl when a certificate is pinned (see [ClientOpts.CertFingerprint]) and traces sentences when tracing (see [ClientOpts.Trace]).
+// The connection is bound to the context - its deadline is applied to the connection and, once done, pending api calls fail.
+// Returns a function unbinding the connection from the context.
+// Returns a [ProtocolMismatchError] if the router's address doesn't serve the routeros api.
+func (c *client) dial(co context.Context) (*routeros.Client, func() bool, error) {
+	conn, err := c.dialRouter(co)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.opsStats.connections.Add(1)
+	conn = &opsStatsConn{Conn: conn, stats: c.opsStats}
+	if c.certFingerprint != nil {
+		conn = tls.Client(conn, newPinnedTlsConfig(c.certFingerprint))
+	}
+	if c.trace {
+		// traced after decryption
+		conn = newTraceConn(conn, c.logger)
+	}
+	rc, err := routeros.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	dl, _ := co.Deadline()
+	ld := time.Now().Add(loginTimeout)
+	if !dl.IsZero() && dl.Before(ld) {
+		ld = dl
+	}
+	conn.SetDeadline(ld)
+	err = rc.Login(c.username, c.password)
+	if err != nil {
+		rc.Close()
+		return nil, nil, c.wrapLoginError(err)
+	}
+	conn.SetDeadline(dl)
+	stop := context.AfterFunc(co, func() {
+		conn.SetDeadline(time.Now())
+	})
+	return rc, stop, nil
+}
+
 // Callback used as part of the [withClient] implementation
 type withClientCallback func() error
 
 // Function that handles opening and closing a connection to routeros.
 // Attaches the connected client to the parent [client] object.
+// While connected, the client's logs are labelled with the identity of the routeros device (see [client.discoverIdentity]).
 func (c *client) withClient(cb withClientCallback) error {
+	return c.withClientContext(context.Background(), cb)
+}
+
+// Implements [client.withClient] - binding a newly opened connection to the context (see [client.dial]).
+func (c *client) withClientContext(co context.Context, cb withClientCallback) error {
 	cc := c.client == nil
 	if cc {
-		rc, err := routeros.Dial(c.address, c.username, c.password)
+		rc, stop, err := c.dial(co)
 		if err != nil {
 			return err
 		}
+		defer stop()
 		c.client = rc
+		id := c.discoverIdentity()
+		if id != "" {
+			l := c.logger
+			c.logger = l.With("router-identity", id)
+			defer func() { c.logger = l }()
+		}
 	}
 
 	defer func() {
@@ -87,122 +300,332 @@ func (c *client) withClient(cb withClientCallback) error {
 	return cb()
 }
 
-// Performs a health check of the client by querying a simple routeros api
-// If the query fails and returns an error, this indicates the client is unhealthy
-func (c *client) Health() error {
+// Checks that routeros supports the [client]'s backend (see [recordBackend]).
+// Returns a [MenuUnavailableError] if the backend's menu is unavailable.
+func (c *client) CheckBackend() error {
 	return c.withClient(func() error {
-		_, err := c.client.RunArgs([]string{"/system/resource/print"})
-		return err
+		return c.backend.Check(c.run)
+	})
+}
+
+// Details describing the connected routeros device
+type RouterInfo struct {
+	Identity string `json:"identity"`
+	Latency  string `json:"latency"`
+	Uptime   string `json:"uptime"`
+	Version  string `json:"version"`
+}
+
+// Queries routeros for details describing the connected device (see [client.queryRouter])
+func (c *client) Info() (RouterInfo, error) {
+	hr, err := c.queryRouter(context.Background())
+	if err != nil {
+		return RouterInfo{}, err
+	}
+	return RouterInfo{Identity: hr.Identity, Latency: hr.Latency.String(), Uptime: hr.Uptime, Version: hr.Version}, nil
+}
+
+// Queries routeros for details describing the connected device - abandoning the queries once the context is done.
+// Latency is measured as the round-trip time of the '/system/resource/print' api call.
+// Returns an error if any api call fails
+func (c *client) queryRouter(co context.Context) (HealthReport, error) {
+	hr := HealthReport{}
+	err := c.withClientContext(co, func() error {
+		st := time.Now()
+		rep, err := c.run([]string{"/system/resource/print"})
+		if err != nil {
+			return err
+		}
+		hr.Latency = time.Since(st)
+		if len(rep.Re) > 0 {
+			hr.Uptime = rep.Re[0].Map["uptime"]
+			hr.Version = rep.Re[0].Map["version"]
+		}
+		rep, err = c.run([]string{"/system/identity/print"})
+		if err != nil {
+			return err
+		}
+		if len(rep.Re) > 0 {
+			hr.Identity = rep.Re[0].Map["name"]
+		}
+		return nil
 	})
+	if err != nil {
+		return HealthReport{}, err
+	}
+	return hr, nil
 }
 
-// Internal method that calls routeros '/ip/dns/static/add' with a [map[string]string] that should have the same shape as a routeros ip dns record.
+// Internal method that creates a record via the [client]'s [recordBackend] with a [map[string]string] that should have the same shape as a routeros ip dns record.
+// If the [client] has a [Journal], the creation is recorded.
 // Returns an error if the api call fails
 func (c *client) createDnsRecord(v map[string]string) error {
 	return c.withClient(func() error {
 		c.logger.Debug(fmt.Sprintf("create routeros dns record %s %s", v["type"], v["name"]))
-		cmd := []string{"/ip/dns/static/add"}
-		for k, v := range v {
-			attr := fmt.Sprintf("=%s=%s", k, v)
-			cmd = append(cmd, attr)
+		id, err := c.backend.Create(c.run, v)
+		if err != nil {
+			return err
 		}
-		_, err := c.client.RunArgs(cmd)
-		return err
+		if c.journal != nil && id != "" {
+			c.journal.add(c.address, fmt.Sprintf("create %s %s (%s)", v["type"], v["name"], id), func(uc *client) error {
+				return uc.deleteDnsRecord(dnsRecord{Id: id})
+			})
+		}
+		return nil
 	})
 }
 
-// Internal method that calls routeros '/ip/dns/static/remove' with a [map[string]string] that should have the same shape as a routeros ip dns record.
+// Internal method that updates the given [dnsRecord] via the [client]'s [recordBackend] - setting the attributes within v.
+// If the [client] has a [Journal], the update is recorded (restoring the record's prior attributes when rolled back).
 // Returns an error if the api call fails
-func (c *client) deleteDnsRecord(v map[string]string) error {
+func (c *client) updateDnsRecord(r dnsRecord, v map[string]string) error {
 	return c.withClient(func() error {
-		c.logger.Debug(fmt.Sprintf("delete routeros dns record %s", v[".id"]))
-		cmd := []string{"/ip/dns/static/remove"}
-		cmd = append(cmd, fmt.Sprintf("=.id=%s", v[".id"]))
-		_, err := c.client.RunArgs(cmd)
-		return err
+		c.logger.Debug(fmt.Sprintf("update routeros dns record %s", r.Id))
+		err := c.backend.Update(c.run, r.Id, v)
+		if err != nil {
+			return err
+		}
+		if c.journal != nil {
+			ra := r.attributes()
+			pv := map[string]string{}
+			for k := range v {
+				pv[k] = ra[k]
+			}
+			c.journal.add(c.address, fmt.Sprintf("update %s %s (%s)", r.Type, r.Name, r.Id), func(uc *client) error {
+				return uc.updateDnsRecord(r, pv)
+			})
+		}
+		return nil
+	})
+}
+
+// Internal method that lists records not managed by the provider via the [client]'s [recordBackend].
+// Only supported with the comment metadata store - where unmanaged records are those whose comments lack metadata.
+func (c *client) listUnmanagedDnsRecords() ([]dnsRecord, error) {
+	rs := []dnsRecord{}
+	err := c.withClient(func() error {
+		ars, err := c.backend.List(c.run)
+		if err != nil {
+			return err
+		}
+		for _, r := range ars {
+			if strings.HasPrefix(r.Comment, recordMetadataPrefix) {
+				continue
+			}
+			rs = append(rs, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return []dnsRecord{}, err
+	}
+	return rs, nil
+}
+
+// Internal method that deletes the given [dnsRecord] via the [client]'s [recordBackend].
+// If the [client] has a [Journal], the deletion is recorded (recreating the record from its attributes when rolled back).
+// Returns an error if the api call fails
+func (c *client) deleteDnsRecord(r dnsRecord) error {
+	return c.deleteDnsRecords([]dnsRecord{r})
+}
+
+// Internal method that deletes the given [dnsRecord] list via a single call to the [client]'s [recordBackend].
+// If the [client] has a [Journal], each deletion is recorded (see [client.deleteDnsRecord]).
+// Returns an error if the api call fails
+func (c *client) deleteDnsRecords(rs []dnsRecord) error {
+	if len(rs) == 0 {
+		return nil
+	}
+	return c.withClient(func() error {
+		ids := []string{}
+		for _, r := range rs {
+			ids = append(ids, r.Id)
+		}
+		c.logger.Debug(fmt.Sprintf("delete routeros dns records %s", strings.Join(ids, ",")))
+		err := c.backend.Delete(c.run, ids...)
+		if err != nil {
+			return err
+		}
+		if c.journal != nil {
+			for _, r := range rs {
+				c.journal.add(c.address, fmt.Sprintf("delete %s %s (%s)", r.Type, r.Name, r.Id), func(uc *client) error {
+					return uc.createDnsRecord(r.attributes())
+				})
+			}
+		}
+		return nil
 	})
 }
 
-// Metadata stored as a comment within a routeros dns record
-type recordMetadata struct{}
+// A routeros ip dns record - holding only the attributes used by the provider.
+// Used in place of a [map[string]string] to keep memory usage low when listing large static dns tables.
+type dnsRecord struct {
+	Address        string
+	AddressList    string
+	CName          string
+	Comment        string
+	ForwardTo      string
+	Id             string
+	MatchSubdomain string
+	MxExchange     string
+	MxPreference   string
+	Name           string
+	Ns             string
+	SrvPort        string
+	SrvPriority    string
+	SrvTarget      string
+	SrvWeight      string
+	Text           string
+	Ttl            string
+	Type           string
 
-// When a routeros dns record is missing metadata via structured data stored in its comment,
-// it's because either a) the record is not managed by external-dns or b) the record is invalid.
-// If the record is invalid, the provider should attempt to clean it up.
-// If the record is not managed by external-dns, it should be ignored.
-// This is a specialized error type that helps disambiguate between these two cases.
-type NotExternalDnsRecordError struct {
-	Id string
+	// Populated by [client.listDnsRecords]
+	Metadata recordMetadata
+	// The companion TXT record holding the record's metadata (see [MetadataStoreTxt])
+	MetadataRecord *dnsRecord
 }
 
-func (e NotExternalDnsRecordError) Error() string {
-	return fmt.Sprintf("dns record %s not managed by external-dns", e.Id)
+// Returns the record's attributes as a [map[string]string] that has the same shape as a routeros ip dns record (see [client.createDnsRecord]).
+// Empty attributes (and the record id) are omitted.
+func (r dnsRecord) attributes() map[string]string {
+	v := map[string]string{
+		"address":       r.Address,
+		"address-list":  r.AddressList,
+		"cname":         r.CName,
+		"comment":       r.Comment,
+		"forward-to":    r.ForwardTo,
+		"mx-exchange":   r.MxExchange,
+		"mx-preference": r.MxPreference,
+		"name":          r.Name,
+		"ns":            r.Ns,
+		"srv-port":      r.SrvPort,
+		"srv-priority":  r.SrvPriority,
+		"srv-target":    r.SrvTarget,
+		"srv-weight":    r.SrvWeight,
+		"text":          r.Text,
+		"ttl":           r.Ttl,
+		"type":          r.Type,
+	}
+	if r.MatchSubdomain == "true" || r.MatchSubdomain == "yes" {
+		v["match-subdomain"] = "yes"
+	}
+	for k, av := range v {
+		if av == "" {
+			delete(v, k)
+		}
+	}
+	return v
 }
 
-// If a routeros dns record comment starts with this prefix, its managed by the provider.
-var recordMetadataPrefix = "external-dns:"
+// Returns the record type of the [endpoint.Endpoint] that produced the record (see [recordMetadata.Type])
+func (r dnsRecord) endpointType() string {
+	if r.Metadata.Type != "" {
+		return r.Metadata.Type
+	}
+	return r.Type
+}
 
-// Retrieves metadata from a routeros dns record
-func (c *client) getRecordMetadata(v map[string]string) (recordMetadata, error) {
-	co := v["comment"]
-	rms, ok := strings.CutPrefix(co, recordMetadataPrefix)
-	if !ok {
-		// comment does not start with prefix - is not managed by external-dns
-		return recordMetadata{}, NotExternalDnsRecordError{Id: v[".id"]}
+// Returns the routeros record type ('A' | 'AAAA') used to store an address target of an A or AAAA endpoint.
+// Allows dual-stack endpoints (e.g., an A endpoint listing both ipv4 and ipv6 addresses) to be stored as separate A and AAAA records.
+// Targets of other record types (and targets that aren't ip addresses) retain the endpoint's record type.
+func getAddressRecordType(rt string, t string) string {
+	if rt != "A" && rt != "AAAA" {
+		return rt
 	}
-	rm := recordMetadata{}
-	err := json.Unmarshal([]byte(rms), &rm)
-	if err != nil {
-		// record is managed by external-dns, but metadata is not parseable
-		return recordMetadata{}, err
+	ip := net.ParseIP(t)
+	if ip == nil {
+		return rt
+	}
+	if ip.To4() != nil {
+		return "A"
 	}
-	return rm, nil
+	return "AAAA"
 }
 
-// Internal method that calls routeros '/ip/dns/static/print' api.
+// Internal method that lists records via the [client]'s [recordBackend].
 // Filters out records that aren't managed by external-dns.
 // Adds default data to records fetched from routeros.
 // Returns an error if the api call fails.
 // Returns an error if cleaning up invalid records fail.
-func (c *client) listDnsRecords() ([]map[string]string, error) {
+func (c *client) listDnsRecords() ([]dnsRecord, error) {
 	c.logger.Debug("list routeros dns records")
-	rs := []map[string]string{}
-	irs := []map[string]string{}
+	rs := []dnsRecord{}
+	irs := []dnsRecord{}
 	err := c.withClient(func() error {
-		rep, err := c.client.RunArgs([]string{"/ip/dns/static/print", "=detail"})
+		ars, err := c.backend.List(c.run)
 		if err != nil {
 			return err
 		}
-		for _, s := range rep.Re {
-			r := s.Map
-			_, err := c.getRecordMetadata(r)
+		mrs := map[string]dnsRecord{}
+		if c.metadataStore == MetadataStoreTxt {
+			for _, r := range ars {
+				k, ok := c.parseMetadataRecordName(r)
+				if !ok {
+					continue
+				}
+				_, ok = mrs[k]
+				if ok {
+					// duplicate companion records (e.g., after a partial delete and re-create) - retain the first
+					c.logger.Debug(fmt.Sprintf("delete duplicate metadata record %s", r.Id))
+					irs = append(irs, r)
+					continue
+				}
+				mrs[k] = r
+			}
+		}
+
+		rs = make([]dnsRecord, 0, len(ars))
+		for _, r := range ars {
+			v := r.Comment
+			if c.metadataStore == MetadataStoreTxt {
+				_, ok := c.parseMetadataRecordName(r)
+				if ok {
+					// companion metadata records are not dns records managed by external-dns
+					continue
+				}
+				mr, ok := mrs[c.makeKey(r.Type, r.Name)]
+				if !ok {
+					v = ""
+				} else {
+					v = mr.Text
+					r.MetadataRecord = &mr
+				}
+			}
+			rm, err := c.getRecordMetadata(r.Id, v)
 			if err != nil {
-				_, nedre := err.(NotExternalDnsRecordError)
-				if nedre {
-					c.logger.Debug(fmt.Sprintf("ignore non-external dns record %s", r[".id"]))
+				if errors.Is(err, ErrNotManaged) {
+					c.logger.Debug(fmt.Sprintf("ignore non-external dns record %s", r.Id))
 					continue
 				}
-				c.logger.Debug(fmt.Sprintf("delete malformed dns record %s", r[".id"]))
-				c.deleteDnsRecord(r)
+				c.logger.Debug(fmt.Sprintf("delete malformed dns record %s", r.Id))
+				irs = append(irs, r)
 				continue
 			}
-			// A records are the default record type
-			if r["type"] == "" {
-				r["type"] = "A"
-			}
+			r.Metadata = rm
 			rs = append(rs, r)
 		}
+
+		// invalid records are deleted directly via the backend - their deletion is not journaled (see [Journal])
+		mids := []string{}
+		for _, r := range irs {
+			err := c.backend.Delete(c.run, r.Id)
+			if err != nil {
+				return err
+			}
+			if r.MetadataRecord != nil && !slices.Contains(mids, r.MetadataRecord.Id) {
+				mids = append(mids, r.MetadataRecord.Id)
+			}
+		}
+		for _, mid := range mids {
+			err := c.backend.Delete(c.run, mid)
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 	if err != nil {
-		return []map[string]string{}, err
-	}
-
-	for _, r := range irs {
-		err := c.deleteDnsRecord(r)
-		if err != nil {
-			return []map[string]string{}, err
-		}
+		return []dnsRecord{}, err
 	}
 
 	return rs, nil
@@ -210,138 +633,745 @@ func (c *client) listDnsRecords() ([]map[string]string, error) {
 
 // A key is used to connect [endpoint.Endpoint] and routeros ip dns records.
 // This function standardizes on this key.
+// Keys include the record type - records sharing a name but differing in type (e.g., an A record and its TXT registry record) have distinct keys.
+// Names are normalized (see [normalizeDnsName]) so that keys match regardless of case or trailing dots.
 func (c *client) makeKey(rt string, n string) string {
-	return fmt.Sprintf("%s::%s", rt, n)
+	return fmt.Sprintf("%s::%s", strings.ToUpper(rt), normalizeDnsName(n))
 }
 
+// Normalizes a dns name to match how routeros stores it - lowercased and without a trailing dot
+func normalizeDnsName(n string) string {
+	return strings.TrimSuffix(strings.ToLower(n), ".")
+}
+
+// Normalizes the dns names embedded within a target of the given record type (see [normalizeDnsName]).
+// Address targets are returned in their canonical form (e.g., ipv6 addresses are lowercased and compressed).
+// Targets of other record types (and malformed targets) are returned unchanged.
+func normalizeTarget(rt string, t string) string {
+	rtd, ok := recordTypes[rt]
+	if !ok || rtd.normalize == nil {
+		return t
+	}
+	return rtd.normalize(t)
+}
+
+// Normalizes an endpoint's dns name and targets in-place (see [normalizeDnsName], [normalizeTarget])
+func normalizeEndpoint(e *endpoint.Endpoint) {
+	uc, ok := e.GetProviderSpecificProperty(providerSpecificWebhookComment)
+	if ok {
+		// listed endpoints hold [providerSpecificComment] - the desired endpoint must match to avoid perpetual updates
+		e.DeleteProviderSpecificProperty(providerSpecificWebhookComment)
+		e.SetProviderSpecificProperty(providerSpecificComment, uc)
+	}
+	ur, ok := e.GetProviderSpecificProperty(providerSpecificWebhookRoute)
+	if ok {
+		// see [providerSpecificWebhookComment]
+		e.DeleteProviderSpecificProperty(providerSpecificWebhookRoute)
+		e.SetProviderSpecificProperty(providerSpecificRoute, ur)
+	}
+	e.DNSName = normalizeDnsName(e.DNSName)
+	for i, t := range e.Targets {
+		e.Targets[i] = normalizeTarget(e.RecordType, t)
+	}
+}
+
+// The ttl of records created from endpoints lacking a ttl - matching routeros' default
+const defaultRecordTtl = 24 * time.Hour
+
+// Provider-specific property setting the routeros 'address-list' attribute of an endpoint's records.
+// When set, routeros adds resolved addresses to the named firewall address list.
+var providerSpecificAddressList = "routeros/address-list"
+
+// Provider-specific property setting the routeros 'match-subdomain' attribute of an endpoint's records (when 'true').
+// Primarily used with FWD records to conditionally forward an entire zone.
+var providerSpecificMatchSubdomain = "routeros/match-subdomain"
+
+// Provider-specific property holding a user-provided comment - stored within record metadata and listed with the endpoint.
+// With the txt metadata store, the comment is additionally set as the comment of each of the endpoint's records.
+var providerSpecificComment = "routeros/comment"
+
+// Provider-specific property set by external-dns from the 'external-dns.alpha.kubernetes.io/webhook-routeros-comment' annotation.
+// Renamed to [providerSpecificComment] by [normalizeEndpoint].
+var providerSpecificWebhookComment = "webhook/routeros-comment"
+
+// Provider-specific property naming the route (see [RouteConfig]) whose router an endpoint's records are sent to - overriding the route selected by the endpoint's name (see [routedClient.routeEndpoint]).
+// Stored within record metadata and listed with the endpoint.
+var providerSpecificRoute = "routeros/route"
+
+// Provider-specific property set by external-dns from the 'external-dns.alpha.kubernetes.io/webhook-routeros-route' annotation.
+// Renamed to [providerSpecificRoute] by [normalizeEndpoint].
+var providerSpecificWebhookRoute = "webhook/routeros-route"
+
 // Creates a new endpoint
+// Creates one routeros record per endpoint target (e.g., an MX endpoint with two targets produces two routeros records).
+// The ipv4 and ipv6 targets of A and AAAA endpoints are stored as A and AAAA records respectively.
+// In adoption mode, existing unmanaged records matching a target are adopted rather than duplicated (see [client.adoptRecord]).
+// Other unmanaged records sharing the endpoint's name are handled according to the [client]'s conflict policy (see [ConflictPolicyIgnore]).
+// Returns an [InvalidTargetError] (prior to creating any records) if a target is invalid.
 func (c *client) CreateEndpoint(e *endpoint.Endpoint) error {
-	rm := recordMetadata{}
-	rmb, err := json.Marshal(rm)
+	err := validateEndpoint(e)
 	if err != nil {
-		// external-dns managed record is invalid - unexpected, should be caught by [listDnsRecords]
 		return err
 	}
-	com := fmt.Sprintf("%s%s", recordMetadataPrefix, string(rmb))
+	rts, coms, rms, err := c.getEndpointMetadata(e)
+	if err != nil {
+		return err
+	}
+	urs := []dnsRecord{}
+	if c.adopt || c.conflictPolicy != ConflictPolicyIgnore {
+		var err error
+		urs, err = c.listUnmanagedDnsRecords()
+		if err != nil {
+			return err
+		}
+	}
+	if c.conflictPolicy != ConflictPolicyIgnore {
+		crs := c.getConflictingRecords(urs, rts, e.DNSName, func(ur dnsRecord) bool {
+			ut, err := c.getRecordTarget(ur)
+			return err == nil && slices.ContainsFunc(e.Targets, func(t string) bool {
+				return ur.Type == getAddressRecordType(e.RecordType, t) && normalizeTarget(ur.Type, ut) == normalizeTarget(ur.Type, t)
+			})
+		})
+		for _, cr := range crs {
+			switch c.conflictPolicy {
+			case ConflictPolicyFail:
+				return ConflictingRecordError{DNSName: cr.Name, Id: cr.Id, RecordType: cr.Type}
+			case ConflictPolicySkip:
+				c.logger.Warn(fmt.Sprintf("skipping record %s %s: %s", e.RecordType, e.DNSName, ConflictingRecordError{DNSName: cr.Name, Id: cr.Id, RecordType: cr.Type}.Error()))
+				return nil
+			case ConflictPolicyOverwrite:
+				c.logger.Info(fmt.Sprintf("deleting unmanaged record %s %s (%s) conflicting with record %s %s", cr.Type, cr.Name, cr.Id, e.RecordType, e.DNSName))
+				err := c.deleteDnsRecord(cr)
+				if err != nil {
+					return err
+				}
+				urs = slices.DeleteFunc(urs, func(ur dnsRecord) bool { return ur.Id == cr.Id })
+			}
+		}
+	}
 	for _, t := range e.Targets {
-		r := map[string]string{
-			"comment": com,
-			"name":    e.DNSName,
-			"type":    e.RecordType,
-			"ttl":     time.Duration(e.RecordTTL * 1e9).String(),
-		}
-		switch e.RecordType {
-		case "A":
-			r["address"] = t
-		case "CNAME":
-			r["cname"] = t
-		case "MX":
-			ps := strings.Split(t, " ")
-			if len(ps) != 2 {
-				return fmt.Errorf("malformed mx record %s", t)
-			}
-			r["mx-preference"] = ps[0]
-			r["mx-exchange"] = ps[1]
-		case "NS":
-			r["ns"] = t
-		case "SRV":
-			ps := strings.Split(t, " ")
-			if len(ps) != 4 {
-				return fmt.Errorf("malformed srv record %s", t)
-			}
-			r["srv-priority"] = ps[0]
-			r["srv-weight"] = ps[1]
-			r["srv-port"] = ps[2]
-			r["srv-target"] = ps[3]
-		case "TXT":
-			r["text"] = t
-		default:
-			return fmt.Errorf("unsupported record type %s", e.RecordType)
-		}
-		err = c.createDnsRecord(r)
+		rt := getAddressRecordType(e.RecordType, t)
+		r, err := c.getEndpointRecord(e, t, rms[rt])
+		if err != nil {
+			return err
+		}
+		ui := slices.IndexFunc(urs, func(ur dnsRecord) bool {
+			if c.makeKey(ur.Type, ur.Name) != c.makeKey(rt, e.DNSName) {
+				return false
+			}
+			ut, err := c.getRecordTarget(ur)
+			return err == nil && normalizeTarget(rt, ut) == normalizeTarget(rt, t)
+		})
+		if ui != -1 {
+			// an existing unmanaged record matches - adopt it rather than creating a duplicate
+			ur := urs[ui]
+			urs = slices.Delete(urs, ui, ui+1)
+			c.logger.Info(fmt.Sprintf("adopting existing record %s %s (%s)", rt, e.DNSName, ur.Id))
+			err := c.adoptRecord(ur, r, rms[rt])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = c.createEndpointRecord(r, rms[rt])
 		if err != nil {
 			return err
 		}
 	}
+	if c.metadataStore == MetadataStoreTxt {
+		// companion records are created last - until then, created records are treated as unmanaged
+		for _, rt := range rts {
+			err := c.createDnsRecord(map[string]string{
+				"name": c.makeMetadataRecordName(rt, e.DNSName),
+				"text": coms[rt],
+				"ttl":  getEndpointTtl(e),
+				"type": "TXT",
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-// Deletes an endpoint
-func (c *client) DeleteEndpoint(e *endpoint.Endpoint) error {
-	rs, err := c.listDnsRecords()
+// Produces the metadata of the records created for an endpoint - keyed by record type.
+// Records may be stored with a record type differing from that of the endpoint (see [getAddressRecordType]) - the record types are returned in order of the endpoint's targets.
+// Additionally returns the encoded metadata (see [client.encodeRecordMetadata]) keyed by record type.
+func (c *client) getEndpointMetadata(e *endpoint.Endpoint) ([]string, map[string]string, map[string]recordMetadata, error) {
+	rts := []string{}
+	coms := map[string]string{}
+	rms := map[string]recordMetadata{}
+	for _, t := range e.Targets {
+		rt := getAddressRecordType(e.RecordType, t)
+		if slices.Contains(rts, rt) {
+			continue
+		}
+		rm := recordMetadata{DefaultTtl: e.RecordTTL == 0}
+		uc, ok := e.GetProviderSpecificProperty(providerSpecificComment)
+		if ok && uc != "" {
+			rm.Comment = uc
+		}
+		ur, ok := e.GetProviderSpecificProperty(providerSpecificRoute)
+		if ok && ur != "" {
+			rm.Route = ur
+		}
+		if len(e.Labels) > 0 {
+			rm.Labels = e.Labels
+		}
+		if rt != e.RecordType {
+			rm.Type = e.RecordType
+		}
+		com, err := c.encodeRecordMetadata(rm)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rts = append(rts, rt)
+		coms[rt] = com
+		rms[rt] = rm
+	}
+	return rts, coms, rms, nil
+}
+
+// Returns the ttl of the records created for an endpoint
+func getEndpointTtl(e *endpoint.Endpoint) string {
+	if e.RecordTTL == 0 {
+		// endpoints bypassing [provider.AdjustEndpoints] (e.g., txt registry records) lack a ttl - which routeros treats as disabled
+		return defaultRecordTtl.String()
+	}
+	return time.Duration(e.RecordTTL * 1e9).String()
+}
+
+// Produces the record created for an endpoint's target as a [map[string]string] that has the same shape as a routeros ip dns record.
+// Metadata is not included - see [client.createManagedRecord].
+// Returns an error if the record type is unsupported or the target is malformed.
+func (c *client) getEndpointRecord(e *endpoint.Endpoint, t string, rm recordMetadata) (map[string]string, error) {
+	rt := getAddressRecordType(e.RecordType, t)
+	r := map[string]string{
+		"name": e.DNSName,
+		"type": rt,
+		"ttl":  getEndpointTtl(e),
+	}
+	al, ok := e.GetProviderSpecificProperty(providerSpecificAddressList)
+	if ok && al != "" {
+		r["address-list"] = al
+	}
+	ms, ok := e.GetProviderSpecificProperty(providerSpecificMatchSubdomain)
+	if ok && ms == "true" {
+		r["match-subdomain"] = "yes"
+	}
+	if c.metadataStore == MetadataStoreTxt && rm.Comment != "" {
+		r["comment"] = rm.Comment
+	}
+	rtd, ok := recordTypes[rt]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnsupportedType, e.RecordType)
+	}
+	err := rtd.encode(t, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Creates the record(s) for an endpoint's target - splitting TXT values longer than the [client]'s max length across records (see [client.createTextParts])
+func (c *client) createEndpointRecord(r map[string]string, rm recordMetadata) error {
+	if r["type"] == "TXT" && c.txtMaxLength > 0 && len(r["text"]) > c.txtMaxLength {
+		return c.createTextParts(r, rm)
+	}
+	return c.createManagedRecord(r, rm)
+}
+
+// Creates the endpoint - or, if records already exist for its record type and name, updates them in place to match the endpoint.
+// Existing records whose targets are listed by the endpoint are updated (when they differ), records of unlisted targets are deleted and records of new targets are created.
+// Unlike deleting and recreating an endpoint, records are never absent while being updated.
+// With the txt metadata store, or when existing TXT values are split across records (see [client.createTextParts]), existing records are replaced instead.
+// Returns an [InvalidTargetError] (prior to changing any records) if a target is invalid.
+func (c *client) CreateOrUpdateEndpoint(e *endpoint.Endpoint) error {
+	err := validateEndpoint(e)
 	if err != nil {
 		return err
 	}
-	k := c.makeKey(e.RecordType, e.DNSName)
+	return c.withClient(func() error {
+		rs, err := c.listDnsRecords()
+		if err != nil {
+			return err
+		}
+		rts, err := c.getRecordTargets(rs)
+		if err != nil {
+			return err
+		}
+		// an endpoint without targets selects all of the records sharing its record type and name
+		ers, mrs := c.getEndpointRecords(&endpoint.Endpoint{DNSName: e.DNSName, RecordType: e.RecordType}, rs, rts)
+		if len(ers) == 0 {
+			return c.CreateEndpoint(e)
+		}
+		if c.metadataStore != MetadataStoreComment || slices.ContainsFunc(ers, func(r dnsRecord) bool { return r.Metadata.Part != nil }) {
+			c.logger.Debug(fmt.Sprintf("replacing records %s %s", e.RecordType, e.DNSName))
+			err := c.deleteDnsRecords(append(ers, mrs...))
+			if err != nil {
+				return err
+			}
+			return c.CreateEndpoint(e)
+		}
+		_, _, rms, err := c.getEndpointMetadata(e)
+		if err != nil {
+			return err
+		}
+		for _, t := range e.Targets {
+			rt := getAddressRecordType(e.RecordType, t)
+			r, err := c.getEndpointRecord(e, t, rms[rt])
+			if err != nil {
+				return err
+			}
+			i := slices.IndexFunc(ers, func(er dnsRecord) bool {
+				return er.Type == rt && normalizeTarget(rt, rts[er.Id]) == normalizeTarget(rt, t)
+			})
+			if i == -1 {
+				err := c.createEndpointRecord(r, rms[rt])
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			er := ers[i]
+			ers = slices.Delete(ers, i, i+1)
+			// attributes absent from the endpoint are cleared
+			if er.AddressList != "" && r["address-list"] == "" {
+				r["address-list"] = ""
+			}
+			if er.MatchSubdomain == "true" && r["match-subdomain"] == "" {
+				r["match-subdomain"] = "no"
+			}
+			err = c.setRecordComment(r, rms[rt])
+			if err != nil {
+				return err
+			}
+			h, err := c.getRecordHash(er)
+			if err != nil {
+				return err
+			}
+			if er.Comment == r["comment"] && er.Metadata.Hash == h {
+				// record is unchanged (and unmodified since it was written)
+				continue
+			}
+			err = c.updateDnsRecord(er, r)
+			if err != nil {
+				return err
+			}
+		}
+		// remaining records hold targets no longer listed by the endpoint
+		return c.deleteDnsRecords(ers)
+	})
+}
+
+// Creates a routeros record managed by the provider.
+// With the comment metadata store, the record's metadata (including a hash of its content - see [client.getRecordHash]) is stored in its comment.
+// With the txt metadata store, metadata is written separately to a companion record (see [client.CreateEndpoint]).
+func (c *client) createManagedRecord(r map[string]string, rm recordMetadata) error {
+	if c.metadataStore == MetadataStoreComment {
+		err := c.setRecordComment(r, rm)
+		if err != nil {
+			return err
+		}
+	}
+	return c.createDnsRecord(r)
+}
+
+// Adopts the existing (unmanaged) routeros record - updating it to match the provided record and tagging it with metadata.
+// Adopted records become managed without being deleted and recreated.
+// Requires the comment metadata store.
+func (c *client) adoptRecord(ur dnsRecord, r map[string]string, rm recordMetadata) error {
+	err := c.setRecordComment(r, rm)
+	if err != nil {
+		return err
+	}
+	return c.updateDnsRecord(ur, r)
+}
+
+// Determines whether an unmanaged record with the given dns name should be adopted (see [client.AdoptRecords])
+type AdoptMatchFunc func(n string) bool
+
+// Adopts existing unmanaged records whose names are matched - tagging them with metadata so that they become managed without being deleted and recreated.
+// Unlike [client.adoptRecord], adopted records are otherwise left unchanged.
+// Returns the adopted records as endpoints (one per record).
+// Requires the comment metadata store.
+func (c *client) AdoptRecords(match AdoptMatchFunc) ([]*endpoint.Endpoint, error) {
+	if c.metadataStore != MetadataStoreComment {
+		return []*endpoint.Endpoint{}, fmt.Errorf("adopting existing records requires metadata store %s", MetadataStoreComment)
+	}
+	rs, err := c.listUnmanagedDnsRecords()
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	es := []*endpoint.Endpoint{}
 	for _, r := range rs {
-		_, err := c.getRecordMetadata(r)
+		if !match(normalizeDnsName(r.Name)) {
+			continue
+		}
+		t, err := c.getRecordTarget(r)
+		if err != nil {
+			// unsupported record types cannot be managed
+			c.logger.Debug(fmt.Sprintf("ignore unsupported dns record %s", r.Id))
+			continue
+		}
+		h, err := c.getRecordHash(r)
+		if err != nil {
+			return []*endpoint.Endpoint{}, err
+		}
+		com, err := c.encodeRecordMetadata(recordMetadata{Hash: h})
+		if err != nil {
+			return []*endpoint.Endpoint{}, err
+		}
+		err = c.updateDnsRecord(r, map[string]string{"comment": com})
+		if err != nil {
+			return []*endpoint.Endpoint{}, err
+		}
+		es = append(es, endpoint.NewEndpoint(r.Name, r.Type, t))
+	}
+	return es, nil
+}
+
+// Sets the comment of a record to its encoded metadata - including a hash of the record's content (see [client.getRecordHash])
+func (c *client) setRecordComment(r map[string]string, rm recordMetadata) error {
+	s := proto.Sentence{}
+	for k, v := range r {
+		s.List = append(s.List, proto.Pair{Key: k, Value: v})
+	}
+	h, err := c.getRecordHash(parseDnsRecord(&s))
+	if err != nil {
+		return err
+	}
+	rm.Hash = h
+	com, err := c.encodeRecordMetadata(rm)
+	if err != nil {
+		return err
+	}
+	r["comment"] = com
+	return nil
+}
+
+// Produces a hash of the content of a routeros record - used to detect external modifications to managed records (see [client.CheckIntegrity]).
+// Content is normalized prior to hashing - accounting for differences between the values written to and read from routeros.
+func (c *client) getRecordHash(r dnsRecord) (string, error) {
+	t, err := c.getRecordTarget(r)
+	if err != nil {
+		return "", err
+	}
+	ttl, err := time.ParseDuration(r.Ttl)
+	if err != nil {
+		return "", err
+	}
+	ms := r.MatchSubdomain == "true" || r.MatchSubdomain == "yes"
+	v := fmt.Sprintf("%s\n%s\n%d\n%s\n%s\n%t", r.Type, normalizeDnsName(r.Name), int64(ttl.Seconds()), normalizeTarget(r.Type, t), r.AddressList, ms)
+	h := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(h[:8]), nil
+}
+
+// Checks managed records for external modifications (e.g., edits made via winbox) by comparing their content to the hash stored within their metadata.
+// When repair is true, modified records are deleted - allowing external-dns to recreate them during its next sync.
+// Returns the modified records as endpoints (one per record).
+// Records without a stored hash (e.g., those using the txt metadata store) are not checked.
+func (c *client) CheckIntegrity(repair bool) ([]*endpoint.Endpoint, error) {
+	rs, err := c.listDnsRecords()
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	es := []*endpoint.Endpoint{}
+	for _, r := range rs {
+		if r.Metadata.Hash == "" {
+			continue
+		}
+		h, err := c.getRecordHash(r)
+		if err != nil {
+			return []*endpoint.Endpoint{}, err
+		}
+		if h == r.Metadata.Hash {
+			continue
+		}
+		t, err := c.getRecordTarget(r)
+		if err != nil {
+			return []*endpoint.Endpoint{}, err
+		}
+		e := endpoint.NewEndpoint(r.Name, r.endpointType(), t)
+		for lk, lv := range r.Metadata.Labels {
+			e.Labels[lk] = lv
+		}
+		es = append(es, e)
+		if repair {
+			err = c.deleteDnsRecord(r)
+			if err != nil {
+				return []*endpoint.Endpoint{}, err
+			}
+		}
+	}
+	return es, nil
+}
+
+// Creates a TXT record whose text exceeds the [client]'s max length as multiple records - each holding part of the text.
+// Each part's metadata identifies the value and the part's position within it (see [recordPart]) - allowing the value to be reassembled (see [client.getRecordTargets]).
+func (c *client) createTextParts(r map[string]string, rm recordMetadata) error {
+	t := r["text"]
+	h := sha256.Sum256([]byte(t))
+	ps := splitText(t, c.txtMaxLength)
+	for i, p := range ps {
+		prm := rm
+		prm.Part = &recordPart{Id: hex.EncodeToString(h[:4]), Index: i, Count: len(ps)}
+		pr := maps.Clone(r)
+		pr["text"] = p
+		err := c.createManagedRecord(pr, prm)
 		if err != nil {
-			// external-dns managed record is invalid - unexpected, should be caught by [listDnsRecords]
 			return err
 		}
-		rk := c.makeKey(r["type"], r["name"])
-		if rk != k {
-			// record is not mapped to endpoint - ignore
+	}
+	return nil
+}
+
+// Splits text into parts of at most l bytes - without splitting multi-byte characters
+func splitText(t string, l int) []string {
+	ps := []string{}
+	p := ""
+	for _, r := range t {
+		rs := string(r)
+		if len(p)+len(rs) > l && p != "" {
+			ps = append(ps, p)
+			p = ""
+		}
+		p += rs
+	}
+	if p != "" {
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// Produces the [endpoint.Endpoint] targets represented by routeros records - keyed by record id (see [client.getRecordTarget]).
+// The parts of a TXT value split across records (see [client.createTextParts]) are reassembled - each part maps to the full value.
+// Incomplete values map to the concatenation of their available parts.
+func (c *client) getRecordTargets(rs []dnsRecord) (map[string]string, error) {
+	ts := map[string]string{}
+	pks := []string{}
+	prs := map[string][]dnsRecord{}
+	for _, r := range rs {
+		t, err := c.getRecordTarget(r)
+		if err != nil {
+			return map[string]string{}, err
+		}
+		ts[r.Id] = t
+		if r.Metadata.Part == nil {
 			continue
 		}
+		pk := c.getRecordPartKey(r)
+		_, ok := prs[pk]
+		if !ok {
+			pks = append(pks, pk)
+		}
+		prs[pk] = append(prs[pk], r)
+	}
+	for _, pk := range pks {
+		ps := prs[pk]
+		slices.SortStableFunc(ps, func(a dnsRecord, b dnsRecord) int {
+			return a.Metadata.Part.Index - b.Metadata.Part.Index
+		})
+		t := ""
+		for _, p := range ps {
+			t += ts[p.Id]
+		}
+		for _, p := range ps {
+			ts[p.Id] = t
+		}
+	}
+	return ts, nil
+}
+
+// Returns a key identifying the split TXT value a record holds part of (see [recordPart])
+func (c *client) getRecordPartKey(r dnsRecord) string {
+	return fmt.Sprintf("%s::%s", c.makeKey(r.endpointType(), r.Name), r.Metadata.Part.Id)
+}
+
+// Produces the [endpoint.Endpoint] target represented by a routeros record
+// Returns an error if the record type is unsupported
+func (c *client) getRecordTarget(r dnsRecord) (string, error) {
+	rtd, ok := recordTypes[r.Type]
+	if !ok {
+		return "", fmt.Errorf("%w %s", ErrUnsupportedType, r.Type)
+	}
+	return rtd.decode(r), nil
+}
+
+// Deletes an endpoint
+// Only routeros records matching both the endpoint's record type and name are deleted - records of other types sharing the name are retained.
+// If the endpoint lists targets, only routeros records matching one of these targets are deleted.
+func (c *client) DeleteEndpoint(e *endpoint.Endpoint) error {
+	rs, err := c.listDnsRecords()
+	if err != nil {
+		return err
+	}
+	rts, err := c.getRecordTargets(rs)
+	if err != nil {
+		return err
+	}
+	drs, mrs := c.getEndpointRecords(e, rs, rts)
+	for _, r := range drs {
 		err = c.deleteDnsRecord(r)
 		if err != nil {
 			return err
 		}
 	}
+	for _, mr := range mrs {
+		// companion record is deleted last - see [client.CreateEndpoint]
+		err = c.deleteDnsRecord(mr)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Deletes the routeros records of several endpoints (see [client.DeleteEndpoint]) via a single api call - rather than one call per record.
+// Records are listed once for all endpoints.
+// Returns an error if any api call fails - as routeros may have deleted some records before failing, callers should re-list records to determine which endpoints were deleted.
+func (c *client) DeleteEndpoints(es []*endpoint.Endpoint) error {
+	return c.withClient(func() error {
+		rs, err := c.listDnsRecords()
+		if err != nil {
+			return err
+		}
+		rts, err := c.getRecordTargets(rs)
+		if err != nil {
+			return err
+		}
+		drs := []dnsRecord{}
+		mrs := []dnsRecord{}
+		ids := map[string]bool{}
+		for _, e := range es {
+			edrs, emrs := c.getEndpointRecords(e, rs, rts)
+			for _, r := range edrs {
+				if !ids[r.Id] {
+					ids[r.Id] = true
+					drs = append(drs, r)
+				}
+			}
+			for _, r := range emrs {
+				if !ids[r.Id] {
+					ids[r.Id] = true
+					mrs = append(mrs, r)
+				}
+			}
+		}
+		// companion records are deleted last - see [client.CreateEndpoint]
+		return c.deleteDnsRecords(append(drs, mrs...))
+	})
+}
+
+// Returns the records (from the provided records and their targets - see [client.getRecordTargets]) that are deleted alongside the endpoint.
+// Additionally returns the companion metadata records deleted once the endpoint's records are deleted - these are omitted when the endpoint's records are only partially deleted.
+func (c *client) getEndpointRecords(e *endpoint.Endpoint, rs []dnsRecord, rts map[string]string) ([]dnsRecord, []dnsRecord) {
+	k := c.makeKey(e.RecordType, e.DNSName)
+	ts := []string{}
+	for _, t := range e.Targets {
+		ts = append(ts, normalizeTarget(e.RecordType, t))
+	}
+	drs := []dnsRecord{}
+	mrs := []dnsRecord{}
+	retained := false
+	for _, r := range rs {
+		rk := c.makeKey(r.endpointType(), r.Name)
+		if rk != k {
+			// record is not mapped to endpoint - ignore
+			continue
+		}
+		if len(ts) > 0 {
+			t := rts[r.Id]
+			if !slices.Contains(ts, normalizeTarget(r.Type, t)) {
+				// record target not listed by endpoint - retain
+				retained = true
+				continue
+			}
+		}
+		drs = append(drs, r)
+		if r.MetadataRecord != nil && !slices.ContainsFunc(mrs, func(mr dnsRecord) bool { return mr.Id == r.MetadataRecord.Id }) {
+			mrs = append(mrs, *r.MetadataRecord)
+		}
+	}
+	if retained {
+		// companion record still describes retained records
+		return drs, []dnsRecord{}
+	}
+	return drs, mrs
+}
+
 // Lists all endpoints
+// Routeros records sharing a record type and name are grouped into a single endpoint with one target per record.
+// Records stored with a record type differing from that of their endpoint (see [getAddressRecordType]) are grouped under the endpoint's record type.
+// Endpoints are ordered by their first routeros record - producing stable results for an unchanged record set.
 func (c *client) ListEndpoints() ([]*endpoint.Endpoint, error) {
 	rs, err := c.listDnsRecords()
 	if err != nil {
 		return []*endpoint.Endpoint{}, err
 	}
+	rts, err := c.getRecordTargets(rs)
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	ks := []string{}
 	mes := map[string]*endpoint.Endpoint{}
+	pks := map[string]bool{}
 	for _, r := range rs {
-		_, err := c.getRecordMetadata(r)
-		if err != nil {
-			// external-dns managed record is invalid - unexpected, should be caught by [listDnsRecords]
-			return []*endpoint.Endpoint{}, err
-		}
-		k := c.makeKey(r["type"], r["name"])
+		k := c.makeKey(r.endpointType(), r.Name)
 		_, ex := mes[k]
 		if !ex {
-			ttl, err := time.ParseDuration(r["ttl"])
+			ks = append(ks, k)
+			ttl, err := time.ParseDuration(r.Ttl)
 			if err != nil {
 				return []*endpoint.Endpoint{}, err
 			}
+			if r.Metadata.DefaultTtl {
+				// reported as created - preventing endpoints lacking a ttl from perpetually differing from their records
+				ttl = 0
+			}
 			mes[k] = &endpoint.Endpoint{
-				DNSName:    r["name"],
+				DNSName:    r.Name,
+				Labels:     endpoint.Labels{},
 				RecordTTL:  endpoint.TTL(ttl),
-				RecordType: r["type"],
+				RecordType: r.endpointType(),
 				Targets:    []string{},
 			}
+			if r.AddressList != "" {
+				mes[k].SetProviderSpecificProperty(providerSpecificAddressList, r.AddressList)
+			}
+			if r.MatchSubdomain == "true" {
+				mes[k].SetProviderSpecificProperty(providerSpecificMatchSubdomain, "true")
+			}
+			if r.Metadata.Comment != "" {
+				mes[k].SetProviderSpecificProperty(providerSpecificComment, r.Metadata.Comment)
+			}
+			if r.Metadata.Route != "" {
+				mes[k].SetProviderSpecificProperty(providerSpecificRoute, r.Metadata.Route)
+			}
+			for lk, lv := range r.Metadata.Labels {
+				mes[k].Labels[lk] = lv
+			}
 		}
 		e := mes[k]
-		switch r["type"] {
-		case "A":
-			e.Targets = append(e.Targets, r["address"])
-		case "CNAME":
-			e.Targets = append(e.Targets, r["cname"])
-		case "MX":
-			t := fmt.Sprintf("%s %s", r["mx-preference"], r["mx-exchange"])
-			e.Targets = append(e.Targets, t)
-		case "NS":
-			e.Targets = append(e.Targets, r["ns"])
-		case "SRV":
-			t := fmt.Sprintf("%s %s %s %s", r["srv-priority"], r["srv-weight"], r["srv-port"], r["srv-target"])
-			e.Targets = append(e.Targets, t)
-		case "TXT":
-			e.Targets = append(e.Targets, r["text"])
-		default:
-			return []*endpoint.Endpoint{}, fmt.Errorf("unsupported record type %s", r["type"])
+		if r.Metadata.Part != nil {
+			// split TXT values produce a single target
+			pk := c.getRecordPartKey(r)
+			if pks[pk] {
+				continue
+			}
+			pks[pk] = true
 		}
+		e.Targets = append(e.Targets, rts[r.Id])
 	}
+	// endpoints are returned in a stable order (that of the routeros records)
 	es := []*endpoint.Endpoint{}
-	for _, v := range mes {
-		es = append(es, v)
+	for _, k := range ks {
+		e := mes[k]
+		normalizeEndpoint(e)
+		// targets (e.g. of multi-target MX and SRV endpoints) are returned in a stable order
+		slices.Sort(e.Targets)
+		es = append(es, e)
 	}
 	return es, nil
 }
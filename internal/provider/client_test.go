@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestNormalizeDnsName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "example.com", expected: "example.com"},
+		{name: "Example.COM", expected: "example.com"},
+		{name: "example.com.", expected: "example.com"},
+		{name: "WWW.Example.Com.", expected: "www.example.com"},
+		{name: "_acme-challenge.Example.com.", expected: "_acme-challenge.example.com"},
+		{name: "", expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := normalizeDnsName(test.name)
+			if actual != test.expected {
+				t.Errorf("normalizeDnsName(%q) = %q, expected %q", test.name, actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeEndpoint(t *testing.T) {
+	tests := []struct {
+		name            string
+		recordType      string
+		targets         []string
+		expectedName    string
+		expectedTargets []string
+	}{
+		{name: "Host.Example.com.", recordType: "A", targets: []string{"10.0.0.1"}, expectedName: "host.example.com", expectedTargets: []string{"10.0.0.1"}},
+		{name: "host.example.com", recordType: "AAAA", targets: []string{"2001:DB8:0:0::1"}, expectedName: "host.example.com", expectedTargets: []string{"2001:db8::1"}},
+		{name: "Alias.Example.com", recordType: "CNAME", targets: []string{"Target.Example.com."}, expectedName: "alias.example.com", expectedTargets: []string{"target.example.com"}},
+		{name: "example.com.", recordType: "MX", targets: []string{"10 Mail.Example.com.", "20 backup.example.com"}, expectedName: "example.com", expectedTargets: []string{"10 mail.example.com", "20 backup.example.com"}},
+		{name: "example.com", recordType: "NS", targets: []string{"NS1.Example.com."}, expectedName: "example.com", expectedTargets: []string{"ns1.example.com"}},
+		{name: "_sip._tcp.Example.com.", recordType: "SRV", targets: []string{"10 5 5060 SIP.Example.com."}, expectedName: "_sip._tcp.example.com", expectedTargets: []string{"10 5 5060 sip.example.com"}},
+		{name: "Example.com", recordType: "TXT", targets: []string{"Mixed Case."}, expectedName: "example.com", expectedTargets: []string{"Mixed Case."}},
+		{name: "host.example.com", recordType: "CNAME", targets: []string{"not a name"}, expectedName: "host.example.com", expectedTargets: []string{"not a name"}},
+	}
+	for _, test := range tests {
+		t.Run(test.recordType+" "+test.name, func(t *testing.T) {
+			// constructed directly - [endpoint.NewEndpoint] trims trailing dots
+			e := &endpoint.Endpoint{DNSName: test.name, RecordType: test.recordType, Targets: slices.Clone(test.targets)}
+			normalizeEndpoint(e)
+			if e.DNSName != test.expectedName {
+				t.Errorf("name %q, expected %q", e.DNSName, test.expectedName)
+			}
+			if !slices.Equal(e.Targets, test.expectedTargets) {
+				t.Errorf("targets %q, expected %q", e.Targets, test.expectedTargets)
+			}
+		})
+	}
+}
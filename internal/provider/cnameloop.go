@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"slices"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// A CNAME record whose creation (or update) was refused because it would create a CNAME loop - see [provider.enforceCnameLoops]
+type cnameLoop struct {
+	endpoint *endpoint.Endpoint
+	// The names forming the loop - starting (and ending) with the name of the refused record
+	names []string
+	// Whether the refused record updates an existing record
+	update bool
+}
+
+// Returns the chain of names that leads from the given name back to itself by following the CNAME records within the graph (mapping names to their CNAME targets).
+// Returns nil if no such chain exists.
+func findCnameLoop(g map[string][]string, n string) []string {
+	visited := map[string]bool{}
+	var visit func(c string, p []string) []string
+	visit = func(c string, p []string) []string {
+		for _, t := range g[c] {
+			if t == n {
+				return append(p, t)
+			}
+			if visited[t] {
+				continue
+			}
+			visited[t] = true
+			l := visit(t, append(slices.Clone(p), t))
+			if l != nil {
+				return l
+			}
+		}
+		return nil
+	}
+	return visit(n, []string{n})
+}
+
+// Removes creations and updates of CNAME records from the changes that would create CNAME loops (e.g., 'a -> b -> a') - protecting the router's resolver from endless recursion.
+// Loops are detected against the CNAME records existing once the changes are applied - including existing managed records.
+// Loops are broken by refusing the first record (in the order of the changes) completing each loop - refused updates leave the existing record unchanged.
+// Returns the refused records.
+func (p *provider) enforceCnameLoops(pc Client, ch *plan.Changes) ([]cnameLoop, error) {
+	isCname := func(e *endpoint.Endpoint) bool { return e.RecordType == endpoint.RecordTypeCNAME }
+	if !slices.ContainsFunc(ch.Create, isCname) && !slices.ContainsFunc(ch.UpdateNew, isCname) {
+		return []cnameLoop{}, nil
+	}
+	es, err := pc.ListEndpoints()
+	if err != nil {
+		return []cnameLoop{}, err
+	}
+
+	g := map[string][]string{}
+	setTargets := func(e *endpoint.Endpoint) {
+		ts := []string{}
+		for _, t := range e.Targets {
+			ts = append(ts, normalizeDnsName(t))
+		}
+		g[normalizeDnsName(e.DNSName)] = ts
+	}
+	for _, e := range es {
+		if isCname(e) {
+			setTargets(e)
+		}
+	}
+	for _, e := range append(slices.Clone(ch.Delete), ch.UpdateOld...) {
+		if isCname(e) {
+			delete(g, normalizeDnsName(e.DNSName))
+		}
+	}
+	for _, e := range append(slices.Clone(ch.Create), ch.UpdateNew...) {
+		if isCname(e) {
+			setTargets(e)
+		}
+	}
+
+	ups := getUpdatePairs(ch)
+	cls := []cnameLoop{}
+	refused := map[*endpoint.Endpoint]bool{}
+	check := func(e *endpoint.Endpoint, u bool) {
+		if !isCname(e) {
+			return
+		}
+		n := normalizeDnsName(e.DNSName)
+		l := findCnameLoop(g, n)
+		if l == nil {
+			return
+		}
+		cls = append(cls, cnameLoop{endpoint: e, names: l, update: u})
+		refused[e] = true
+		// the existing record (if any) is retained
+		delete(g, n)
+		oe, ok := ups[e]
+		if ok {
+			refused[oe] = true
+			setTargets(oe)
+		}
+	}
+	for _, e := range ch.Create {
+		check(e, false)
+	}
+	for _, e := range ch.UpdateNew {
+		check(e, true)
+	}
+	if len(cls) == 0 {
+		return cls, nil
+	}
+
+	keep := func(es []*endpoint.Endpoint) []*endpoint.Endpoint {
+		return slices.DeleteFunc(slices.Clone(es), func(e *endpoint.Endpoint) bool { return refused[e] })
+	}
+	ch.Create = keep(ch.Create)
+	ch.UpdateNew = keep(ch.UpdateNew)
+	ch.UpdateOld = keep(ch.UpdateOld)
+	return cls, nil
+}
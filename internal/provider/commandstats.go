@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counts the routeros api commands executed (and their total duration) on behalf of a webhook request.
+// Attached to a [client] via [client.WithCommandStats] - copies of the client share the stats.
+// Safe for concurrent use (e.g., by the concurrent groups of [provider.ApplyChanges]).
+type CommandStats struct {
+	count    atomic.Uint64
+	duration atomic.Int64
+	routers  sync.Map
+}
+
+// Records an executed command, the identity of the routeros device executing it (see [client.discoverIdentity]) and its duration
+func (cs *CommandStats) add(id string, d time.Duration) {
+	cs.count.Add(1)
+	cs.duration.Add(int64(d))
+	if id != "" {
+		cs.routers.Store(id, true)
+	}
+}
+
+// Returns the number of executed commands
+func (cs *CommandStats) Count() uint64 {
+	return cs.count.Load()
+}
+
+// Returns the total duration of executed commands
+func (cs *CommandStats) Duration() time.Duration {
+	return time.Duration(cs.duration.Load())
+}
+
+// Returns the (sorted) identities of the routeros devices that executed commands
+func (cs *CommandStats) Routers() []string {
+	ids := []string{}
+	cs.routers.Range(func(k any, v any) bool {
+		ids = append(ids, k.(string))
+		return true
+	})
+	slices.Sort(ids)
+	return ids
+}
+
+// Used as a key to a request's [context.Context] to store [CommandStats] - see [NewContextWithCommandStats]
+type ContextCommandStats struct{}
+
+// Returns a copy of the [context.Context] holding the given [CommandStats].
+// When provided to provider methods, the routeros api commands executed during the call are recorded within the stats.
+func NewContextWithCommandStats(c context.Context, cs *CommandStats) context.Context {
+	return context.WithValue(c, ContextCommandStats{}, cs)
+}
+
+// Returns a copy of the [client] recording executed routeros api commands within the given [CommandStats]
+func (c *client) WithCommandStats(cs *CommandStats) Client {
+	cc := *c
+	cc.client = nil
+	cc.commandStats = cs
+	return &cc
+}
+
+// Returns a copy of the [routedClient] whose clients record executed commands within the given [CommandStats]
+func (rc *routedClient) WithCommandStats(cs *CommandStats) Client {
+	return rc.with(func(c Client) Client { return c.WithCommandStats(cs) })
+}
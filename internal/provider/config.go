@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Provider configuration read from a yaml file (e.g., a mounted Kubernetes ConfigMap).
+// Fields that are set override their equivalent [Opts] fields.
+type FileConfig struct {
+	ApplyConcurrency   *uint    `yaml:"applyConcurrency"`
+	FilterExclude      []string `yaml:"filterExclude"`
+	FilterInclude      []string `yaml:"filterInclude"`
+	FilterRegexExclude string   `yaml:"filterRegexExclude"`
+	FilterRegexInclude string   `yaml:"filterRegexInclude"`
+	LogSampleLimit     *uint    `yaml:"logSampleLimit"`
+}
+
+// Reads a [FileConfig] from the yaml file at the given path.
+// Additionally returns the raw file contents.
+// Returns an error if the file cannot be read or parsed.
+func ReadFileConfig(p string) (FileConfig, []byte, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		return FileConfig{}, nil, err
+	}
+	fc := FileConfig{}
+	err = yaml.UnmarshalStrict(d, &fc)
+	if err != nil {
+		return FileConfig{}, nil, fmt.Errorf("config file %s invalid: %w", p, err)
+	}
+	return fc, d, nil
+}
+
+// Returns a copy of the provided [Opts] overridden by fields set within the [FileConfig].
+// Returns an error if a regex filter fails to compile.
+func (fc FileConfig) apply(o Opts) (Opts, error) {
+	if fc.ApplyConcurrency != nil {
+		o.ApplyConcurrency = *fc.ApplyConcurrency
+	}
+	if fc.FilterExclude != nil {
+		o.FilterExclude = fc.FilterExclude
+	}
+	if fc.FilterInclude != nil {
+		o.FilterInclude = fc.FilterInclude
+	}
+	if fc.FilterRegexExclude != "" {
+		fre, err := regexp.Compile(fc.FilterRegexExclude)
+		if err != nil {
+			return Opts{}, err
+		}
+		o.FilterRegexExclude = fre
+	}
+	if fc.FilterRegexInclude != "" {
+		fri, err := regexp.Compile(fc.FilterRegexInclude)
+		if err != nil {
+			return Opts{}, err
+		}
+		o.FilterRegexInclude = fri
+	}
+	if fc.LogSampleLimit != nil {
+		o.LogSampleLimit = *fc.LogSampleLimit
+	}
+	return o, nil
+}
+
+// Creates a [endpoint.DomainFilter] from the filters within [Opts].
+// Regex filters take precedence over plain filters.
+func (o Opts) domainFilter() endpoint.DomainFilter {
+	if o.FilterRegexExclude != nil || o.FilterRegexInclude != nil {
+		return endpoint.NewRegexDomainFilter(o.FilterRegexInclude, o.FilterRegexExclude)
+	} else if o.FilterExclude != nil {
+		return endpoint.NewDomainFilterWithExclusions(o.FilterInclude, o.FilterExclude)
+	}
+	return endpoint.NewDomainFilter(o.FilterInclude)
+}
+
+// Callback invoked by a [configFileWatcher] when the config file changes
+type configFileCallback func(fc FileConfig) error
+
+// Polls a config file for changes - invoking a callback when the file's contents change.
+// Polling (rather than filesystem notifications) handles the symlink swaps used to update mounted Kubernetes ConfigMaps.
+type configFileWatcher struct {
+	callback configFileCallback
+	data     []byte
+	interval time.Duration
+	logger   *slog.Logger
+	path     string
+}
+
+// Polls the config file until the process exits.
+// Unreadable or invalid config files are logged and ignored - the previous configuration remains in effect.
+func (w *configFileWatcher) Run() {
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for range t.C {
+		fc, d, err := ReadFileConfig(w.path)
+		if err != nil {
+			w.logger.Warn(fmt.Sprintf("failed to read config file: %s", err.Error()))
+			continue
+		}
+		if bytes.Equal(d, w.data) {
+			continue
+		}
+		w.data = d
+		w.logger.Info(fmt.Sprintf("config file %s changed - reloading", w.path))
+		err = w.callback(fc)
+		if err != nil {
+			w.logger.Warn(fmt.Sprintf("failed to reload config file: %s", err.Error()))
+		}
+	}
+}
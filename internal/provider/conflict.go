@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Unmanaged records conflicting with a created endpoint are ignored - the endpoint's records are created alongside them (the default)
+const ConflictPolicyIgnore = "ignore"
+
+// Unmanaged records conflicting with a created endpoint are deleted prior to creating the endpoint's records
+const ConflictPolicyOverwrite = "overwrite"
+
+// Creating an endpoint conflicting with unmanaged records fails with a [ConflictingRecordError]
+const ConflictPolicyFail = "fail"
+
+// Creating an endpoint conflicting with unmanaged records is skipped (and logged as a warning)
+const ConflictPolicySkip = "skip"
+
+// Known conflict policies - see [ClientOpts.ConflictPolicy]
+var conflictPolicies = []string{ConflictPolicyFail, ConflictPolicyIgnore, ConflictPolicyOverwrite, ConflictPolicySkip}
+
+// Returned when an endpoint conflicts with an existing, unmanaged routeros record (see [ConflictPolicyFail])
+type ConflictingRecordError struct {
+	DNSName    string
+	Id         string
+	RecordType string
+}
+
+func (e ConflictingRecordError) Error() string {
+	return fmt.Sprintf("conflicts with unmanaged record %s %s (%s)", e.RecordType, e.DNSName, e.Id)
+}
+
+// Returns the unmanaged records conflicting with the creation of records of the given routeros types and name.
+// Records conflict when they share a name and either share a type or either is a CNAME record.
+// Records that are adopted (see [client.adoptRecord]) are not conflicts - the provided callback identifies them.
+func (c *client) getConflictingRecords(urs []dnsRecord, rts []string, n string, adopted func(ur dnsRecord) bool) []dnsRecord {
+	crs := []dnsRecord{}
+	for _, ur := range urs {
+		if normalizeDnsName(ur.Name) != normalizeDnsName(n) {
+			continue
+		}
+		if !slices.Contains(rts, ur.Type) && ur.Type != "CNAME" && !slices.Contains(rts, "CNAME") {
+			continue
+		}
+		if c.adopt && adopted(ur) {
+			continue
+		}
+		crs = append(crs, ur)
+	}
+	return crs
+}
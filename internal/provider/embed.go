@@ -1,6 +1,54 @@
 package provider
 
-import _ "embed"
+import (
+	_ "embed"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
 
 //go:embed version.txt
 var ProviderVersion string
+
+// The commit the provider was built from - set at build time (e.g., '-ldflags "-X <package>.ProviderCommit=<commit>"'), otherwise read from the build's vcs information (if present)
+var ProviderCommit string
+
+// The time (RFC3339) the provider was built - set at build time (see [ProviderCommit]), otherwise the commit time read from the build's vcs information (if present)
+var ProviderBuildDate string
+
+// The versions of the external-dns webhook protocol (see the 'application/external.dns.webhook+json;version=<version>' media type) supported by the provider
+var WebhookProtocolVersions = []string{"1"}
+
+// Describes the provider build - see [GetVersionInfo]
+type VersionInfo struct {
+	BuildDate               string   `json:"buildDate"`
+	Commit                  string   `json:"commit"`
+	GoVersion               string   `json:"goVersion"`
+	Version                 string   `json:"version"`
+	WebhookProtocolVersions []string `json:"webhookProtocolVersions"`
+}
+
+// Returns the [VersionInfo] of the running provider.
+// The commit and build date are empty if neither set at build time nor available from the build's vcs information.
+func GetVersionInfo() VersionInfo {
+	vi := VersionInfo{
+		BuildDate:               ProviderBuildDate,
+		Commit:                  ProviderCommit,
+		GoVersion:               runtime.Version(),
+		Version:                 strings.TrimSpace(ProviderVersion),
+		WebhookProtocolVersions: WebhookProtocolVersions,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return vi
+	}
+	for _, s := range bi.Settings {
+		switch {
+		case s.Key == "vcs.revision" && vi.Commit == "":
+			vi.Commit = s.Value
+		case s.Key == "vcs.time" && vi.BuildDate == "":
+			vi.BuildDate = s.Value
+		}
+	}
+	return vi
+}
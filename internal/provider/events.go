@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Records events describing record operations against the Kubernetes resources that produced an [endpoint.Endpoint]
+type EventRecorder interface {
+	Record(e *endpoint.Endpoint, et string, reason string, message string)
+}
+
+// Event types - see [EventRecorder]
+const (
+	EventTypeNormal  = "Normal"
+	EventTypeWarning = "Warning"
+)
+
+// The kind and api version of a Kubernetes resource
+type eventObjectKind struct {
+	apiVersion string
+	kind       string
+}
+
+// Maps the resource types found within external-dns' 'resource' endpoint label (e.g., 'ingress/default/web') to Kubernetes kinds
+var eventObjectKinds = map[string]eventObjectKind{
+	"crd":       {apiVersion: "externaldns.k8s.io/v1alpha1", kind: "DNSEndpoint"},
+	"grpcroute": {apiVersion: "gateway.networking.k8s.io/v1alpha2", kind: "GRPCRoute"},
+	"httproute": {apiVersion: "gateway.networking.k8s.io/v1", kind: "HTTPRoute"},
+	"ingress":   {apiVersion: "networking.k8s.io/v1", kind: "Ingress"},
+	"service":   {apiVersion: "v1", kind: "Service"},
+	"tcproute":  {apiVersion: "gateway.networking.k8s.io/v1alpha2", kind: "TCPRoute"},
+	"tlsroute":  {apiVersion: "gateway.networking.k8s.io/v1alpha2", kind: "TLSRoute"},
+	"udproute":  {apiVersion: "gateway.networking.k8s.io/v1alpha2", kind: "UDPRoute"},
+}
+
+// Paths to in-cluster service account credentials
+var (
+	serviceAccountCaPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// An [EventRecorder] that creates Kubernetes Events via the Kubernetes API using in-cluster service account credentials.
+// Requires RBAC permitting the creation of 'events' in the namespaces of source resources.
+type kubernetesEventRecorder struct {
+	client *http.Client
+	host   string
+	logger *slog.Logger
+}
+
+// Creates a new [kubernetesEventRecorder] from the in-cluster environment.
+// Returns an error if not running within a Kubernetes cluster.
+func NewKubernetesEventRecorder(l *slog.Logger) (*kubernetesEventRecorder, error) {
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	h := os.Getenv("KUBERNETES_SERVICE_HOST")
+	p := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if h == "" || p == "" {
+		return nil, fmt.Errorf("not running within a kubernetes cluster")
+	}
+	ca, err := os.ReadFile(serviceAccountCaPath)
+	if err != nil {
+		return nil, err
+	}
+	cp := x509.NewCertPool()
+	if !cp.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("service account ca invalid")
+	}
+	return &kubernetesEventRecorder{
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: cp}},
+		},
+		host:   net.JoinHostPort(h, p),
+		logger: l,
+	}, nil
+}
+
+// Records an event against the resource referenced by the endpoint's 'resource' label.
+// Endpoints without a (recognized) resource label are ignored.
+// Events are created asynchronously - failures are logged.
+func (r *kubernetesEventRecorder) Record(e *endpoint.Endpoint, et string, reason string, message string) {
+	rs := e.Labels[endpoint.ResourceLabelKey]
+	ps := strings.Split(rs, "/")
+	if len(ps) != 3 {
+		return
+	}
+	ok, found := eventObjectKinds[ps[0]]
+	if !found {
+		return
+	}
+	go func() {
+		err := r.create(ok, ps[1], ps[2], et, reason, message)
+		if err != nil {
+			r.logger.Warn(fmt.Sprintf("failed to create event for %s: %s", rs, err.Error()))
+		}
+	}()
+}
+
+// Creates a Kubernetes Event via the Kubernetes API
+func (r *kubernetesEventRecorder) create(ok eventObjectKind, ns string, n string, et string, reason string, message string) error {
+	t, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	ev := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata": map[string]any{
+			"generateName": fmt.Sprintf("%s.", n),
+			"namespace":    ns,
+		},
+		"involvedObject": map[string]any{
+			"apiVersion": ok.apiVersion,
+			"kind":       ok.kind,
+			"name":       n,
+			"namespace":  ns,
+		},
+		"count":          1,
+		"firstTimestamp": now,
+		"lastTimestamp":  now,
+		"message":        message,
+		"reason":         reason,
+		"source":         map[string]any{"component": "external-dns-routeros-provider"},
+		"type":           et,
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("https://%s/api/v1/namespaces/%s/events", r.host, ns)
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", strings.TrimSpace(string(t))))
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		rb, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("kubernetes api responded with %d: %s", res.StatusCode, string(rb))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Adopts existing unmanaged records matching records being created (see [Opts.AdoptExisting])
+	FeatureAdoptExisting = "AdoptExisting"
+	// Rolls back all changes of a sync when any of its changes fail (see [Opts.RollbackOnFailure])
+	FeatureRollbackOnFailure = "RollbackOnFailure"
+)
+
+// Features that can be toggled via [FeatureGates]
+var features = []string{FeatureAdoptExisting, FeatureRollbackOnFailure}
+
+// Enables or disables subsystems per deployment - allowing experimental subsystems to ship disabled.
+// Keyed by feature name (see [features]).
+// A gate overrides the option enabling its feature (e.g., 'RollbackOnFailure=false' disables rollbacks even if [Opts.RollbackOnFailure] is set) - features without a gate are controlled by their options.
+type FeatureGates map[string]bool
+
+// Parses feature gates of the form '<feature>=<bool>,...' (e.g., 'RollbackOnFailure=true,AdoptExisting=false').
+// Returns an error if a feature is unrecognized or a value isn't a bool.
+func ParseFeatureGates(s string) (FeatureGates, error) {
+	fg := FeatureGates{}
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		f, v, ok := strings.Cut(p, "=")
+		if !ok {
+			return nil, fmt.Errorf("feature gate %s not <feature>=<bool> format", p)
+		}
+		if !slices.Contains(features, f) {
+			return nil, fmt.Errorf("unrecognized feature %s (expected one of %s)", f, strings.Join(features, ", "))
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("feature gate %s value %s invalid: %w", f, v, err)
+		}
+		fg[f] = b
+	}
+	return fg, nil
+}
+
+// Returns a copy of the [Opts] with the options of gated features overridden (see [FeatureGates]).
+// Logs each gate applied.
+func (fg FeatureGates) apply(o Opts, l *slog.Logger) Opts {
+	for _, f := range features {
+		v, ok := fg[f]
+		if !ok {
+			continue
+		}
+		l.Info(fmt.Sprintf("feature gate %s=%t", f, v))
+		switch f {
+		case FeatureAdoptExisting:
+			o.AdoptExisting = v
+		case FeatureRollbackOnFailure:
+			o.RollbackOnFailure = v
+		}
+	}
+	return o
+}
@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Checks the given endpoints - provided to [provider.AdjustEndpoints] - against the provider's domain filter (see [provider.GetDomainFilter]).
+// External-dns silently discards endpoints outside of the domain filter - leaving users wondering why records are never created.
+// Warns (and records a kubernetes event) when an endpoint is first skipped - an endpoint is warned about again only if it stops being skipped in between.
+// Counts newly skipped endpoints - see [provider.FilteredCount], [provider.FilteredNames].
+func (p *provider) checkDomainFilter(es []*endpoint.Endpoint) {
+	df := p.GetDomainFilter()
+	fns := map[string]bool{}
+	for _, e := range es {
+		if df.Match(e.DNSName) {
+			continue
+		}
+		k := fmt.Sprintf("%s %s", e.RecordType, e.DNSName)
+		if fns[k] {
+			continue
+		}
+		fns[k] = true
+
+		p.filteredMutex.Lock()
+		ok := p.filteredNames[k]
+		p.filteredMutex.Unlock()
+		if ok {
+			continue
+		}
+		p.filteredCount.Add(1)
+		p.logger.Warn(fmt.Sprintf("skipping endpoint %s %s (resource %s): name outside of domain filter", e.RecordType, e.DNSName, e.Labels[endpoint.ResourceLabelKey]))
+		if p.eventRecorder != nil {
+			p.eventRecorder.Record(e, EventTypeWarning, "EndpointFiltered", fmt.Sprintf("routeros dns record %s %s skipped: name outside of domain filter", e.RecordType, e.DNSName))
+		}
+	}
+	p.filteredMutex.Lock()
+	defer p.filteredMutex.Unlock()
+	p.filteredNames = fns
+}
+
+// Returns the number of endpoints skipped (when first skipped) due to the domain filter - see [provider.checkDomainFilter]
+func (p *provider) FilteredCount() uint64 {
+	return p.filteredCount.Load()
+}
+
+// Returns the endpoints ('<type> <name>') currently skipped due to the domain filter - see [provider.checkDomainFilter]
+func (p *provider) FilteredNames() []string {
+	p.filteredMutex.Lock()
+	defer p.filteredMutex.Unlock()
+	fns := []string{}
+	for k := range p.filteredNames {
+		fns = append(fns, k)
+	}
+	slices.Sort(fns)
+	return fns
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// The outcome of a health check - see [Client.Health]
+type HealthReport struct {
+	// The identity (see '/system identity') of the router
+	Identity string `json:"identity"`
+	// Round-trip time of the health check's api call
+	Latency time.Duration `json:"latency"`
+	// Reports of the routers of routes (see [RouteConfig]) - keyed by route name
+	Routes map[string]HealthReport `json:"routes,omitempty"`
+	Uptime string                  `json:"uptime"`
+	// The routeros version of the router
+	Version string `json:"version"`
+}
+
+// Performs a health check of the client by querying routeros for details describing the device (see [client.queryRouter]).
+// If the query fails and returns an error, this indicates the client is unhealthy.
+// Returns the context's error if the context is done before the check completes - the check's connection is bound to the context (see [client.dial]).
+func (c *client) Health(co context.Context) (HealthReport, error) {
+	hr, err := c.queryRouter(co)
+	if err != nil && co.Err() != nil {
+		return HealthReport{}, co.Err()
+	}
+	return hr, err
+}
+
+// The outcome of the provider's most recent health check - see [provider.Health]
+type HealthStatus struct {
+	Error  string       `json:"error,omitempty"`
+	Report HealthReport `json:"report"`
+	Time   time.Time    `json:"time"`
+}
+
+// Performs a health check of provider and client - see [Client.Health].
+// The outcome is retained (see [provider.HealthStatus]).
+// Returns an error if the provider/client are unhealthy.
+func (p *provider) Health(co context.Context) (HealthReport, error) {
+	l := p.contextLogger(co)
+	l.Info("performing health check")
+	// [provider.contextClient] returns a copy - ensuring concurrent checks use their own routeros connection
+	hr, err := p.contextClient(co, p.client).Health(co)
+	if err != nil {
+		err = fmt.Errorf("client health check failed: %w", err)
+	}
+	hs := HealthStatus{Report: hr, Time: time.Now()}
+	if err != nil {
+		hs.Error = err.Error()
+	}
+	p.healthMutex.Lock()
+	p.healthStatus = hs
+	p.healthMutex.Unlock()
+	return hr, err
+}
+
+// Returns the outcome of the provider's most recent health check (see [provider.Health]) - zero if no check has been performed
+func (p *provider) HealthStatus() HealthStatus {
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+	return p.healthStatus
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHealthReportsInfo(t *testing.T) {
+	c := newStubClient(t, ClientOpts{}, newStubRouter())
+	hr, err := c.Health(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hr.Identity != "stub" {
+		t.Errorf("identity %q, expected stub", hr.Identity)
+	}
+	if hr.Latency <= 0 {
+		t.Errorf("latency %s, expected a measured latency", hr.Latency)
+	}
+}
+
+func TestHealthHonoursContext(t *testing.T) {
+	// accepts connections - but never responds
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	c, err := NewClient(&ClientOpts{Address: l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	co, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	st := time.Now()
+	_, err = c.Health(co)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(st); d > loginTimeout/2 {
+		t.Errorf("health check took %s", d)
+	}
+}
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"fmt"
+	"sync"
+)
+
+// The identity (see '/system/identity') of a routeros device.
+// Discovered when a [client] first connects (see [client.withClient]) and shared by copies of the client.
+type routerIdentity struct {
+	mutex sync.Mutex
+	name  string
+	ok    bool
+}
+
+// Returns the discovered identity - or an empty string if not yet discovered
+func (ri *routerIdentity) get() string {
+	ri.mutex.Lock()
+	defer ri.mutex.Unlock()
+	return ri.name
+}
+
+// Returns the identity of the connected routeros device - querying routeros (via the connection opened by [client.withClient]) if not yet discovered.
+// Failures are logged and return an empty identity - discovery is retried during the client's next connection.
+// The query runs via [client.run] (which reads the identity) - the identity's mutex is therefore not held during the query.
+func (c *client) discoverIdentity() string {
+	c.identity.mutex.Lock()
+	if c.identity.ok {
+		defer c.identity.mutex.Unlock()
+		return c.identity.name
+	}
+	c.identity.mutex.Unlock()
+	rep, err := c.run([]string{"/system/identity/print"})
+	if err != nil {
+		c.logger.Debug(fmt.Sprintf("failed to discover router identity: %s", err.Error()))
+		return ""
+	}
+	c.identity.mutex.Lock()
+	defer c.identity.mutex.Unlock()
+	if len(rep.Re) > 0 {
+		c.identity.name = rep.Re[0].Map["name"]
+	}
+	c.identity.ok = true
+	return c.identity.name
+}
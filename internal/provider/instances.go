@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v2"
+)
+
+// Configuration of a named provider instance - allowing several providers (e.g., for different routers) to run within a single process.
+// Fields that are set override their equivalent [Opts] fields.
+type InstanceConfig struct {
+	FilterExclude           []string `yaml:"filterExclude"`
+	FilterInclude           []string `yaml:"filterInclude"`
+	FilterRegexExclude      string   `yaml:"filterRegexExclude"`
+	FilterRegexInclude      string   `yaml:"filterRegexInclude"`
+	IntentLog               string   `yaml:"intentLog"`
+	InternalServerPort      *uint    `yaml:"internalServerPort"`
+	Name                    string   `yaml:"name"`
+	RouterOSAddress         string   `yaml:"routerosAddress"`
+	RouterOSCertFingerprint string   `yaml:"routerosCertFingerprint"`
+	RouterOSFallbackIP      string   `yaml:"routerosFallbackIp"`
+	RouterOSMenu            string   `yaml:"routerosMenu"`
+	RouterOSPassword        string   `yaml:"routerosPassword"`
+	RouterOSUsername        string   `yaml:"routerosUsername"`
+	ServerPort              *uint    `yaml:"serverPort"`
+}
+
+// The shape of the yaml file read by [ReadInstancesFile]
+type instancesFile struct {
+	Instances []InstanceConfig `yaml:"instances"`
+}
+
+// Reads a list of [InstanceConfig] from the yaml file at the given path.
+// Returns an error if the file cannot be read or parsed.
+func ReadInstancesFile(p string) ([]InstanceConfig, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		return []InstanceConfig{}, err
+	}
+	f := instancesFile{}
+	err = yaml.UnmarshalStrict(d, &f)
+	if err != nil {
+		return []InstanceConfig{}, fmt.Errorf("instances file %s invalid: %w", p, err)
+	}
+	return f.Instances, nil
+}
+
+// Returns a copy of the provided [Opts] overridden by fields set within the [InstanceConfig].
+// If the base options enable an intent log that the instance doesn't override, the instance's name is appended to its path - preventing instances from sharing an intent log.
+// Similarly, the instance's name is appended to the paths of probe files (see [ProbeFileOpts]).
+// Returns an error if a regex filter fails to compile.
+func (ic InstanceConfig) apply(o Opts) (Opts, error) {
+	if ic.FilterExclude != nil {
+		o.FilterExclude = ic.FilterExclude
+	}
+	if ic.FilterInclude != nil {
+		o.FilterInclude = ic.FilterInclude
+	}
+	if ic.FilterRegexExclude != "" {
+		fre, err := regexp.Compile(ic.FilterRegexExclude)
+		if err != nil {
+			return Opts{}, err
+		}
+		o.FilterRegexExclude = fre
+	}
+	if ic.FilterRegexInclude != "" {
+		fri, err := regexp.Compile(ic.FilterRegexInclude)
+		if err != nil {
+			return Opts{}, err
+		}
+		o.FilterRegexInclude = fri
+	}
+	if ic.IntentLog != "" {
+		o.IntentLog = ic.IntentLog
+	} else if o.IntentLog != "" {
+		o.IntentLog = fmt.Sprintf("%s.%s", o.IntentLog, ic.Name)
+	}
+	if o.HeartbeatFile != "" {
+		o.HeartbeatFile = fmt.Sprintf("%s.%s", o.HeartbeatFile, ic.Name)
+	}
+	if o.ReadyFile != "" {
+		o.ReadyFile = fmt.Sprintf("%s.%s", o.ReadyFile, ic.Name)
+	}
+	if ic.RouterOSAddress != "" {
+		// the fallback ip is specific to a router
+		o.RouterOSAddress = ic.RouterOSAddress
+		o.RouterOSFallbackIP = ""
+	}
+	if ic.RouterOSCertFingerprint != "" {
+		o.RouterOSCertFingerprint = ic.RouterOSCertFingerprint
+	}
+	if ic.RouterOSFallbackIP != "" {
+		o.RouterOSFallbackIP = ic.RouterOSFallbackIP
+	}
+	if ic.RouterOSMenu != "" {
+		o.RouterOSMenu = ic.RouterOSMenu
+	}
+	if ic.RouterOSPassword != "" {
+		o.RouterOSPassword = ic.RouterOSPassword
+	}
+	if ic.RouterOSUsername != "" {
+		o.RouterOSUsername = ic.RouterOSUsername
+	}
+	if ic.InternalServerPort != nil {
+		o.InternalServerPort = *ic.InternalServerPort
+	}
+	if ic.ServerPort != nil {
+		o.ServerPort = *ic.ServerPort
+	}
+	return o, nil
+}
+
+// Returns the (non-zero) ports bound by the server configured within the [Opts] - its webhook and internal ports
+func serverPorts(o Opts) []uint {
+	ps := []uint{}
+	for _, p := range []uint{o.ServerPort, o.InternalServerPort} {
+		if p != 0 {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// Runs several named provider instances (each with its own [server]) within a single process
+type instances struct {
+	names   []string
+	servers []*server
+}
+
+// Initializes a provider instance (see [New]) per [InstanceConfig] - each using [Opts] overridden by its config.
+// Returns an error if instance names are missing or duplicated, if instances share a server port, or if an instance fails to initialize.
+func NewInstances(o *Opts, ics []InstanceConfig) (*instances, error) {
+	l := o.Logger
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if len(ics) == 0 {
+		return nil, fmt.Errorf("no instances configured")
+	}
+	is := instances{names: []string{}, servers: []*server{}}
+	ps := map[uint]string{}
+	for _, ic := range ics {
+		if ic.Name == "" {
+			return nil, fmt.Errorf("instance name unset")
+		}
+		for _, n := range is.names {
+			if n == ic.Name {
+				return nil, fmt.Errorf("instance %s defined multiple times", ic.Name)
+			}
+		}
+		iop, err := ic.apply(*o)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s invalid: %w", ic.Name, err)
+		}
+		for _, p := range serverPorts(iop) {
+			pn, ok := ps[p]
+			if ok {
+				return nil, fmt.Errorf("instances %s and %s share server port %d", pn, ic.Name, p)
+			}
+			ps[p] = ic.Name
+		}
+		iop.Logger = l.With("instance", ic.Name)
+		s, err := New(&iop)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", ic.Name, err)
+		}
+		is.names = append(is.names, ic.Name)
+		is.servers = append(is.servers, s)
+	}
+	return &is, nil
+}
+
+// Runs the server of each instance.
+// If any server fails, the remaining servers are stopped and the failure is returned.
+func (is *instances) Run() error {
+	g, c := errgroup.WithContext(context.Background())
+	for i, s := range is.servers {
+		n := is.names[i]
+		g.Go(func() error {
+			err := s.Run()
+			if errors.Is(err, http.ErrServerClosed) && c.Err() != nil {
+				// stopped due to another instance's failure
+				return nil
+			}
+			return fmt.Errorf("instance %s: %w", n, err)
+		})
+	}
+	g.Go(func() error {
+		<-c.Done()
+		for _, s := range is.servers {
+			s.Close()
+		}
+		return nil
+	})
+	return g.Wait()
+}
+
+// Toggles maintenance mode for all instances - see [server.ToggleMaintenance]
+func (is *instances) ToggleMaintenance() {
+	for _, s := range is.servers {
+		s.ToggleMaintenance()
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// A write-ahead log persisting the changes of an in-progress sync to disk.
+// If the provider crashes mid-sync, the changes are found on restart and the sync is completed (see [provider.Recover]).
+// Safe for concurrent use.
+type intentLog struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// Creates a new [intentLog] persisting changes to the given path
+func newIntentLog(p string) *intentLog {
+	return &intentLog{path: p}
+}
+
+// Persists changes to the intent log - replacing previously persisted changes.
+// The file is replaced atomically - a crash while writing leaves either the previous or the new changes.
+func (il *intentLog) Write(ch *plan.Changes) error {
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+	d, err := json.Marshal(ch)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(il.path), filepath.Base(il.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(d)
+	if err == nil {
+		err = f.Sync()
+	}
+	cerr := f.Close()
+	if err != nil {
+		return err
+	}
+	if cerr != nil {
+		return cerr
+	}
+	return os.Rename(f.Name(), il.path)
+}
+
+// Reads changes from the intent log.
+// Returns false if no changes are persisted.
+func (il *intentLog) Read() (*plan.Changes, bool, error) {
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+	d, err := os.ReadFile(il.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	ch := &plan.Changes{}
+	err = json.Unmarshal(d, ch)
+	if err != nil {
+		return nil, false, err
+	}
+	return ch, true, nil
+}
+
+// Removes persisted changes from the intent log
+func (il *intentLog) Clear() error {
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+	err := os.Remove(il.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Records changes made to routeros records - allowing a batch of changes to be rolled back (see [client.Rollback]).
+// Held in memory - unlike routeros' safe mode (unavailable to api sessions), changes are not rolled back if the webhook exits before rolling them back.
+// Attached to a [client] via [client.WithJournal] - copies of the client (see [client.WithLogAttrs]) share the journal.
+// Safe for concurrent use.
+type Journal struct {
+	entries []journalEntry
+	mutex   sync.Mutex
+}
+
+// A change recorded within a [Journal] alongside the operation that undoes it.
+// The router address identifies the [client] able to undo the change - allowing a journal to be shared by clients of different routers (see [routedClient]).
+type journalEntry struct {
+	desc   string
+	router string
+	undo   func(c *client) error
+}
+
+// Creates a new, empty [Journal]
+func NewJournal() *Journal {
+	return &Journal{entries: []journalEntry{}}
+}
+
+// Records a change and the operation that undoes it
+func (j *Journal) add(router string, desc string, undo func(c *client) error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.entries = append(j.entries, journalEntry{desc: desc, router: router, undo: undo})
+}
+
+// Returns the number of changes recorded within the journal
+func (j *Journal) Len() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return len(j.entries)
+}
+
+// Returns a copy of the [client] recording changes to the given [Journal]
+func (c *client) WithJournal(j *Journal) Client {
+	cc := *c
+	cc.client = nil
+	cc.journal = j
+	return &cc
+}
+
+// Undoes the changes made to this client's router recorded within the [Journal] - most recent first.
+// Changes made to other routers are left within the journal.
+// Undo operations are not themselves journaled.
+// Failing undo operations are logged and skipped - returning an error once all operations have been attempted.
+func (c *client) Rollback(j *Journal) error {
+	j.mutex.Lock()
+	es := []journalEntry{}
+	rs := []journalEntry{}
+	for _, e := range j.entries {
+		if e.router == c.address {
+			es = append(es, e)
+		} else {
+			rs = append(rs, e)
+		}
+	}
+	j.entries = rs
+	j.mutex.Unlock()
+	if len(es) == 0 {
+		return nil
+	}
+
+	cc := *c
+	cc.client = nil
+	cc.journal = nil
+	return cc.withClient(func() error {
+		errs := []error{}
+		for i := len(es) - 1; i >= 0; i-- {
+			e := es[i]
+			cc.logger.Info(fmt.Sprintf("rolling back: %s", e.desc))
+			err := e.undo(&cc)
+			if err != nil {
+				cc.logger.Warn(fmt.Sprintf("failed to roll back %s: %s", e.desc, err.Error()))
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
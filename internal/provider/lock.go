@@ -0,0 +1,270 @@
+package provider
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options controlling the lock taken on routeros while changes are applied - serializing the writes of independent providers (e.g., in different clusters) sharing a router.
+// The lock is a TXT record (not managed by external-dns) identifying its owner and expiry.
+type LockOpts struct {
+	// How long the lock is held before it expires - allowing other providers to take the lock if its owner crashes. Defaults to 5m.
+	Lease time.Duration
+	// Name of the TXT record holding the lock - locking is disabled when empty
+	Record string
+	// How long to wait for the lock before failing. Defaults to 1m.
+	Timeout time.Duration
+}
+
+// The interval between attempts to acquire a held lock
+const lockInterval = time.Second
+
+// Returned when a lock is held by another owner
+type LockHeldError struct {
+	Expires time.Time
+	Owner   string
+}
+
+func (e LockHeldError) Error() string {
+	return fmt.Sprintf("lock held by %s (expires %s)", e.Owner, e.Expires.Format(time.RFC3339))
+}
+
+// Produces the text of a lock record
+func formatLock(owner string, exp time.Time) string {
+	return fmt.Sprintf("owner=%s;expires=%d", owner, exp.Unix())
+}
+
+// Parses the text of a lock record - returning its owner and expiry.
+// Unparseable locks are treated as expired.
+func parseLock(t string) (string, time.Time) {
+	o := ""
+	exp := time.Time{}
+	for _, p := range strings.Split(t, ";") {
+		k, v, _ := strings.Cut(p, "=")
+		switch k {
+		case "owner":
+			o = v
+		case "expires":
+			s, err := strconv.ParseInt(v, 10, 64)
+			if err == nil {
+				exp = time.Unix(s, 0)
+			}
+		}
+	}
+	return o, exp
+}
+
+// Lists the lock records with the given name - ordered by id (i.e., creation order)
+func (c *client) listLockRecords(n string) ([]dnsRecord, error) {
+	rs, err := c.backend.List(c.run)
+	if err != nil {
+		return []dnsRecord{}, err
+	}
+	lrs := []dnsRecord{}
+	for _, r := range rs {
+		if r.Type == "TXT" && normalizeDnsName(r.Name) == normalizeDnsName(n) {
+			lrs = append(lrs, r)
+		}
+	}
+	slices.SortFunc(lrs, func(a dnsRecord, b dnsRecord) int {
+		ai, _ := strconv.ParseUint(strings.TrimPrefix(a.Id, "*"), 16, 64)
+		bi, _ := strconv.ParseUint(strings.TrimPrefix(b.Id, "*"), 16, 64)
+		return cmp.Compare(ai, bi)
+	})
+	return lrs, nil
+}
+
+// Attempts to acquire (or extend) the lock held within the named TXT record for the given owner.
+// As routeros offers no compare-and-set, a lock record is created and records are re-listed - when several providers race, the earliest lock record wins and the others are deleted.
+// Lock records are written directly via the [client]'s [recordBackend] and are not journaled.
+// Returns a [LockHeldError] if another owner holds an unexpired lock.
+func (c *client) AcquireLock(n string, owner string, lease time.Duration) error {
+	return c.withClient(func() error {
+		lrs, err := c.listLockRecords(n)
+		if err != nil {
+			return err
+		}
+		v := map[string]string{"text": formatLock(owner, time.Now().Add(lease))}
+		for _, lr := range lrs {
+			lo, exp := parseLock(lr.Text)
+			if lo != owner && time.Now().Before(exp) {
+				return LockHeldError{Expires: exp, Owner: lo}
+			}
+		}
+		if len(lrs) > 0 {
+			// expired (or already owned) - take over the earliest lock record, deleting others
+			c.logger.Debug(fmt.Sprintf("update lock record %s (%s)", n, lrs[0].Id))
+			err = c.backend.Update(c.run, lrs[0].Id, v)
+			if err != nil {
+				return err
+			}
+			for _, lr := range lrs[1:] {
+				err = c.backend.Delete(c.run, lr.Id)
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			c.logger.Debug(fmt.Sprintf("create lock record %s", n))
+			v["name"] = normalizeDnsName(n)
+			v["type"] = "TXT"
+			_, err = c.backend.Create(c.run, v)
+			if err != nil {
+				return err
+			}
+		}
+		if c.dryRun != nil {
+			return nil
+		}
+		lrs, err = c.listLockRecords(n)
+		if err != nil {
+			return err
+		}
+		if len(lrs) == 0 {
+			return fmt.Errorf("lock record %s missing after creation", n)
+		}
+		lo, exp := parseLock(lrs[0].Text)
+		if lo != owner {
+			// another provider's lock record was created first
+			for _, lr := range lrs[1:] {
+				o, _ := parseLock(lr.Text)
+				if o == owner {
+					err = c.backend.Delete(c.run, lr.Id)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return LockHeldError{Expires: exp, Owner: lo}
+		}
+		return nil
+	})
+}
+
+// Releases the lock held within the named TXT record by the given owner.
+// Locks held by other owners are left unchanged.
+func (c *client) ReleaseLock(n string, owner string) error {
+	return c.withClient(func() error {
+		lrs, err := c.listLockRecords(n)
+		if err != nil {
+			return err
+		}
+		for _, lr := range lrs {
+			lo, _ := parseLock(lr.Text)
+			if lo != owner {
+				continue
+			}
+			c.logger.Debug(fmt.Sprintf("delete lock record %s (%s)", n, lr.Id))
+			err = c.backend.Delete(c.run, lr.Id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Acquires the lock on every router (see [client.AcquireLock]) - in a fixed order, preventing deadlocks between providers.
+// If any lock cannot be acquired, the acquired locks are released.
+func (rc *routedClient) AcquireLock(n string, owner string, lease time.Duration) error {
+	for i, c := range rc.clients {
+		err := c.AcquireLock(n, owner, lease)
+		if err != nil {
+			for _, ac := range rc.clients[:i] {
+				ac.ReleaseLock(n, owner)
+			}
+			return fmt.Errorf("route %s: %w", rc.names[i], err)
+		}
+	}
+	return nil
+}
+
+// Releases the lock on every router (see [client.ReleaseLock])
+func (rc *routedClient) ReleaseLock(n string, owner string) error {
+	errs := []error{}
+	for i, c := range rc.clients {
+		err := c.ReleaseLock(n, owner)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", rc.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Produces an owner identifying this provider process - its hostname and a random suffix.
+// Returns an error if the random suffix cannot be generated.
+func newLockOwner() (string, error) {
+	h, err := os.Hostname()
+	if err != nil {
+		h = "unknown"
+	}
+	b := make([]byte, 4)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", h, hex.EncodeToString(b)), nil
+}
+
+// Acquires the provider's lock (see [LockOpts]) - retrying while the lock is held until the lock timeout elapses (or the context is done).
+// While held, the lock's lease is renewed in the background (every half lease) - preventing the lock from expiring during long syncs.
+// Returns a function releasing the lock (and stopping its renewal).
+func (p *provider) acquireLock(co context.Context, pc Client) (func(), error) {
+	l := p.contextLogger(co)
+	d := time.Now().Add(p.lock.Timeout)
+	for {
+		err := pc.AcquireLock(p.lock.Record, p.lockOwner, p.lock.Lease)
+		if err == nil {
+			break
+		}
+		if time.Now().After(d) {
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", p.lock.Record, err)
+		}
+		l.Info(fmt.Sprintf("waiting for lock %s: %s", p.lock.Record, err.Error()))
+		select {
+		case <-co.Done():
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", p.lock.Record, errors.Join(err, co.Err()))
+		case <-time.After(lockInterval):
+		}
+	}
+	l.Debug(fmt.Sprintf("acquired lock %s", p.lock.Record))
+
+	// the client isn't safe for concurrent use - it is used by the renewal until the renewal stops
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(max(p.lock.Lease/2, lockInterval))
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+			err := pc.AcquireLock(p.lock.Record, p.lockOwner, p.lock.Lease)
+			if err != nil {
+				l.Warn(fmt.Sprintf("failed to renew lock %s: %s", p.lock.Record, err.Error()))
+				continue
+			}
+			l.Debug(fmt.Sprintf("renewed lock %s", p.lock.Record))
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		err := pc.ReleaseLock(p.lock.Record, p.lockOwner)
+		if err != nil {
+			l.Warn(fmt.Sprintf("failed to release lock %s: %s", p.lock.Record, err.Error()))
+		}
+	}, nil
+}
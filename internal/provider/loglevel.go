@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parses a logging level ('error' | 'warn' | 'info' | 'debug') - defaulting to 'info' when empty
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch s {
+	case "error":
+		return slog.LevelError, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	default:
+		return 0, fmt.Errorf("unrecognized log level %s", s)
+	}
+}
+
+// A logging level that can be changed at runtime (e.g., to temporarily enable debug logging while reproducing an issue) without restarting the process.
+// Implements [slog.Leveler] - and is used as the level of the application's [slog.Handler].
+// Safe for concurrent use.
+type LogLevel struct {
+	base   slog.Level
+	level  slog.LevelVar
+	mutex  sync.Mutex
+	revert time.Time
+	timer  *time.Timer
+}
+
+// Creates a new [LogLevel] starting at (and reverting to) the given level
+func NewLogLevel(l slog.Level) *LogLevel {
+	ll := &LogLevel{base: l}
+	ll.level.Set(l)
+	return ll
+}
+
+// Returns the current logging level
+func (ll *LogLevel) Level() slog.Level {
+	return ll.level.Level()
+}
+
+// Sets the logging level.
+// If the duration is non-zero, the level reverts to the initial level once it elapses.
+func (ll *LogLevel) Set(l slog.Level, d time.Duration) {
+	ll.mutex.Lock()
+	defer ll.mutex.Unlock()
+	if ll.timer != nil {
+		ll.timer.Stop()
+		ll.timer = nil
+	}
+	ll.level.Set(l)
+	ll.revert = time.Time{}
+	if d > 0 {
+		ll.revert = time.Now().Add(d)
+		ll.timer = time.AfterFunc(d, ll.Reset)
+	}
+}
+
+// Reverts to the initial logging level
+func (ll *LogLevel) Reset() {
+	ll.Set(ll.base, 0)
+}
+
+// Toggles debug logging - enabling debug logging (see [LogLevel.Set]) unless already enabled, otherwise reverting to the initial level
+func (ll *LogLevel) ToggleDebug(d time.Duration) {
+	if ll.Level() == slog.LevelDebug && ll.base != slog.LevelDebug {
+		ll.Reset()
+		return
+	}
+	ll.Set(slog.LevelDebug, d)
+}
+
+// Status of the logging level returned by log level endpoints
+type LogLevelStatus struct {
+	Level string `json:"level"`
+	// The time the level reverts to the initial level (empty if permanent)
+	RevertAt string `json:"revertAt,omitempty"`
+}
+
+// Returns the [LogLevelStatus] of the [LogLevel]
+func (ll *LogLevel) Status() LogLevelStatus {
+	ll.mutex.Lock()
+	defer ll.mutex.Unlock()
+	lls := LogLevelStatus{Level: strings.ToLower(ll.level.Level().String())}
+	if !ll.revert.IsZero() {
+		lls.RevertAt = ll.revert.UTC().Format(time.RFC3339)
+	}
+	return lls
+}
@@ -1,70 +1,364 @@
 package provider
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"regexp"
+	"slices"
+	"time"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
 // Options to provide to the main entry point [New]
 type Opts struct {
-	FilterExclude      []string
-	FilterInclude      []string
-	FilterRegexExclude *regexp.Regexp
-	FilterRegexInclude *regexp.Regexp
-	Logger             *slog.Logger
-	RouterOSAddress    string
-	RouterOSPassword   string
-	RouterOSUsername   string
-	ServerHost         string
-	ServerPort         uint
+	AdoptExisting           bool
+	ApplyConcurrency        uint
+	ApplyBatchDelay         time.Duration
+	ApplyBatchSize          uint
+	ApplyDebounce           time.Duration
+	Backend                 string
+	ConfigFile              string
+	ConfigFileInterval      time.Duration
+	ConflictPolicy          string
+	EnablePprof             bool
+	FeatureGates            FeatureGates
+	FilterExclude           []string
+	FilterInclude           []string
+	FilterRegexExclude      *regexp.Regexp
+	FilterRegexInclude      *regexp.Regexp
+	HeartbeatFile           string
+	HeartbeatInterval       time.Duration
+	IntegrityInterval       time.Duration
+	IntegrityRepair         bool
+	IntentLog               string
+	InternalServerHost      string
+	InternalServerPort      uint
+	KubernetesEvents        bool
+	LockLease               time.Duration
+	LockRecord              string
+	LockTimeout             time.Duration
+	Logger                  *slog.Logger
+	LogLevel                *LogLevel
+	LogSampleLimit          uint
+	MetadataStore           string
+	MetricsInterval         time.Duration
+	MetricsOtlpEndpoint     string
+	MetricsOtlpProxy        string
+	MetricsStatsdAddress    string
+	MigrateRecords          bool
+	NamePolicy              string
+	OnReady                 ReadyCallback
+	ProtectedNames          []string
+	ProtectedRegex          *regexp.Regexp
+	QuotaLabel              string
+	QuotaLabelMax           uint
+	QuotaNamespace          uint
+	ReadyFile               string
+	RecordsCacheTtl         time.Duration
+	RewriteRules            []RewriteRule
+	RollbackOnFailure       bool
+	RouterOSAddress         string
+	RouterOSCertFingerprint string
+	RouterOSFallbackIP      string
+	RouterOSMenu            string
+	RouterOSPassword        string
+	RouterOSResolver        string
+	RouterOSUsername        string
+	Routes                  []RouteConfig
+	SerialRecord            string
+	SerialScript            string
+	ServerHost              string
+	ServerPort              uint
+	Standby                 bool
+	TraceRouterOS           bool
+	TtlHigh                 time.Duration
+	TtlLow                  time.Duration
+	TxtMaxLength            uint
+	VerifyAddress           string
+	VerifyWindow            time.Duration
+}
+
+// Returns the address of the dns server queried when verifying records (see [verifier]).
+// Defaults to the standard dns port of the routeros device.
+func (o Opts) verifyAddress() string {
+	if o.VerifyAddress != "" {
+		return o.VerifyAddress
+	}
+	h, _, err := net.SplitHostPort(o.RouterOSAddress)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(h, "53")
 }
 
 // Initializes the application and returns the configured [server] exposing the provider webhook.
+// If a config file is provided, its settings override those within [Opts] and the file is polled for changes in the background (see [FileConfig]).
+// Feature gates override both (see [FeatureGates]).
+// A server port of 0 binds an ephemeral port (see [ServerOpts.Listener]).
 func New(o *Opts) (*server, error) {
 	l := o.Logger
 	if l == nil {
 		l = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
 
-	pc, err := NewClient(&ClientOpts{
-		Address:  o.RouterOSAddress,
-		Logger:   l.With("name", "client"),
-		Password: o.RouterOSPassword,
-		Username: o.RouterOSUsername,
-	})
+	// options prior to applying the config file - used as the base when the config file is reloaded
+	bo := *o
+	var cfd []byte
+	if o.ConfigFile != "" {
+		fc, d, err := ReadFileConfig(o.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		fo, err := fc.apply(*o)
+		if err != nil {
+			return nil, err
+		}
+		cfd = d
+		o = &fo
+	}
+	fo := o.FeatureGates.apply(*o, l)
+	o = &fo
+
+	pc, err := NewRoutedClient(&ClientOpts{
+		Address:         o.RouterOSAddress,
+		AdoptExisting:   o.AdoptExisting,
+		Backend:         o.Backend,
+		CertFingerprint: o.RouterOSCertFingerprint,
+		FallbackIP:      o.RouterOSFallbackIP,
+		ConflictPolicy:  o.ConflictPolicy,
+		Logger:          l.With("name", "client"),
+		Menu:            o.RouterOSMenu,
+		MetadataStore:   o.MetadataStore,
+		Password:        o.RouterOSPassword,
+		Resolver:        o.RouterOSResolver,
+		Trace:           o.TraceRouterOS,
+		TxtMaxLength:    o.TxtMaxLength,
+		Username:        o.RouterOSUsername,
+	}, o.Routes)
 	if err != nil {
 		return nil, err
 	}
 
-	var df endpoint.DomainFilter
-	if o.FilterRegexExclude != nil || o.FilterRegexInclude != nil {
-		df = endpoint.NewRegexDomainFilter(o.FilterRegexInclude, o.FilterRegexExclude)
-	} else if o.FilterExclude != nil {
-		df = endpoint.NewDomainFilterWithExclusions(o.FilterInclude, o.FilterExclude)
-	} else {
-		df = endpoint.NewDomainFilter(o.FilterInclude)
+	err = pc.CheckBackend()
+	if errors.As(err, &MenuUnavailableError{}) {
+		// every command would fail - fail fast with an actionable error
+		return nil, err
+	} else if err != nil {
+		l.Warn(fmt.Sprintf("failed to check routeros dns support: %s", err.Error()))
+	}
+
+	var er EventRecorder
+	if o.KubernetesEvents {
+		ker, err := NewKubernetesEventRecorder(l.With("name", "events"))
+		if err != nil {
+			return nil, err
+		}
+		er = ker
 	}
+
+	df := o.domainFilter()
 	p, err := NewProvider(&ProviderOpts{
-		Client:       pc,
-		DomainFilter: df,
-		Logger:       l.With("name", "provider"),
+		ApplyDebounce:     o.ApplyDebounce,
+		Batch:             BatchOpts{Delay: o.ApplyBatchDelay, Size: o.ApplyBatchSize},
+		Client:            pc,
+		Concurrency:       o.ApplyConcurrency,
+		DomainFilter:      df,
+		EventRecorder:     er,
+		Integrity:         IntegrityOpts{Interval: o.IntegrityInterval, Repair: o.IntegrityRepair},
+		IntentLog:         o.IntentLog,
+		Lock:              LockOpts{Lease: o.LockLease, Record: o.LockRecord, Timeout: o.LockTimeout},
+		Logger:            l.With("name", "provider"),
+		LogSampleLimit:    o.LogSampleLimit,
+		NamePolicy:        o.NamePolicy,
+		ProtectedNames:    o.ProtectedNames,
+		ProtectedRegex:    o.ProtectedRegex,
+		QuotaLabel:        o.QuotaLabel,
+		QuotaLabelMax:     o.QuotaLabelMax,
+		QuotaNamespace:    o.QuotaNamespace,
+		RecordsCache:      o.RecordsCacheTtl,
+		RewriteRules:      o.RewriteRules,
+		RollbackOnFailure: o.RollbackOnFailure,
+		Serial:            SerialOpts{Record: o.SerialRecord, Script: o.SerialScript},
+		Ttl:               TtlOpts{High: o.TtlHigh, Low: o.TtlLow},
+		Verify:            VerifyOpts{Address: o.verifyAddress(), Window: o.VerifyWindow},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	s, err := NewServer(&ServerOpts{
-		Host:     o.ServerHost,
-		Logger:   l.With("name", "server"),
-		Port:     o.ServerPort,
-		Provider: p,
-	})
+	if o.ConfigFile != "" {
+		ci := o.ConfigFileInterval
+		if ci == 0 {
+			ci = 10 * time.Second
+		}
+		w := configFileWatcher{
+			callback: func(fc FileConfig) error {
+				fo, err := fc.apply(bo)
+				if err != nil {
+					return err
+				}
+				p.UpdateSettings(ProviderSettings{
+					Concurrency:    fo.ApplyConcurrency,
+					DomainFilter:   fo.domainFilter(),
+					LogSampleLimit: fo.LogSampleLimit,
+				})
+				return nil
+			},
+			data:     cfd,
+			interval: ci,
+			logger:   l.With("name", "config"),
+			path:     o.ConfigFile,
+		}
+		go w.Run()
+	}
+
+	if !o.Standby {
+		// standby instances do not modify records
+		if o.MigrateRecords {
+			_, err := pc.MigrateRecords()
+			if err != nil {
+				l.Warn(fmt.Sprintf("failed to migrate records: %s", err.Error()))
+			}
+		}
+		err = p.Recover(context.Background())
+		if err != nil {
+			l.Warn(fmt.Sprintf("failed to recover interrupted sync: %s", err.Error()))
+		}
+		go p.RunIntegrityChecks()
+	}
+	mo := MetricsOpts{Interval: o.MetricsInterval, OtlpEndpoint: o.MetricsOtlpEndpoint, OtlpProxy: o.MetricsOtlpProxy, StatsdAddress: o.MetricsStatsdAddress}
+	_, err = mo.exporters()
+	if err != nil {
+		return nil, err
+	}
+	go p.RunMetricsExport(mo, l.With("name", "metrics"))
+
+	so := &ServerOpts{
+		EnablePprof:  o.EnablePprof,
+		Host:         o.ServerHost,
+		InternalHost: o.InternalServerHost,
+		InternalPort: o.InternalServerPort,
+		Logger:       l.With("name", "server"),
+		LogLevel:     o.LogLevel,
+		OnReady:      o.OnReady,
+		Port:         o.ServerPort,
+		ProbeFiles:   ProbeFileOpts{HeartbeatFile: o.HeartbeatFile, HeartbeatInterval: o.HeartbeatInterval, ReadyFile: o.ReadyFile},
+		Provider:     p,
+		Standby:      o.Standby,
+	}
+	err = so.validate()
+	if err != nil {
+		return nil, err
+	}
+	if o.ServerPort == 0 {
+		// binds an ephemeral port - [NewServer] otherwise defaults the port
+		h := o.ServerHost
+		if h == "" {
+			h = "127.0.0.1"
+		}
+		so.Listener, err = net.Listen("tcp", net.JoinHostPort(h, "0"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	s, err := NewServer(so)
 	if err != nil {
 		return nil, err
 	}
 
 	return s, nil
 }
+
+// Options used when adopting existing records via [Adopt]
+type AdoptOpts struct {
+	// Adopt records whose names match the domain filter within [Opts]
+	AllMatchingFilter bool
+	// Report the records that would be adopted without adopting them
+	DryRun bool
+	// Adopt records with these dns names
+	Names []string
+}
+
+// Adopts existing unmanaged routeros records (see [client.AdoptRecords]) using the routeros settings within [Opts].
+// Returns the adopted (or, in dry-run mode, adoptable) records.
+func Adopt(o *Opts, ao *AdoptOpts) ([]*endpoint.Endpoint, error) {
+	l := o.Logger
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if len(ao.Names) == 0 && !ao.AllMatchingFilter {
+		return []*endpoint.Endpoint{}, fmt.Errorf("no records selected for adoption")
+	}
+	pc, err := NewRoutedClient(&ClientOpts{
+		Address:         o.RouterOSAddress,
+		Backend:         o.Backend,
+		CertFingerprint: o.RouterOSCertFingerprint,
+		FallbackIP:      o.RouterOSFallbackIP,
+		Logger:          l.With("name", "client"),
+		Menu:            o.RouterOSMenu,
+		MetadataStore:   o.MetadataStore,
+		Password:        o.RouterOSPassword,
+		Resolver:        o.RouterOSResolver,
+		Trace:           o.TraceRouterOS,
+		Username:        o.RouterOSUsername,
+	}, o.Routes)
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	ns := []string{}
+	for _, n := range ao.Names {
+		ns = append(ns, normalizeDnsName(n))
+	}
+	df := o.domainFilter()
+	c := pc
+	if ao.DryRun {
+		c = pc.WithDryRun(func(cmd []string) {})
+	}
+	return c.AdoptRecords(func(n string) bool {
+		if slices.Contains(ns, n) {
+			return true
+		}
+		return ao.AllMatchingFilter && df.Match(n)
+	})
+}
+
+// Options used when migrating records via [Migrate]
+type MigrateOpts struct {
+	// Report the records that would be migrated without migrating them
+	DryRun bool
+}
+
+// Upgrades managed records written by older provider versions in place (see [client.MigrateRecords]) using the routeros settings within [Opts].
+// Returns the migrated (or, in dry-run mode, migratable) records.
+func Migrate(o *Opts, mo *MigrateOpts) ([]MigratedRecord, error) {
+	l := o.Logger
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	pc, err := NewRoutedClient(&ClientOpts{
+		Address:         o.RouterOSAddress,
+		Backend:         o.Backend,
+		CertFingerprint: o.RouterOSCertFingerprint,
+		FallbackIP:      o.RouterOSFallbackIP,
+		Logger:          l.With("name", "client"),
+		Menu:            o.RouterOSMenu,
+		MetadataStore:   o.MetadataStore,
+		Password:        o.RouterOSPassword,
+		Resolver:        o.RouterOSResolver,
+		Trace:           o.TraceRouterOS,
+		Username:        o.RouterOSUsername,
+	}, o.Routes)
+	if err != nil {
+		return []MigratedRecord{}, err
+	}
+	c := pc
+	if mo.DryRun {
+		c = pc.WithDryRun(func(cmd []string) {})
+	}
+	return c.MigrateRecords()
+}
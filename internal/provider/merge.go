@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Merges (normalized) endpoints sharing a routeros record type and name - e.g., those declared by different resources or produced by rewrite rules (see [RewriteRule]).
+// As routeros stores the targets of both endpoints under the same key, applying them separately would thrash (each sync replacing the other's records).
+// The targets of later endpoints are appended to the first endpoint - whose ttl, labels and provider-specific properties are retained.
+// CNAME endpoints cannot hold multiple targets - later CNAME endpoints with differing targets are rejected instead.
+// Calls the callback with each merged or rejected endpoint and a message explaining why.
+func mergeEndpoints(es []*endpoint.Endpoint, cb func(e *endpoint.Endpoint, merged bool, m string)) []*endpoint.Endpoint {
+	mes := []*endpoint.Endpoint{}
+	ks := map[string]*endpoint.Endpoint{}
+	for _, e := range es {
+		k := fmt.Sprintf("%s::%s::%s", e.RecordType, e.DNSName, e.SetIdentifier)
+		fe, ok := ks[k]
+		if !ok {
+			ks[k] = e
+			mes = append(mes, e)
+			continue
+		}
+		fr := fe.Labels[endpoint.ResourceLabelKey]
+		ts := []string{}
+		for _, t := range e.Targets {
+			if !slices.Contains(fe.Targets, t) && !slices.Contains(ts, t) {
+				ts = append(ts, t)
+			}
+		}
+		if len(ts) == 0 {
+			if fe.RecordTTL != e.RecordTTL {
+				cb(e, true, fmt.Sprintf("duplicates endpoint %s %s (resource %s) with a different ttl - using ttl %d", fe.RecordType, fe.DNSName, fr, fe.RecordTTL))
+			}
+			continue
+		}
+		if e.RecordType == "CNAME" {
+			cb(e, false, fmt.Sprintf("conflicts with endpoint %s %s (resource %s) - CNAME records hold a single target (%s)", fe.RecordType, fe.DNSName, fr, fe.Targets))
+			continue
+		}
+		fe.Targets = append(fe.Targets, ts...)
+		cb(e, true, fmt.Sprintf("merged targets %s into endpoint %s %s (resource %s) sharing its name", ts, fe.RecordType, fe.DNSName, fr))
+	}
+	return mes
+}
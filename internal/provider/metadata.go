@@ -0,0 +1,320 @@
+package provider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"unicode/utf16"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Metadata stored as a comment within a routeros dns record
+type recordMetadata struct {
+	// User-provided comment of the [endpoint.Endpoint] that produced the record (see [providerSpecificComment])
+	Comment string `json:"comment,omitempty"`
+	// Labels of the [endpoint.Endpoint] that produced the record (e.g., 'resource')
+	Labels map[string]string `json:"labels,omitempty"`
+	// The route (see [providerSpecificRoute]) selected by the [endpoint.Endpoint] that produced the record
+	Route string `json:"route,omitempty"`
+	// Record type of the [endpoint.Endpoint] that produced the record - when it differs from that of the record (see [getAddressRecordType])
+	Type string `json:"type,omitempty"`
+	// Set when the record holds part of a TXT value split across records (see [client.createTextParts])
+	Part *recordPart `json:"part,omitempty"`
+	// Hash of the record's content when written by the provider (see [client.getRecordHash])
+	Hash string `json:"hash,omitempty"`
+	// Set when the [endpoint.Endpoint] that produced the record lacked a ttl - and the record was created with [defaultRecordTtl]
+	DefaultTtl bool `json:"defaultTtl,omitempty"`
+}
+
+// Identifies part of a TXT value split across multiple routeros records
+type recordPart struct {
+	// Identifies the split value - shared by all of its parts
+	Id string `json:"id"`
+	// The position of this part within the split value
+	Index int `json:"index"`
+	// The total number of parts the value was split into
+	Count int `json:"count"`
+}
+
+// Returns a copy of the [recordMetadata] holding only the fields required to identify a record as managed by the provider (and the router it belongs to).
+// The user-provided comment is retained - as it is listed with the endpoint, dropping it would cause perpetual updates (see [validateComment]).
+// Used when the full metadata does not fit within [recordCommentMaxLength].
+func (rm recordMetadata) essential() recordMetadata {
+	return recordMetadata{Comment: rm.Comment, DefaultTtl: rm.DefaultTtl, Part: rm.Part, Route: rm.Route, Type: rm.Type}
+}
+
+// When a routeros dns record is missing metadata via structured data stored in its comment,
+// it's because either a) the record is not managed by external-dns or b) the record is invalid.
+// If the record is invalid, the provider should attempt to clean it up.
+// If the record is not managed by external-dns, it should be ignored.
+// This is a specialized error type that helps disambiguate between these two cases.
+// Matches [ErrNotManaged] (via [errors.Is]).
+type NotExternalDnsRecordError struct {
+	Id string
+}
+
+func (e NotExternalDnsRecordError) Error() string {
+	return fmt.Sprintf("dns record %s not managed by external-dns", e.Id)
+}
+
+func (e NotExternalDnsRecordError) Is(target error) bool {
+	return target == ErrNotManaged
+}
+
+// Matches errors describing routeros dns records not managed by external-dns (see [NotExternalDnsRecordError])
+var ErrNotManaged = errors.New("dns record not managed by external-dns")
+
+// Record metadata is stored in the comment of each routeros dns record
+const MetadataStoreComment = "comment"
+
+// Record metadata is stored in a companion TXT record (per record type and name) - leaving comments to operators.
+// See [client.makeMetadataRecordName].
+const MetadataStoreTxt = "txt"
+
+// Companion TXT records holding record metadata have names starting with this prefix
+var metadataRecordNamePrefix = "_external-dns-routeros."
+
+// Produces the name of the companion TXT record holding metadata for records of the given type and name.
+func (c *client) makeMetadataRecordName(rt string, n string) string {
+	return fmt.Sprintf("%s%s.%s", metadataRecordNamePrefix, strings.ToLower(rt), n)
+}
+
+// Determines whether the given record is a companion TXT record holding metadata.
+// If so, returns the key (see [client.makeKey]) of the records the companion record describes.
+func (c *client) parseMetadataRecordName(r dnsRecord) (string, bool) {
+	if r.Type != "TXT" {
+		return "", false
+	}
+	v, ok := strings.CutPrefix(r.Name, metadataRecordNamePrefix)
+	if !ok {
+		return "", false
+	}
+	rt, n, ok := strings.Cut(v, ".")
+	if !ok {
+		return "", false
+	}
+	return c.makeKey(strings.ToUpper(rt), n), true
+}
+
+// If a routeros dns record comment starts with this prefix, its managed by the provider.
+var recordMetadataPrefix = "external-dns:"
+
+// The maximum length of a routeros dns record comment written by the provider
+var recordCommentMaxLength = 255
+
+// Returned when encoded [recordMetadata] does not fit within a routeros dns record comment
+type RecordCommentTooLongError struct {
+	Length int
+}
+
+func (e RecordCommentTooLongError) Error() string {
+	return fmt.Sprintf("record metadata comment length %d exceeds maximum %d", e.Length, recordCommentMaxLength)
+}
+
+// The maximum length of a user-provided comment (see [providerSpecificComment]) once encoded as json (see [escapeJsonNonAscii]).
+// Leaves room within [recordCommentMaxLength] for the remaining essential metadata (see [recordMetadata.essential]).
+var userCommentMaxLength = 128
+
+// Returned when a user-provided comment (see [providerSpecificComment]) exceeds [userCommentMaxLength]
+type CommentTooLongError struct {
+	Length int
+}
+
+func (e CommentTooLongError) Error() string {
+	return fmt.Sprintf("comment length %d exceeds maximum %d", e.Length, userCommentMaxLength)
+}
+
+// Checks that a user-provided comment (see [providerSpecificComment]) fits within record metadata.
+// Returns a [CommentTooLongError] if the comment, encoded as json, exceeds [userCommentMaxLength].
+func validateComment(uc string) error {
+	b, err := json.Marshal(uc)
+	if err != nil {
+		return err
+	}
+	l := len(escapeJsonNonAscii(string(b)))
+	if l > userCommentMaxLength {
+		return CommentTooLongError{Length: l}
+	}
+	return nil
+}
+
+// Encoded metadata may be preceded by the source resource of the record enclosed by these delimiters (e.g., '[ingress/default/web]').
+// Unlike the remaining (possibly gzipped) metadata, the resource is readable within router-side comments.
+var recordResourceStart, recordResourceEnd = "[", "]"
+
+// Determines whether the given source resource can be stored as readable text ahead of encoded metadata
+func isReadableResource(r string) bool {
+	if r == "" || strings.Contains(r, recordResourceEnd) {
+		return false
+	}
+	for _, c := range r {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Metadata following this (versioned) prefix is gzipped json, base64 encoded.
+// Metadata without a version prefix is plain json (the original encoding).
+var recordMetadataGzipPrefix = "v2:"
+
+// Encodes [recordMetadata] into a routeros dns record comment.
+// If the comment exceeds [recordCommentMaxLength], non-essential metadata is dropped (see [recordMetadata.essential]) - retaining the source resource if possible.
+// Returns a [RecordCommentTooLongError] if the comment still exceeds [recordCommentMaxLength].
+// Returns an error if encoding fails.
+func (c *client) encodeRecordMetadata(rm recordMetadata) (string, error) {
+	v, err := c.encodeRecordMetadataValue(rm)
+	if err != nil {
+		return "", err
+	}
+	if len(v) <= recordCommentMaxLength {
+		return v, nil
+	}
+	c.logger.Warn(fmt.Sprintf("record metadata comment length %d exceeds maximum %d - dropping non-essential metadata", len(v), recordCommentMaxLength))
+	erm := rm.essential()
+	r, ok := rm.Labels[endpoint.ResourceLabelKey]
+	if ok {
+		erm.Labels = map[string]string{endpoint.ResourceLabelKey: r}
+		v, err = c.encodeRecordMetadataValue(erm)
+		if err != nil {
+			return "", err
+		}
+		if len(v) <= recordCommentMaxLength {
+			return v, nil
+		}
+		erm.Labels = nil
+	}
+	v, err = c.encodeRecordMetadataValue(erm)
+	if err != nil {
+		return "", err
+	}
+	if len(v) > recordCommentMaxLength {
+		return "", RecordCommentTooLongError{Length: len(v)}
+	}
+	return v, nil
+}
+
+// Encodes [recordMetadata] into a routeros dns record comment - without enforcing length limits.
+// RouterOS comments are length-limited - the shorter of plain json and gzipped json is used.
+// Plain json is ascii-only (see [escapeJsonNonAscii]).
+// The source resource (see [endpoint.ResourceLabelKey]) is moved ahead of the metadata (see [recordResourceStart]).
+// Returns an error if encoding fails.
+func (c *client) encodeRecordMetadataValue(rm recordMetadata) (string, error) {
+	rs := ""
+	r := rm.Labels[endpoint.ResourceLabelKey]
+	if isReadableResource(r) {
+		rs = recordResourceStart + r + recordResourceEnd
+		rm.Labels = maps.Clone(rm.Labels)
+		delete(rm.Labels, endpoint.ResourceLabelKey)
+		if len(rm.Labels) == 0 {
+			rm.Labels = nil
+		}
+	}
+	rmb, err := json.Marshal(rm)
+	if err != nil {
+		return "", err
+	}
+	v := escapeJsonNonAscii(string(rmb))
+
+	b := bytes.Buffer{}
+	w := gzip.NewWriter(&b)
+	_, err = w.Write(rmb)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	gv := recordMetadataGzipPrefix + base64.RawURLEncoding.EncodeToString(b.Bytes())
+	if len(gv) < len(v) {
+		v = gv
+	}
+
+	return recordMetadataPrefix + rs + v, nil
+}
+
+// Escapes non-ascii characters within encoded json as '\uXXXX' sequences.
+// RouterOS tools (e.g., winbox, the terminal) treat comments as single-byte strings - re-saving a comment holding multi-byte characters would corrupt it.
+func escapeJsonNonAscii(v string) string {
+	sb := strings.Builder{}
+	for _, r := range v {
+		if r < 0x80 {
+			sb.WriteRune(r)
+			continue
+		}
+		r1, r2 := utf16.EncodeRune(r)
+		if r1 == '\uFFFD' {
+			fmt.Fprintf(&sb, "\\u%04x", r)
+			continue
+		}
+		fmt.Fprintf(&sb, "\\u%04x\\u%04x", r1, r2)
+	}
+	return sb.String()
+}
+
+// Decodes [recordMetadata] from the (prefix-stripped) value of a routeros dns record comment.
+// Handles both plain json and gzipped json encodings - optionally preceded by the source resource (see [recordResourceStart]).
+// Returns an error if the value is not parseable.
+func (c *client) decodeRecordMetadata(v string) (recordMetadata, error) {
+	r := ""
+	rv, ok := strings.CutPrefix(v, recordResourceStart)
+	if ok {
+		r, v, ok = strings.Cut(rv, recordResourceEnd)
+		if !ok {
+			return recordMetadata{}, fmt.Errorf("source resource unterminated")
+		}
+	}
+	rmb := []byte(v)
+	gv, ok := strings.CutPrefix(v, recordMetadataGzipPrefix)
+	if ok {
+		gb, err := base64.RawURLEncoding.DecodeString(gv)
+		if err != nil {
+			return recordMetadata{}, err
+		}
+		r, err := gzip.NewReader(bytes.NewReader(gb))
+		if err != nil {
+			return recordMetadata{}, err
+		}
+		rmb, err = io.ReadAll(r)
+		if err != nil {
+			return recordMetadata{}, err
+		}
+	}
+	rm := recordMetadata{}
+	err := json.Unmarshal(rmb, &rm)
+	if err != nil {
+		return recordMetadata{}, err
+	}
+	if r != "" {
+		if rm.Labels == nil {
+			rm.Labels = map[string]string{}
+		}
+		rm.Labels[endpoint.ResourceLabelKey] = r
+	}
+	return rm, nil
+}
+
+// Retrieves metadata for a routeros dns record from the given value.
+// Depending on the [client]'s metadata store, this value is either the record's comment or the text of the record's companion TXT record.
+func (c *client) getRecordMetadata(id string, v string) (recordMetadata, error) {
+	rms, ok := strings.CutPrefix(v, recordMetadataPrefix)
+	if !ok {
+		// value does not start with prefix - is not managed by external-dns
+		return recordMetadata{}, NotExternalDnsRecordError{Id: id}
+	}
+	rm, err := c.decodeRecordMetadata(rms)
+	if err != nil {
+		// record is managed by external-dns, but metadata is not parseable
+		return recordMetadata{}, err
+	}
+	return rm, nil
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestValidateComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		err     bool
+	}{
+		{name: "empty", comment: ""},
+		{name: "short", comment: "web frontend"},
+		{name: "maximum", comment: strings.Repeat("x", userCommentMaxLength-2)},
+		{name: "too long", comment: strings.Repeat("x", userCommentMaxLength-1), err: true},
+		{name: "escaped", comment: strings.Repeat(`"`, userCommentMaxLength/2), err: true},
+		{name: "non-ascii", comment: strings.Repeat("é", userCommentMaxLength/6+1), err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateComment(test.comment)
+			if test.err != (err != nil) {
+				t.Errorf("validateComment(%q) = %v, expected error %t", test.comment, err, test.err)
+			}
+		})
+	}
+}
+
+func TestEncodeRecordMetadataRetainsComment(t *testing.T) {
+	c := &client{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	uc := strings.Repeat("c", userCommentMaxLength-2)
+	ls := map[string]string{endpoint.ResourceLabelKey: "ingress/default/" + strings.Repeat("r", 200)}
+	for i := 0; i < 10; i++ {
+		ls[strings.Repeat("k", i+1)] = strings.Repeat("v", 50)
+	}
+	rm := recordMetadata{Comment: uc, DefaultTtl: true, Labels: ls, Route: "default", Type: "AAAA"}
+	v, err := c.encodeRecordMetadata(rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) > recordCommentMaxLength {
+		t.Fatalf("comment length %d exceeds maximum %d", len(v), recordCommentMaxLength)
+	}
+	drm, err := c.getRecordMetadata("*1", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drm.Comment != uc {
+		t.Errorf("decoded comment %q, expected %q", drm.Comment, uc)
+	}
+}
@@ -0,0 +1,327 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefixes the names of exported metrics
+var metricNamePrefix = "external_dns_routeros_"
+
+// A point-in-time measurement reported by the provider - see [provider.Metrics]
+type Metric struct {
+	// Whether the value only increases over the lifetime of the process (otherwise, the value is a gauge)
+	Counter bool
+	Name    string
+	Tags    map[string]string
+	Value   float64
+}
+
+// Returns the provider's current metrics - counters tracked by the provider (e.g., [provider.ProtectedCount]), the outcome of the most recent sync (see [provider.Status]) and health check (see [provider.HealthStatus]) and the load imposed on each router (see [provider.Stats])
+func (p *provider) Metrics() []Metric {
+	ms := []Metric{
+		{Counter: true, Name: "protected_changes_total", Value: float64(p.ProtectedCount())},
+		{Counter: true, Name: "filtered_endpoints_total", Value: float64(p.FilteredCount())},
+		{Name: "filtered_endpoints", Value: float64(len(p.FilteredNames()))},
+		{Counter: true, Name: "modified_records_total", Value: float64(p.ModifiedCount())},
+		{Counter: true, Name: "ttl_high_records_total", Value: float64(p.TtlHighCount())},
+		{Counter: true, Name: "ttl_low_records_total", Value: float64(p.TtlLowCount())},
+		{Counter: true, Name: "unverified_records_total", Value: float64(p.UnverifiedCount())},
+	}
+	as := p.Status()
+	if !as.Time.IsZero() {
+		d, _ := time.ParseDuration(as.Duration)
+		ms = append(ms,
+			Metric{Name: "last_sync_created", Value: float64(as.Created)},
+			Metric{Name: "last_sync_deleted", Value: float64(as.Deleted)},
+			Metric{Name: "last_sync_duration_seconds", Value: d.Seconds()},
+			Metric{Name: "last_sync_failed", Value: float64(as.Failed)},
+			Metric{Name: "last_sync_timestamp_seconds", Value: float64(as.Time.Unix())},
+			Metric{Name: "last_sync_updated", Value: float64(as.Updated)},
+		)
+	}
+	hs := p.HealthStatus()
+	if !hs.Time.IsZero() {
+		up := 1.0
+		if hs.Error != "" {
+			up = 0
+		}
+		ms = append(ms,
+			Metric{Name: "health_check_timestamp_seconds", Value: float64(hs.Time.Unix())},
+			Metric{Name: "health_check_up", Value: up},
+		)
+		hrs := map[string]HealthReport{"default": hs.Report}
+		rns := []string{"default"}
+		for rn, rhr := range hs.Report.Routes {
+			hrs[rn] = rhr
+			rns = append(rns, rn)
+		}
+		slices.Sort(rns)
+		for _, rn := range rns {
+			hr := hrs[rn]
+			if hr.Latency == 0 {
+				// the router's check failed
+				continue
+			}
+			ts := map[string]string{"route": rn, "router_version": hr.Version}
+			ms = append(ms, Metric{Name: "health_check_latency_seconds", Tags: ts, Value: hr.Latency.Seconds()})
+		}
+	}
+	for _, cs := range p.Stats() {
+		ts := map[string]string{"router": cs.Address}
+		if cs.Identity != "" {
+			ts["router_identity"] = cs.Identity
+		}
+		al, _ := time.ParseDuration(cs.AverageLatency)
+		ms = append(ms,
+			Metric{Name: "routeros_average_latency_seconds", Tags: ts, Value: al.Seconds()},
+			Metric{Counter: true, Name: "routeros_bytes_received_total", Tags: ts, Value: float64(cs.BytesReceived)},
+			Metric{Counter: true, Name: "routeros_bytes_sent_total", Tags: ts, Value: float64(cs.BytesSent)},
+			Metric{Counter: true, Name: "routeros_commands_total", Tags: ts, Value: float64(cs.Commands)},
+			Metric{Counter: true, Name: "routeros_connections_total", Tags: ts, Value: float64(cs.Connections)},
+		)
+	}
+	return ms
+}
+
+// Returns the keys of the given tags in sorted order - ensuring exported tags are consistently ordered
+func sortedTagKeys(ts map[string]string) []string {
+	ks := []string{}
+	for k := range ts {
+		ks = append(ks, k)
+	}
+	slices.Sort(ks)
+	return ks
+}
+
+// Formats metrics using the Prometheus text exposition format - served by [server.metrics].
+// Metrics sharing a name are grouped beneath a single '# TYPE' line (ordered by their first occurrence).
+func formatPrometheus(ms []Metric) []byte {
+	ns := []string{}
+	byName := map[string][]Metric{}
+	for _, m := range ms {
+		_, ok := byName[m.Name]
+		if !ok {
+			ns = append(ns, m.Name)
+		}
+		byName[m.Name] = append(byName[m.Name], m)
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	b := bytes.Buffer{}
+	for _, n := range ns {
+		fn := metricNamePrefix + n
+		t := "gauge"
+		if byName[n][0].Counter {
+			t = "counter"
+		}
+		fmt.Fprintf(&b, "# TYPE %s %s\n", fn, t)
+		for _, m := range byName[n] {
+			ls := []string{}
+			for _, k := range sortedTagKeys(m.Tags) {
+				ls = append(ls, fmt.Sprintf(`%s="%s"`, k, r.Replace(m.Tags[k])))
+			}
+			l := ""
+			if len(ls) > 0 {
+				l = "{" + strings.Join(ls, ",") + "}"
+			}
+			fmt.Fprintf(&b, "%s%s %s\n", fn, l, strconv.FormatFloat(m.Value, 'f', -1, 64))
+		}
+	}
+	return b.Bytes()
+}
+
+// Sends metrics to a monitoring system - see [MetricsOpts]
+type metricsExporter interface {
+	Export(ms []Metric) error
+}
+
+// Options controlling the export of metrics (see [provider.RunMetricsExport])
+type MetricsOpts struct {
+	// The interval between exports - defaults to 15s
+	Interval time.Duration
+	// The OTLP/HTTP endpoint (e.g., 'http://otel-collector:4318') metrics are exported to - disabled when empty
+	OtlpEndpoint string
+	// The proxy (e.g., 'http://proxy:3128') OTLP requests are sent via - defaults to the proxy configured within the environment (HTTP_PROXY, HTTPS_PROXY, NO_PROXY)
+	OtlpProxy string
+	// The statsd server ('<host>:<port>') metrics are exported to - disabled when empty
+	StatsdAddress string
+}
+
+// Creates the [metricsExporter] list configured within the [MetricsOpts].
+// Returns an error if an exporter's options are invalid.
+func (o MetricsOpts) exporters() ([]metricsExporter, error) {
+	mes := []metricsExporter{}
+	if o.OtlpEndpoint != "" {
+		oe, err := newOtlpExporter(o.OtlpEndpoint, o.OtlpProxy)
+		if err != nil {
+			return []metricsExporter{}, err
+		}
+		mes = append(mes, oe)
+	}
+	if o.StatsdAddress != "" {
+		mes = append(mes, newStatsdExporter(o.StatsdAddress))
+	}
+	return mes, nil
+}
+
+// Periodically exports the provider's metrics (see [provider.Metrics]) until the process exits.
+// Failing exports are logged.
+// Does nothing if no exporters are configured (see [MetricsOpts]).
+func (p *provider) RunMetricsExport(o MetricsOpts, l *slog.Logger) {
+	mes, err := o.exporters()
+	if err != nil {
+		l.Error(fmt.Sprintf("failed to create metrics exporters: %s", err.Error()))
+		return
+	}
+	if len(mes) == 0 {
+		return
+	}
+	i := o.Interval
+	if i == 0 {
+		i = 15 * time.Second
+	}
+	t := time.NewTicker(i)
+	defer t.Stop()
+	for range t.C {
+		ms := p.Metrics()
+		for _, me := range mes {
+			err := me.Export(ms)
+			if err != nil {
+				l.Warn(fmt.Sprintf("failed to export metrics: %s", err.Error()))
+			}
+		}
+	}
+}
+
+// Exports metrics to a statsd server (e.g., telegraf's statsd input) over udp.
+// Tags are encoded within metric names (e.g., 'name,router=192.168.88.1_8728:1|g') as understood by telegraf.
+// As statsd counters are incremental, counters are sent as the increase since the previous export.
+type statsdExporter struct {
+	address string
+	last    map[string]float64
+	mutex   sync.Mutex
+}
+
+// Creates a new [statsdExporter] sending metrics to the given address
+func newStatsdExporter(a string) *statsdExporter {
+	return &statsdExporter{address: a, last: map[string]float64{}}
+}
+
+// Formats a metric's name (including its tags) for statsd
+func (se *statsdExporter) formatName(m Metric) string {
+	n := metricNamePrefix + m.Name
+	r := strings.NewReplacer(",", "_", "=", "_", ":", "_", "|", "_", " ", "_")
+	for _, k := range sortedTagKeys(m.Tags) {
+		n += fmt.Sprintf(",%s=%s", k, r.Replace(m.Tags[k]))
+	}
+	return n
+}
+
+// Sends the metrics - one datagram per metric
+func (se *statsdExporter) Export(ms []Metric) error {
+	se.mutex.Lock()
+	defer se.mutex.Unlock()
+	conn, err := net.Dial("udp", se.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	errs := []error{}
+	for _, m := range ms {
+		n := se.formatName(m)
+		v := m.Value
+		t := "g"
+		if m.Counter {
+			t = "c"
+			v = m.Value - se.last[n]
+			se.last[n] = m.Value
+		}
+		_, err := fmt.Fprintf(conn, "%s:%s|%s", n, strconv.FormatFloat(v, 'f', -1, 64), t)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Exports metrics to an OpenTelemetry collector via OTLP/HTTP (using the json encoding).
+// Counters are exported as cumulative monotonic sums - all other metrics as gauges.
+type otlpExporter struct {
+	client   *http.Client
+	endpoint string
+	start    time.Time
+}
+
+// Creates a new [otlpExporter] sending metrics to the given endpoint.
+// Requests are sent via the given proxy url - or, when empty, via the proxy configured within the environment (HTTP_PROXY, HTTPS_PROXY, NO_PROXY).
+// Returns an error if the proxy isn't an http(s) url.
+func newOtlpExporter(e string, pr string) (*otlpExporter, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if pr != "" {
+		u, err := url.Parse(pr)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("otlp proxy %s not an http(s) url", pr)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	return &otlpExporter{
+		client:   &http.Client{Timeout: 10 * time.Second, Transport: t},
+		endpoint: strings.TrimSuffix(e, "/") + "/v1/metrics",
+		start:    time.Now(),
+	}, nil
+}
+
+// Sends the metrics as a single OTLP export request
+func (oe *otlpExporter) Export(ms []Metric) error {
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	st := strconv.FormatInt(oe.start.UnixNano(), 10)
+	attrs := func(ts map[string]string) []any {
+		as := []any{}
+		for _, k := range sortedTagKeys(ts) {
+			as = append(as, map[string]any{"key": k, "value": map[string]any{"stringValue": ts[k]}})
+		}
+		return as
+	}
+	oms := []any{}
+	for _, m := range ms {
+		dp := map[string]any{"asDouble": m.Value, "attributes": attrs(m.Tags), "timeUnixNano": now}
+		om := map[string]any{"name": metricNamePrefix + m.Name}
+		if m.Counter {
+			dp["startTimeUnixNano"] = st
+			// aggregation temporality 2 is cumulative
+			om["sum"] = map[string]any{"aggregationTemporality": 2, "dataPoints": []any{dp}, "isMonotonic": true}
+		} else {
+			om["gauge"] = map[string]any{"dataPoints": []any{dp}}
+		}
+		oms = append(oms, om)
+	}
+	d, err := json.Marshal(map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource":     map[string]any{"attributes": attrs(map[string]string{"service.name": "external-dns-routeros-provider"})},
+			"scopeMetrics": []any{map[string]any{"metrics": oms, "scope": map[string]any{"name": "external-dns-routeros-provider"}}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	rsp, err := oe.client.Post(oe.endpoint, "application/json", bytes.NewReader(d))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp endpoint %s responded with status %d", oe.endpoint, rsp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"testing"
+)
+
+func TestFormatPrometheus(t *testing.T) {
+	ms := []Metric{
+		{Counter: true, Name: "routeros_commands_total", Tags: map[string]string{"router": "192.168.88.1:8728"}, Value: 3},
+		{Name: "filtered_endpoints", Value: 0.5},
+		{Counter: true, Name: "routeros_commands_total", Tags: map[string]string{"router_identity": `a "b"\c` + "\n", "router": "192.168.88.2:8728"}, Value: 1},
+	}
+	expected := `# TYPE external_dns_routeros_routeros_commands_total counter
+external_dns_routeros_routeros_commands_total{router="192.168.88.1:8728"} 3
+external_dns_routeros_routeros_commands_total{router="192.168.88.2:8728",router_identity="a \"b\"\\c\n"} 1
+# TYPE external_dns_routeros_filtered_endpoints gauge
+external_dns_routeros_filtered_endpoints 0.5
+`
+	actual := string(formatPrometheus(ms))
+	if actual != expected {
+		t.Errorf("formatPrometheus = %q, expected %q", actual, expected)
+	}
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// A managed routeros record being upgraded by [client.MigrateRecords]
+type migratingRecord struct {
+	// Set when the record's metadata could not be parsed
+	malformed bool
+	metadata  recordMetadata
+	record    dnsRecord
+}
+
+// A migration upgrading managed routeros records written by older provider versions in place
+type recordMigration struct {
+	desc string
+	// Modifies the record - returning whether the migration applied
+	migrate func(mr *migratingRecord) bool
+	// Identifies the migration - migrations are applied in order
+	version int
+}
+
+// Known migrations - ordered by version.
+// Migrations must be idempotent - they're attempted on every record whenever migrations run.
+var recordMigrations = []recordMigration{
+	{
+		desc: "replace unparseable metadata",
+		migrate: func(mr *migratingRecord) bool {
+			if !mr.malformed {
+				return false
+			}
+			mr.malformed = false
+			mr.metadata = recordMetadata{}
+			return true
+		},
+		version: 1,
+	},
+	{
+		desc: "apply default ttl",
+		migrate: func(mr *migratingRecord) bool {
+			ttl, err := time.ParseDuration(mr.record.Ttl)
+			if mr.record.Ttl != "" && (err != nil || ttl != 0) {
+				return false
+			}
+			mr.record.Ttl = defaultRecordTtl.String()
+			mr.metadata.DefaultTtl = true
+			return true
+		},
+		version: 2,
+	},
+	{
+		desc: "add content hash",
+		migrate: func(mr *migratingRecord) bool {
+			// the hash is computed once all migrations are applied (see [client.MigrateRecords])
+			return mr.metadata.Hash == ""
+		},
+		version: 3,
+	},
+	{
+		desc: "show source resource",
+		migrate: func(mr *migratingRecord) bool {
+			// the resource is moved ahead of the metadata when the comment is rewritten (see [client.encodeRecordMetadataValue])
+			return isReadableResource(mr.metadata.Labels[endpoint.ResourceLabelKey]) && !strings.HasPrefix(mr.record.Comment, recordMetadataPrefix+recordResourceStart)
+		},
+		version: 4,
+	},
+}
+
+// A record upgraded by [client.MigrateRecords]
+type MigratedRecord struct {
+	DNSName    string   `json:"dnsName"`
+	Id         string   `json:"id"`
+	Migrations []string `json:"migrations"`
+	RecordType string   `json:"recordType"`
+}
+
+// Upgrades managed records written by older provider versions in place (see [recordMigrations]) - rather than deleting them as malformed.
+// Records whose comments start with the metadata prefix are migrated - and their metadata (including its hash) rewritten.
+// Only supported with the comment metadata store - does nothing otherwise.
+// Use [client.WithDryRun] to report the records that would be migrated without migrating them.
+// Returns the migrated records.
+func (c *client) MigrateRecords() ([]MigratedRecord, error) {
+	if c.metadataStore != MetadataStoreComment {
+		return []MigratedRecord{}, nil
+	}
+	mrs := []MigratedRecord{}
+	err := c.withClient(func() error {
+		rs, err := c.backend.List(c.run)
+		if err != nil {
+			return err
+		}
+		for _, r := range rs {
+			rms, ok := strings.CutPrefix(r.Comment, recordMetadataPrefix)
+			if !ok {
+				continue
+			}
+			mr := migratingRecord{record: r}
+			mr.metadata, err = c.decodeRecordMetadata(rms)
+			mr.malformed = err != nil
+			ms := []string{}
+			for _, m := range recordMigrations {
+				if m.migrate(&mr) {
+					ms = append(ms, fmt.Sprintf("%d: %s", m.version, m.desc))
+				}
+			}
+			if len(ms) == 0 {
+				continue
+			}
+			v := mr.record.attributes()
+			err = c.setRecordComment(v, mr.metadata)
+			if err != nil {
+				return fmt.Errorf("record %s: %w", r.Id, err)
+			}
+			if v["comment"] == r.Comment && v["ttl"] == r.Ttl {
+				// e.g., the hash of records holding only essential metadata (see [recordMetadata.essential]) cannot be added
+				continue
+			}
+			c.logger.Info(fmt.Sprintf("migrating record %s %s (%s): %s", r.Type, r.Name, r.Id, strings.Join(ms, ", ")))
+			err = c.backend.Update(c.run, r.Id, map[string]string{"comment": v["comment"], "ttl": v["ttl"]})
+			if err != nil {
+				return fmt.Errorf("record %s: %w", r.Id, err)
+			}
+			mrs = append(mrs, MigratedRecord{DNSName: r.Name, Id: r.Id, Migrations: ms, RecordType: r.Type})
+		}
+		return nil
+	})
+	if err != nil {
+		return mrs, err
+	}
+	return mrs, nil
+}
+
+// Migrates the records of every router (see [client.MigrateRecords])
+func (rc *routedClient) MigrateRecords() ([]MigratedRecord, error) {
+	mrs := []MigratedRecord{}
+	for i, c := range rc.clients {
+		cmrs, err := c.MigrateRecords()
+		mrs = append(mrs, cmrs...)
+		if err != nil {
+			return mrs, fmt.Errorf("route %s: %w", rc.names[i], err)
+		}
+	}
+	return mrs, nil
+}
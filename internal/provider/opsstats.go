@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Counts the load a [client] imposes on its routeros device over the lifetime of the process.
+// Shared by copies of the client (e.g., those created per webhook request) - and safe for concurrent use.
+// Unlike [CommandStats], stats are not scoped to a webhook request.
+type OpsStats struct {
+	bytesReceived atomic.Uint64
+	bytesSent     atomic.Uint64
+	commands      atomic.Uint64
+	connections   atomic.Uint64
+	latency       atomic.Int64
+}
+
+// Records an executed command and its round-trip duration
+func (ops *OpsStats) add(d time.Duration) {
+	ops.commands.Add(1)
+	ops.latency.Add(int64(d))
+}
+
+// A point-in-time copy of a [client]'s [OpsStats] - see [Client.Stats]
+type ClientStats struct {
+	// The address of the routeros device
+	Address string `json:"address"`
+	// The average round-trip duration of executed commands
+	AverageLatency string `json:"averageLatency"`
+	BytesReceived  uint64 `json:"bytesReceived"`
+	BytesSent      uint64 `json:"bytesSent"`
+	// The number of executed commands (see [client.run])
+	Commands uint64 `json:"commands"`
+	// The number of api connections opened (see [client.withClient])
+	Connections uint64 `json:"connections"`
+	// The identity of the routeros device (see [client.discoverIdentity]) - empty until discovered
+	Identity string `json:"identity"`
+}
+
+// Returns the load the [client] has imposed on its routeros device (see [OpsStats])
+func (c *client) Stats() []ClientStats {
+	cs := ClientStats{
+		Address:       c.address,
+		BytesReceived: c.opsStats.bytesReceived.Load(),
+		BytesSent:     c.opsStats.bytesSent.Load(),
+		Commands:      c.opsStats.commands.Load(),
+		Connections:   c.opsStats.connections.Load(),
+		Identity:      c.identity.get(),
+	}
+	var al time.Duration
+	if cs.Commands > 0 {
+		al = time.Duration(c.opsStats.latency.Load() / int64(cs.Commands))
+	}
+	cs.AverageLatency = al.String()
+	return []ClientStats{cs}
+}
+
+// Returns the load imposed on each router (see [client.Stats])
+func (rc *routedClient) Stats() []ClientStats {
+	css := []ClientStats{}
+	for _, c := range rc.clients {
+		css = append(css, c.Stats()...)
+	}
+	return css
+}
+
+// A connection counting the bytes sent and received within an [OpsStats]
+type opsStatsConn struct {
+	net.Conn
+	stats *OpsStats
+}
+
+// Reads from the connection - counting the bytes received
+func (oc *opsStatsConn) Read(b []byte) (int, error) {
+	n, err := oc.Conn.Read(b)
+	oc.stats.bytesReceived.Add(uint64(n))
+	return n, err
+}
+
+// Writes to the connection - counting the bytes sent
+func (oc *opsStatsConn) Write(b []byte) (int, error) {
+	n, err := oc.Conn.Write(b)
+	oc.stats.bytesSent.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"slices"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Returns the dns names (normalized - see [normalizeDnsName]) that the targets of the given endpoint refer to (e.g., the targets of CNAME and SRV records).
+// Creating an endpoint before the records its targets refer to can cause transient resolution failures - see [orderNames].
+func endpointDependencies(e *endpoint.Endpoint) []string {
+	rt, ok := recordTypes[e.RecordType]
+	if !ok || rt.dependency == nil {
+		return []string{}
+	}
+	ds := []string{}
+	for _, t := range e.Targets {
+		d := rt.dependency(t)
+		if d == "" {
+			continue
+		}
+		d = normalizeDnsName(d)
+		if !slices.Contains(ds, d) {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+// Orders dns names such that names are preceded by the names they depend upon (topological ordering) - otherwise preserving the given order.
+// The dependencies of each name are provided by the callback - dependencies outside of the given names are ignored.
+// Cyclic dependencies (e.g., CNAME records targeting one another) are broken in favor of the given order.
+func orderNames(ns []string, deps func(n string) []string) []string {
+	in := map[string]bool{}
+	for _, n := range ns {
+		in[n] = true
+	}
+	ons := []string{}
+	visited := map[string]bool{}
+	var visit func(n string)
+	visit = func(n string) {
+		if visited[n] {
+			return
+		}
+		visited[n] = true
+		for _, d := range deps(n) {
+			if in[d] {
+				visit(d)
+			}
+		}
+		ons = append(ons, n)
+	}
+	for _, n := range ns {
+		visit(n)
+	}
+	return ons
+}
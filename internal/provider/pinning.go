@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Parses a sha256 certificate fingerprint (e.g., as shown by '/certificate print detail') - ignoring case and ':' separators.
+// Returns an error if the fingerprint is not 32 hex-encoded bytes.
+func parseCertFingerprint(f string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(f, ":", ""))
+	if err != nil || len(b) != sha256.Size {
+		return nil, fmt.Errorf("cert fingerprint %s not a hex-encoded sha256 digest", f)
+	}
+	return b, nil
+}
+
+// Produces a [tls.Config] trusting only the routeros certificate whose sha256 fingerprint matches the given fingerprint.
+// As the certificate itself is pinned, self-signed certificates are accepted - the certificate chain and hostname are not verified.
+func newPinnedTlsConfig(fp []byte) *tls.Config {
+	return &tls.Config{
+		// verification is performed by VerifyPeerCertificate
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rcs [][]byte, _ [][]*x509.Certificate) error {
+			if len(rcs) == 0 {
+				return fmt.Errorf("routeros presented no certificate")
+			}
+			h := sha256.Sum256(rcs[0])
+			if !bytes.Equal(h[:], fp) {
+				return fmt.Errorf("routeros certificate fingerprint %s does not match pinned fingerprint %s", hex.EncodeToString(h[:]), hex.EncodeToString(fp))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Options controlling files written for exec-based probes (e.g., when running as an external-dns sidecar) - see [server.runProbeFiles]
+type ProbeFileOpts struct {
+	// Path to a file rewritten (with the current time) after each successful health check (see [Provider.Health]) - disabled when empty
+	HeartbeatFile string
+	// The interval between health checks - defaults to 30s
+	HeartbeatInterval time.Duration
+	// Path to a file created once the server accepts connections (and removed once it stops) - disabled when empty
+	ReadyFile string
+}
+
+// Writes the current time to the file at the given path
+func touchProbeFile(p string) error {
+	return os.WriteFile(p, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// Removes the file at the given path (if present)
+func removeProbeFile(p string) error {
+	err := os.Remove(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Writes the probe files configured within [ProbeFileOpts] once the server accepts connections.
+// The heartbeat file is rewritten after each successful health check until the returned function is called - which additionally removes the ready file.
+// A failing health check leaves the heartbeat file untouched - probes detect failures via the file's age.
+func (s *server) runProbeFiles() func() {
+	o := s.probeFiles
+	if o.ReadyFile != "" {
+		err := touchProbeFile(o.ReadyFile)
+		if err != nil {
+			s.logger.Warn(fmt.Sprintf("failed to write ready file %s: %s", o.ReadyFile, err.Error()))
+		}
+	}
+	done := make(chan struct{})
+	if o.HeartbeatFile != "" {
+		i := o.HeartbeatInterval
+		if i == 0 {
+			i = 30 * time.Second
+		}
+		go func() {
+			t := time.NewTicker(i)
+			defer t.Stop()
+			for {
+				_, err := s.provider.Health(context.Background())
+				if err == nil {
+					err = touchProbeFile(o.HeartbeatFile)
+				}
+				if err != nil {
+					s.logger.Warn(fmt.Sprintf("failed to write heartbeat file %s: %s", o.HeartbeatFile, err.Error()))
+				}
+				select {
+				case <-done:
+					return
+				case <-t.C:
+				}
+			}
+		}()
+	}
+	return func() {
+		close(done)
+		if o.ReadyFile != "" {
+			err := removeProbeFile(o.ReadyFile)
+			if err != nil {
+				s.logger.Warn(fmt.Sprintf("failed to remove ready file %s: %s", o.ReadyFile, err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-routeros/routeros/v3"
+)
+
+// How long logging into routeros (see [client.dial]) may take - services other than the routeros api (e.g., winbox) may never respond
+var loginTimeout = 15 * time.Second
+
+// Services commonly (mistakenly) configured as the routeros address - keyed by their default port
+var nonApiPorts = map[string]string{
+	"21":   "ftp",
+	"22":   "ssh",
+	"23":   "telnet",
+	"80":   "www",
+	"443":  "www-ssl",
+	"8291": "winbox",
+}
+
+// Returned when the routeros address (see [ClientOpts.Address]) doesn't serve the routeros api - e.g., when pointed at winbox or ssh
+type ProtocolMismatchError struct {
+	Address string
+	Reason  string
+	// The service typically listening on the address' port (empty if unknown)
+	Service string
+	Tls     bool
+}
+
+func (e ProtocolMismatchError) Error() string {
+	s := fmt.Sprintf("routeros address %s does not serve the routeros api (%s)", e.Address, e.Reason)
+	if e.Service != "" {
+		s = fmt.Sprintf("%s - the port is typically used by %s", s, e.Service)
+	}
+	if e.Tls {
+		return s + " - ensure the address uses the api-ssl port (default: 8729) and api-ssl is enabled (/ip service)"
+	}
+	return s + " - ensure the address uses the api port (default: 8728, or 8729 for api-ssl with --routeros-cert-fingerprint) and the api is enabled (/ip service)"
+}
+
+// Converts errors returned while logging into routeros that indicate the address serves a protocol other than the routeros api into a [ProtocolMismatchError].
+// A routeros api connection never closes, stalls or responds with garbage during login - such failures indicate another service (e.g., winbox, ssh) or a tls mismatch (e.g., api-ssl on the api port).
+// Errors reported by routeros (e.g., invalid credentials) are returned unchanged.
+func (c *client) wrapLoginError(err error) error {
+	de := &routeros.DeviceError{}
+	if errors.As(err, &de) {
+		return err
+	}
+	r := ""
+	ne := net.Error(nil)
+	rhe := tls.RecordHeaderError{}
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNRESET):
+		r = "connection closed during login"
+	case errors.As(err, &ne) && ne.Timeout():
+		r = fmt.Sprintf("no response to login within %s", loginTimeout)
+	case errors.As(err, &rhe):
+		r = "tls handshake rejected - the service doesn't use tls"
+	case strings.Contains(err.Error(), "invalid RouterOS sentence word"), strings.Contains(err.Error(), "unknown RouterOS reply word"):
+		r = "unrecognized response to login"
+	default:
+		return err
+	}
+	_, p, _ := net.SplitHostPort(c.address)
+	return ProtocolMismatchError{Address: c.address, Reason: r, Service: nonApiPorts[p], Tls: c.certFingerprint != nil}
+}
@@ -2,10 +2,19 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 	ednsprovider "sigs.k8s.io/external-dns/provider"
@@ -14,21 +23,132 @@ import (
 // Defines a provider interface - extending that defined by [ednsprovider.Provider]
 type Provider interface {
 	ednsprovider.Provider
-	Health() error
+	Capabilities() Capabilities
+	Health(c context.Context) (HealthReport, error)
+	HealthStatus() HealthStatus
+	Info() (RouterInfo, error)
+	Metrics() []Metric
+	ApplyChangesWithStatus(c context.Context, ch *plan.Changes) (ApplyStatus, error)
+	Resync(c context.Context) (ResyncResult, error)
+	Simulate(c context.Context, ch *plan.Changes) (SimulateResult, error)
+	Stats() []ClientStats
+	Status() ApplyStatus
 }
 
 // Internal configuration and state of a provider struct
 type provider struct {
-	client       Client
-	domainFilter endpoint.DomainFilter
-	logger       *slog.Logger
+	applyQueue        *applyQueue
+	applyStatus       ApplyStatus
+	batch             BatchOpts
+	client            Client
+	concurrency       uint
+	domainFilter      endpoint.DomainFilter
+	eventRecorder     EventRecorder
+	filteredCount     atomic.Uint64
+	filteredMutex     sync.Mutex
+	filteredNames     map[string]bool
+	healthMutex       sync.Mutex
+	healthStatus      HealthStatus
+	integrity         IntegrityOpts
+	intentLog         *intentLog
+	lock              LockOpts
+	lockOwner         string
+	logger            *slog.Logger
+	logSampleLimit    uint
+	modifiedCount     atomic.Uint64
+	namePolicy        string
+	protectedCount    atomic.Uint64
+	protectedNames    []string
+	protectedRegex    *regexp.Regexp
+	quotaLabel        string
+	quotaLabelMax     uint
+	quotaNamespace    uint
+	recordsCache      *recordsCache
+	recordsGroup      singleflight.Group
+	rewriteRules      []RewriteRule
+	rollbackOnFailure bool
+	serial            SerialOpts
+	settingsMutex     sync.RWMutex
+	statusMutex       sync.RWMutex
+	ttl               TtlOpts
+	ttlHighCount      atomic.Uint64
+	ttlLowCount       atomic.Uint64
+	unverifiedCount   atomic.Uint64
+	verifier          *verifier
 }
 
 // Options used when constructing a new provider
 type ProviderOpts struct {
-	DomainFilter endpoint.DomainFilter
-	Client       Client
-	Logger       *slog.Logger
+	ApplyDebounce     time.Duration
+	Batch             BatchOpts
+	DomainFilter      endpoint.DomainFilter
+	Client            Client
+	EventRecorder     EventRecorder
+	Concurrency       uint
+	Integrity         IntegrityOpts
+	IntentLog         string
+	Lock              LockOpts
+	Logger            *slog.Logger
+	LogSampleLimit    uint
+	NamePolicy        string
+	ProtectedNames    []string
+	ProtectedRegex    *regexp.Regexp
+	QuotaLabel        string
+	QuotaLabelMax     uint
+	QuotaNamespace    uint
+	RecordsCache      time.Duration
+	RewriteRules      []RewriteRule
+	RollbackOnFailure bool
+	Serial            SerialOpts
+	Ttl               TtlOpts
+	Verify            VerifyOpts
+}
+
+// Used as a key to a request's [context.Context] to store the webhook request id.
+type ContextRequestId struct{}
+
+// Returns logging attributes derived from the provided [context.Context].
+// Currently, this is the webhook request id (if present).
+func (p *provider) contextLogAttrs(c context.Context) []any {
+	id, ok := c.Value(ContextRequestId{}).(string)
+	if !ok || id == "" {
+		return []any{}
+	}
+	return []any{"request-id", id}
+}
+
+// Used as a key to a request's [context.Context] to store a [slog.Logger] - see [NewContextWithLogger]
+type ContextLogger struct{}
+
+// Returns a copy of the [context.Context] holding the given logger.
+// When provided to provider methods, the logger (rather than that provided via [ProviderOpts]) is used for the duration of the call - allowing embedders to attach request-scoped fields.
+func NewContextWithLogger(c context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(c, ContextLogger{}, l)
+}
+
+// Returns the logger used for the duration of a call - see [NewContextWithLogger], [provider.contextLogAttrs]
+func (p *provider) contextLogger(c context.Context) *slog.Logger {
+	l := p.logger
+	cl, ok := c.Value(ContextLogger{}).(*slog.Logger)
+	if ok && cl != nil {
+		l = cl.With("name", "provider")
+	}
+	return l.With(p.contextLogAttrs(c)...)
+}
+
+// Returns a copy of the given client used for the duration of a call - logging via the logger held by the [context.Context] (if present).
+// Executed commands are recorded within the [CommandStats] held by the [context.Context] (if present).
+// As the client is a copy, it uses its own routeros connection (see [Client.WithLogAttrs]).
+func (p *provider) contextClient(c context.Context, pc Client) Client {
+	cl, ok := c.Value(ContextLogger{}).(*slog.Logger)
+	if ok && cl != nil {
+		pc = pc.WithLogger(cl.With("name", "client"))
+	}
+	cs, ok := c.Value(ContextCommandStats{}).(*CommandStats)
+	if ok && cs != nil {
+		pc = pc.WithCommandStats(cs)
+	}
+	return pc.WithLogAttrs(p.contextLogAttrs(c)...)
 }
 
 // Creates a new [provider] using the provided options within [ProviderOpts]
@@ -37,70 +157,1022 @@ func NewProvider(o *ProviderOpts) (*provider, error) {
 	if l == nil {
 		l = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
-	return &provider{
-		client:       o.Client,
-		domainFilter: o.DomainFilter,
-		logger:       l,
-	}, nil
+	c := o.Concurrency
+	if c == 0 {
+		c = 1
+	}
+	np := o.NamePolicy
+	if np == "" {
+		np = NamePolicyPermissive
+	}
+	if !slices.Contains(namePolicies, np) {
+		return nil, fmt.Errorf("unrecognized name policy %s", np)
+	}
+	err := o.Ttl.validate()
+	if err != nil {
+		return nil, err
+	}
+	p := &provider{
+		batch:             o.Batch,
+		client:            o.Client,
+		concurrency:       c,
+		domainFilter:      o.DomainFilter,
+		eventRecorder:     o.EventRecorder,
+		integrity:         o.Integrity,
+		logger:            l,
+		logSampleLimit:    o.LogSampleLimit,
+		namePolicy:        np,
+		protectedRegex:    o.ProtectedRegex,
+		quotaLabel:        o.QuotaLabel,
+		quotaLabelMax:     o.QuotaLabelMax,
+		quotaNamespace:    o.QuotaNamespace,
+		recordsCache:      &recordsCache{ttl: o.RecordsCache},
+		rewriteRules:      o.RewriteRules,
+		rollbackOnFailure: o.RollbackOnFailure,
+		serial:            o.Serial,
+		ttl:               o.Ttl,
+	}
+	for _, n := range o.ProtectedNames {
+		p.protectedNames = append(p.protectedNames, normalizeDnsName(n))
+	}
+	if o.IntentLog != "" {
+		p.intentLog = newIntentLog(o.IntentLog)
+	}
+	if o.Lock.Record != "" {
+		p.lock = o.Lock
+		if p.lock.Lease == 0 {
+			p.lock.Lease = 5 * time.Minute
+		}
+		if p.lock.Timeout == 0 {
+			p.lock.Timeout = time.Minute
+		}
+		p.lockOwner, err = newLockOwner()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create lock owner: %w", err)
+		}
+	}
+	if o.Verify.Window > 0 {
+		p.verifier = newVerifier(o.Verify, l.With("name", "verifier"))
+	}
+	if o.ApplyDebounce > 0 {
+		p.applyQueue = newApplyQueue(p.applyBatches, o.ApplyDebounce, l)
+	}
+	return p, nil
+}
+
+// Limits per-record log output by logging the first N messages for a given key at info level.
+// Subsequent messages for the same key are logged at debug level and summarized via [logSampler.Summarize].
+// A limit of 0 disables sampling.
+// Safe for concurrent use.
+type logSampler struct {
+	counts map[string]uint
+	keys   []string
+	limit  uint
+	logger *slog.Logger
+	mutex  sync.Mutex
+}
+
+// Creates a new [logSampler] with the given logger and limit
+func newLogSampler(l *slog.Logger, lim uint) *logSampler {
+	return &logSampler{
+		counts: map[string]uint{},
+		keys:   []string{},
+		limit:  lim,
+		logger: l,
+	}
+}
+
+// Logs a message for the given key - at info level if the key's limit has not been reached, otherwise at debug level
+func (ls *logSampler) Info(k string, m string) {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	c, ok := ls.counts[k]
+	if !ok {
+		ls.keys = append(ls.keys, k)
+	}
+	ls.counts[k] = c + 1
+	if ls.limit == 0 || c < ls.limit {
+		ls.logger.Info(m)
+		return
+	}
+	ls.logger.Debug(m)
+}
+
+// Logs a summary for each key whose messages exceeded the sampling limit
+func (ls *logSampler) Summarize() {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	if ls.limit == 0 {
+		return
+	}
+	for _, k := range ls.keys {
+		c := ls.counts[k]
+		if c <= ls.limit {
+			continue
+		}
+		ls.logger.Info(fmt.Sprintf("%s: %d records total (%d omitted, see debug logs)", k, c, c-ls.limit))
+	}
 }
 
 // According to [ednsprovider.Provider], 'canonicalizes' endpoints to be consistent with that of the provider.
-// Currently, this provider does not need to canonicalize endpoints and simply returns the given input.
+// Lowercases and removes trailing dots from dns names and targets (see [normalizeEndpoint]) - matching how routeros stores records.
+// Afterwards, dns names are rewritten using the configured rewrite rules (see [RewriteRule]).
+// With the strict name policy, endpoints with invalid dns names are dropped beforehand (see [validateStrictDnsName]).
+// Over-long comments (see [validateComment]) are removed.
+// Finally, endpoints sharing a record type and name are merged (see [mergeEndpoints]) - merged and rejected endpoints are logged (and reported as kubernetes events).
 func (p *provider) AdjustEndpoints(es []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	aes := []*endpoint.Endpoint{}
+	for _, e := range es {
+		if p.namePolicy == NamePolicyStrict {
+			err := validateStrictDnsName(e.RecordType, e.DNSName)
+			if err != nil {
+				p.logger.Warn(fmt.Sprintf("dropping endpoint %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+				continue
+			}
+		}
+		normalizeEndpoint(e)
+		rewriteEndpoint(e, p.rewriteRules)
+		uc, ok := e.GetProviderSpecificProperty(providerSpecificComment)
+		if ok {
+			err := validateComment(uc)
+			if err != nil {
+				// an over-long comment would be dropped from record metadata - and differ from the listed endpoint forever
+				p.logger.Warn(fmt.Sprintf("removing comment of endpoint %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+				e.DeleteProviderSpecificProperty(providerSpecificComment)
+			}
+		}
+		err := validateEndpoint(e)
+		if err != nil {
+			// invalid endpoints are retained - their creation fails with this error (see [client.CreateEndpoint])
+			p.logger.Warn(fmt.Sprintf("endpoint %s %s invalid: %s", e.RecordType, e.DNSName, err.Error()))
+		}
+		aes = append(aes, e)
+	}
+	p.checkDomainFilter(aes)
+	return mergeEndpoints(aes, func(e *endpoint.Endpoint, merged bool, m string) {
+		r := e.Labels[endpoint.ResourceLabelKey]
+		if !merged {
+			p.logger.Warn(fmt.Sprintf("rejecting endpoint %s %s (resource %s): %s", e.RecordType, e.DNSName, r, m))
+			if p.eventRecorder != nil {
+				p.eventRecorder.Record(e, EventTypeWarning, "EndpointRejected", fmt.Sprintf("routeros dns record %s %s rejected: %s", e.RecordType, e.DNSName, m))
+			}
+			return
+		}
+		p.logger.Warn(fmt.Sprintf("merging endpoint %s %s (resource %s): %s", e.RecordType, e.DNSName, r, m))
+		if p.eventRecorder != nil {
+			p.eventRecorder.Record(e, EventTypeWarning, "EndpointMerged", fmt.Sprintf("routeros dns record %s %s merged: %s", e.RecordType, e.DNSName, m))
+		}
+	}), nil
+}
+
+// Changes grouped for a single dns name - see [provider.ApplyChanges]
+// A group may hold changes for multiple record types sharing the name (e.g., an A record and its TXT registry record).
+type nameChanges struct {
+	creates []*endpoint.Endpoint
+	deletes []*endpoint.Endpoint
+	updates []*endpoint.Endpoint
+}
+
+// Pairs the updated endpoints of the given changes - mapping each endpoint within [plan.Changes.UpdateNew] to the endpoint it replaces within [plan.Changes.UpdateOld].
+// Updated endpoints lacking a counterpart are omitted.
+func getUpdatePairs(ch *plan.Changes) map[*endpoint.Endpoint]*endpoint.Endpoint {
+	ups := map[*endpoint.Endpoint]*endpoint.Endpoint{}
+	for _, ne := range ch.UpdateNew {
+		for _, oe := range ch.UpdateOld {
+			if oe.RecordType != ne.RecordType || normalizeDnsName(oe.DNSName) != normalizeDnsName(ne.DNSName) {
+				continue
+			}
+			ups[ne] = oe
+			break
+		}
+	}
+	return ups
+}
+
+// Applies DNS changes to the target using this provider.
+// If configured with a debounce window, changes are queued, coalesced and applied serially (see [applyQueue]).
+// If configured with a batch size, large sets of changes are applied in batches (see [provider.applyBatches]).
+func (p *provider) ApplyChanges(co context.Context, ch *plan.Changes) error {
+	_, err := p.ApplyChangesWithStatus(co, ch)
+	return err
+}
+
+// Applies DNS changes (see [provider.ApplyChanges]) - additionally returning the outcome of the sync, including the outcome of each change (see [ApplyResult]).
+// When changes are coalesced with those of other calls (see [applyQueue]), the outcome of the coalesced sync is returned.
+func (p *provider) ApplyChangesWithStatus(co context.Context, ch *plan.Changes) (ApplyStatus, error) {
+	if p.applyQueue != nil {
+		return p.applyQueue.Enqueue(co, ch)
+	}
+	return p.applyBatches(co, ch)
+}
+
+// Determines whether the given endpoint's name is protected from changes (via the configured protected names or regex)
+func (p *provider) isProtected(e *endpoint.Endpoint) bool {
+	n := normalizeDnsName(e.DNSName)
+	if slices.Contains(p.protectedNames, n) {
+		return true
+	}
+	return p.protectedRegex != nil && p.protectedRegex.MatchString(n)
+}
+
+// Returns the quota keys (e.g., 'namespace/default') that an endpoint counts against.
+// Endpoints lacking the labels used by configured quotas do not count against those quotas.
+func (p *provider) quotaKeys(e *endpoint.Endpoint) []string {
+	ks := []string{}
+	if p.quotaNamespace > 0 {
+		ps := strings.Split(e.Labels[endpoint.ResourceLabelKey], "/")
+		if len(ps) == 3 {
+			ks = append(ks, fmt.Sprintf("namespace/%s", ps[1]))
+		}
+	}
+	if p.quotaLabel != "" && p.quotaLabelMax > 0 {
+		v, ok := e.Labels[p.quotaLabel]
+		if ok {
+			ks = append(ks, fmt.Sprintf("label/%s", v))
+		}
+	}
+	return ks
+}
+
+// Returns the quota limit for the given quota key (see [provider.quotaKeys])
+func (p *provider) quotaLimit(k string) uint {
+	if strings.HasPrefix(k, "namespace/") {
+		return p.quotaNamespace
+	}
+	return p.quotaLabelMax
+}
+
+// Removes creations from the changes that would exceed configured quotas.
+// Usage is computed from existing records (via the record labels stored within record metadata) and the changes themselves.
+// Returns the endpoints whose creation was refused.
+func (p *provider) enforceQuotas(pc Client, ch *plan.Changes) ([]*endpoint.Endpoint, error) {
+	if p.quotaNamespace == 0 && (p.quotaLabel == "" || p.quotaLabelMax == 0) {
+		return []*endpoint.Endpoint{}, nil
+	}
+	es, err := pc.ListEndpoints()
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	u := map[string]uint{}
+	for _, e := range es {
+		for _, k := range p.quotaKeys(e) {
+			u[k] += 1
+		}
+	}
+	for _, e := range ch.Delete {
+		for _, k := range p.quotaKeys(e) {
+			if u[k] > 0 {
+				u[k] -= 1
+			}
+		}
+	}
+	refused := []*endpoint.Endpoint{}
+	creates := []*endpoint.Endpoint{}
+	for _, e := range ch.Create {
+		ks := p.quotaKeys(e)
+		exceeded := false
+		for _, k := range ks {
+			if u[k]+1 > p.quotaLimit(k) {
+				exceeded = true
+			}
+		}
+		if exceeded {
+			refused = append(refused, e)
+			continue
+		}
+		for _, k := range ks {
+			u[k] += 1
+		}
+		creates = append(creates, e)
+	}
+	ch.Create = creates
+	return refused, nil
+}
+
+// Returns the number of changes refused because they targeted protected names
+func (p *provider) ProtectedCount() uint64 {
+	return p.protectedCount.Load()
+}
+
+// Completes a sync interrupted by a provider crash - using the changes persisted to the intent log (see [intentLog]).
+// Records the interrupted sync intended to create are deleted (if present) and recreated - and records it intended to delete are deleted.
+// As a result, recovery is idempotent regardless of how far the interrupted sync progressed.
+// Does nothing if the intent log is disabled or empty.
+func (p *provider) Recover(co context.Context) error {
+	if p.intentLog == nil {
+		return nil
+	}
+	ch, ok, err := p.intentLog.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read intent log: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	p.contextLogger(co).Warn("recovering interrupted sync from intent log")
+	rch := &plan.Changes{Create: []*endpoint.Endpoint{}, Delete: []*endpoint.Endpoint{}}
+	rch.Create = append(rch.Create, ch.Create...)
+	rch.Create = append(rch.Create, ch.UpdateNew...)
+	rch.Delete = append(rch.Delete, ch.Delete...)
+	rch.Delete = append(rch.Delete, ch.UpdateOld...)
+	rch.Delete = append(rch.Delete, rch.Create...)
+	_, err = p.applyChanges(co, rch)
+	return err
+}
+
+// Options controlling periodic checks for external modifications to managed records (see [client.CheckIntegrity])
+type IntegrityOpts struct {
+	// The interval between checks - checks are disabled when 0
+	Interval time.Duration
+	// Whether modified records are deleted (and subsequently recreated by external-dns)
+	Repair bool
+}
+
+// Periodically checks managed records for external modifications until the process exits.
+// Modified records are logged, counted and (optionally) reported as kubernetes events.
+// Does nothing if checks are disabled (see [IntegrityOpts]).
+func (p *provider) RunIntegrityChecks() {
+	if p.integrity.Interval == 0 {
+		return
+	}
+	t := time.NewTicker(p.integrity.Interval)
+	defer t.Stop()
+	for range t.C {
+		_, err := p.checkIntegrity(p.logger, p.client.WithLogAttrs())
+		if err != nil {
+			p.logger.Warn(fmt.Sprintf("failed to check records for external modifications: %s", err.Error()))
+		}
+	}
+}
+
+// Checks managed records for external modifications once using the given client - see [provider.RunIntegrityChecks].
+// Returns the modified records.
+func (p *provider) checkIntegrity(l *slog.Logger, pc Client) ([]*endpoint.Endpoint, error) {
+	l.Debug("checking records for external modifications")
+	es, err := pc.CheckIntegrity(p.integrity.Repair)
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	if p.integrity.Repair && len(es) > 0 {
+		p.invalidateRecords()
+	}
+	for _, e := range es {
+		m := fmt.Sprintf("record %s %s (%s) modified externally", e.RecordType, e.DNSName, strings.Join(e.Targets, ","))
+		if p.integrity.Repair {
+			m = fmt.Sprintf("%s - deleted for recreation", m)
+		}
+		l.Warn(m)
+		if p.eventRecorder != nil {
+			p.eventRecorder.Record(e, EventTypeWarning, "RecordModified", fmt.Sprintf("routeros dns %s", m))
+		}
+	}
+	p.modifiedCount.Add(uint64(len(es)))
 	return es, nil
 }
 
+// The result of [provider.Resync]
+type ResyncResult struct {
+	// Number of externally modified records detected (see [provider.checkIntegrity])
+	Modified int `json:"modified"`
+	// Number of records listed
+	Records int `json:"records"`
+}
+
+// Forces the provider to resynchronize with routeros - allowing operators to recover from inconsistencies without restarting the provider.
+// Completes any sync interrupted by a crash (see [provider.Recover]), discards in-flight and cached record listings (so that subsequent calls to [provider.Records] list records afresh), lists records (cleaning up malformed records) and checks records for external modifications (repairing them if configured - see [IntegrityOpts]).
+// Returns an error if any step fails.
+func (p *provider) Resync(co context.Context) (ResyncResult, error) {
+	l := p.contextLogger(co)
+	l.Info("resyncing records")
+	err := p.Recover(co)
+	if err != nil {
+		return ResyncResult{}, err
+	}
+	p.invalidateRecords()
+	pc := p.contextClient(co, p.client)
+	es, err := pc.ListEndpoints()
+	if err != nil {
+		return ResyncResult{}, err
+	}
+	mes, err := p.checkIntegrity(l, pc)
+	if err != nil {
+		return ResyncResult{}, err
+	}
+	return ResyncResult{Modified: len(mes), Records: len(es)}, nil
+}
+
+// Returns the number of externally modified records detected (see [provider.RunIntegrityChecks])
+func (p *provider) ModifiedCount() uint64 {
+	return p.modifiedCount.Load()
+}
+
 // Applies DNS changes to the target using this provider.
+// Changes targeting protected names are refused (logged and counted) - see [provider.isProtected].
+// CNAME records that would create a loop are refused (logged and counted) - see [provider.enforceCnameLoops].
+// Changes are grouped by dns name - groups are applied concurrently (bounded by the configured concurrency).
+// Within a group, deletions are applied before updates - which are applied before creations.
+// Groups are applied after the groups creating or updating the records they depend upon (e.g., a CNAME record's target - see [endpointDependencies]).
+// Updates are applied in place (see [Client.CreateOrUpdateEndpoint]) rather than as a deletion followed by a creation - unless they move records to a different router (see [isRouteChange]), in which case the replaced endpoint is deleted from the router holding it.
+// Deletions are batched into a single routeros api call (see [Client.DeleteEndpoints]) when possible - in which case they precede all creations.
 // Returns an error if any update operation fails.
 // Attempts to apply all changes before returning an error on failure.
-func (p *provider) ApplyChanges(co context.Context, ch *plan.Changes) error {
-	p.logger.Info("applying changes")
+func (p *provider) applyChanges(co context.Context, ch *plan.Changes) (ApplyStatus, error) {
+	l := p.contextLogger(co)
+	l.Info("applying changes")
 
-	errs := []error{}
+	// listings overlapping the sync are discarded - subsequent calls to [provider.Records] list records afresh
+	p.invalidateRecords()
+	defer p.invalidateRecords()
 
-	for _, e := range append(ch.Delete, ch.UpdateOld...) {
-		p.logger.Info(fmt.Sprintf("deleting record %s %s", e.RecordType, e.DNSName))
-		err := p.client.DeleteEndpoint(e)
+	ns := []string{}
+	ncs := map[string]*nameChanges{}
+	getNameChanges := func(n string) *nameChanges {
+		nc, ok := ncs[n]
+		if !ok {
+			nc = &nameChanges{}
+			ncs[n] = nc
+			ns = append(ns, n)
+		}
+		return nc
+	}
+	p.settingsMutex.RLock()
+	c := p.concurrency
+	lsl := p.logSampleLimit
+	p.settingsMutex.RUnlock()
+
+	ls := newLogSampler(l, lsl)
+
+	qch := *ch
+	ch = &qch
+	refused, err := p.enforceQuotas(p.contextClient(co, p.client), ch)
+	if err != nil {
+		return ApplyStatus{}, err
+	}
+	rs := []ApplyResult{}
+	addRefused := func(op string, e *endpoint.Endpoint, o string) {
+		rs = append(rs, ApplyResult{DNSName: e.DNSName, Operation: op, Outcome: o, RecordType: e.RecordType})
+	}
+	for _, e := range refused {
+		addRefused("create", e, ApplyOutcomeQuotaExceeded)
+		l.Warn(fmt.Sprintf("refusing to create record %s %s: quota exceeded", e.RecordType, e.DNSName))
+		if p.eventRecorder != nil {
+			p.eventRecorder.Record(e, EventTypeWarning, "QuotaExceeded", fmt.Sprintf("refused to create routeros dns record %s %s: quota exceeded", e.RecordType, e.DNSName))
+		}
+	}
+	cls, err := p.enforceCnameLoops(p.contextClient(co, p.client), ch)
+	if err != nil {
+		return ApplyStatus{}, err
+	}
+	for _, cl := range cls {
+		e := cl.endpoint
+		op := "create"
+		if cl.update {
+			op = "update"
+		}
+		m := fmt.Sprintf("cname loop %s", strings.Join(cl.names, " -> "))
+		addRefused(op, e, ApplyOutcomeCnameLoop)
+		l.Warn(fmt.Sprintf("refusing to %s record %s %s: %s", op, e.RecordType, e.DNSName, m))
+		if p.eventRecorder != nil {
+			p.eventRecorder.Record(e, EventTypeWarning, "CnameLoop", fmt.Sprintf("refused to %s routeros dns record %s %s: %s", op, e.RecordType, e.DNSName, m))
+		}
+	}
+
+	if p.lock.Record != "" {
+		// the lock is held until all changes (including rollbacks) are applied
+		release, err := p.acquireLock(co, p.contextClient(co, p.client))
+		if err != nil {
+			return ApplyStatus{}, err
+		}
+		defer release()
+	}
+
+	if p.intentLog != nil {
+		err := p.intentLog.Write(ch)
 		if err != nil {
-			p.logger.Warn(fmt.Sprintf("failed to delete record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
-			errs = append(errs, err)
+			return ApplyStatus{}, fmt.Errorf("failed to write intent log: %w", err)
+		}
+		defer func() {
+			err := p.intentLog.Clear()
+			if err != nil {
+				l.Warn(fmt.Sprintf("failed to clear intent log: %s", err.Error()))
+			}
+		}()
+	}
+
+	// updates are logged as a single before/after line - their individual operations are logged at debug level
+	updated := map[*endpoint.Endpoint]bool{}
+	// updates moving records to a different router delete the replaced endpoint from the router holding it (see [isRouteChange])
+	moved := map[*endpoint.Endpoint]bool{}
+	ups := getUpdatePairs(ch)
+	for _, ne := range ch.UpdateNew {
+		oe, ok := ups[ne]
+		if !ok {
+			continue
+		}
+		if isRouteChange(oe, ne) {
+			moved[oe] = true
+		}
+		if !p.isProtected(ne) {
+			ls.Info(fmt.Sprintf("updating %s records", ne.RecordType), fmt.Sprintf("updating record %s %s: ttl %d -> %d, targets %s -> %s", ne.RecordType, ne.DNSName, oe.RecordTTL, ne.RecordTTL, oe.Targets, ne.Targets))
 		}
+		updated[oe] = true
+		updated[ne] = true
+	}
+	logChange := func(e *endpoint.Endpoint, op string) {
+		m := fmt.Sprintf("%s record %s %s", op, e.RecordType, e.DNSName)
+		r, ok := e.Labels[endpoint.ResourceLabelKey]
+		if ok && r != "" {
+			m += fmt.Sprintf(" (resource %s)", r)
+		}
+		if updated[e] {
+			l.Debug(m)
+			return
+		}
+		ls.Info(fmt.Sprintf("%s %s records", op, e.RecordType), m)
 	}
 
+	for _, e := range append(ch.Delete, ch.UpdateOld...) {
+		if p.isProtected(e) {
+			l.Warn(fmt.Sprintf("refusing to delete protected record %s %s", e.RecordType, e.DNSName))
+			p.protectedCount.Add(1)
+			if !updated[e] {
+				addRefused("delete", e, ApplyOutcomeProtected)
+			}
+			continue
+		}
+		if updated[e] && !moved[e] {
+			// applied alongside its replacement
+			continue
+		}
+		nc := getNameChanges(e.DNSName)
+		nc.deletes = append(nc.deletes, e)
+	}
 	for _, e := range append(ch.Create, ch.UpdateNew...) {
-		p.logger.Info(fmt.Sprintf("creating record %s %s", e.RecordType, e.DNSName))
-		err := p.client.CreateEndpoint(e)
+		if p.isProtected(e) {
+			l.Warn(fmt.Sprintf("refusing to create protected record %s %s", e.RecordType, e.DNSName))
+			p.protectedCount.Add(1)
+			op := "create"
+			if updated[e] {
+				op = "update"
+			}
+			addRefused(op, e, ApplyOutcomeProtected)
+			continue
+		}
+		p.checkTtl(l, e)
+		nc := getNameChanges(e.DNSName)
+		if updated[e] {
+			nc.updates = append(nc.updates, e)
+			continue
+		}
+		nc.creates = append(nc.creates, e)
+	}
+
+	// names are ordered such that records are created before the records depending upon them (e.g., a CNAME record's target) - dependent groups wait for the groups they depend upon
+	nns := map[string]string{}
+	for _, n := range ns {
+		nn := normalizeDnsName(n)
+		_, ok := nns[nn]
+		if !ok {
+			nns[nn] = n
+		}
+	}
+	deps := map[string][]string{}
+	for _, n := range ns {
+		nc := ncs[n]
+		for _, e := range append(slices.Clone(nc.updates), nc.creates...) {
+			for _, d := range endpointDependencies(e) {
+				dn, ok := nns[d]
+				if ok && dn != n && len(ncs[dn].updates)+len(ncs[dn].creates) > 0 && !slices.Contains(deps[n], dn) {
+					deps[n] = append(deps[n], dn)
+				}
+			}
+		}
+	}
+	ns = orderNames(ns, func(n string) []string { return deps[n] })
+	done := map[string]chan struct{}{}
+	pos := map[string]int{}
+	for i, n := range ns {
+		done[n] = make(chan struct{})
+		pos[n] = i
+	}
+
+	as := ApplyStatus{
+		CnameLoops:    len(cls),
+		Failures:      []ApplyFailure{},
+		Protected:     len(ch.Delete) + len(ch.UpdateOld) + len(ch.Create) + len(ch.UpdateNew),
+		QuotaExceeded: len(refused),
+		Time:          time.Now(),
+	}
+	for _, nc := range ncs {
+		// an update replaces two endpoints
+		as.Protected -= len(nc.deletes) + 2*len(nc.updates) + len(nc.creates)
+	}
+	errs := []error{}
+	created := []*endpoint.Endpoint{}
+	em := sync.Mutex{}
+	addResult := func(op string, e *endpoint.Endpoint, err error) {
+		em.Lock()
+		defer em.Unlock()
+		r := ApplyResult{DNSName: e.DNSName, Operation: op, Outcome: ApplyOutcomeApplied, RecordType: e.RecordType}
+		if err != nil {
+			r.Error = err.Error()
+			r.Outcome = ApplyOutcomeFailed
+		}
+		rs = append(rs, r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s record %s %s: %w", op, e.RecordType, e.DNSName, err))
+			as.Failures = append(as.Failures, ApplyFailure{
+				DNSName:    e.DNSName,
+				Error:      err.Error(),
+				Operation:  op,
+				RecordType: e.RecordType,
+			})
+			return
+		}
+		switch op {
+		case "create":
+			as.Created += 1
+			created = append(created, e)
+		case "delete":
+			as.Deleted += 1
+		case "update":
+			as.Updated += 1
+			created = append(created, e)
+		}
+	}
+	recordEvent := func(op string, e *endpoint.Endpoint, err error) {
+		if p.eventRecorder == nil {
+			return
+		}
+		if err != nil {
+			p.eventRecorder.Record(e, EventTypeWarning, "RecordFailed", fmt.Sprintf("failed to %s routeros dns record %s %s: %s", op, e.RecordType, e.DNSName, err.Error()))
+			return
+		}
+		switch op {
+		case "create":
+			p.eventRecorder.Record(e, EventTypeNormal, "RecordCreated", fmt.Sprintf("created routeros dns record %s %s", e.RecordType, e.DNSName))
+		case "update":
+			p.eventRecorder.Record(e, EventTypeNormal, "RecordUpdated", fmt.Sprintf("updated routeros dns record %s %s", e.RecordType, e.DNSName))
+		}
+	}
+
+	// when rolling back on failure, changes are journaled - allowing them to be rolled back if any change fails
+	bc := p.client
+	var j *Journal
+	if p.rollbackOnFailure {
+		j = NewJournal()
+		bc = bc.WithJournal(j)
+	}
+
+	// deletions are batched into a single api call - falling back to per-name deletions (identifying the failing records) if the batch fails.
+	// as a failing batch may have deleted some records without journaling them, deletions are not batched when rolling back on failure.
+	des := []*endpoint.Endpoint{}
+	for _, n := range ns {
+		des = append(des, ncs[n].deletes...)
+	}
+	if len(des) > 1 && j == nil {
+		err := p.contextClient(co, bc).DeleteEndpoints(des)
+		if err != nil {
+			l.Warn(fmt.Sprintf("failed to delete %d records in batch - deleting individually: %s", len(des), err.Error()))
+		} else {
+			for _, e := range des {
+				logChange(e, "deleted")
+				addResult("delete", e, nil)
+				recordEvent("delete", e, nil)
+			}
+			for _, nc := range ncs {
+				nc.deletes = []*endpoint.Endpoint{}
+			}
+		}
+	}
+
+	g := errgroup.Group{}
+	g.SetLimit(int(c))
+	for _, n := range ns {
+		nc := ncs[n]
+		g.Go(func() error {
+			defer close(done[n])
+			// groups are started in order - groups depending upon earlier groups (which have already started) wait for them to complete
+			for _, d := range deps[n] {
+				if pos[d] < pos[n] {
+					<-done[d]
+				}
+			}
+
+			// [provider.contextClient] returns a copy - ensuring each group uses its own routeros connection
+			pc := p.contextClient(co, bc)
+
+			for _, e := range nc.deletes {
+				logChange(e, "deleting")
+				err := pc.DeleteEndpoint(e)
+				if err != nil {
+					l.Warn(fmt.Sprintf("failed to delete record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+				}
+				addResult("delete", e, err)
+				recordEvent("delete", e, err)
+			}
+
+			for _, e := range nc.updates {
+				logChange(e, "updating")
+				err := pc.CreateOrUpdateEndpoint(e)
+				if err != nil {
+					l.Error(fmt.Sprintf("failed to update record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+				}
+				addResult("update", e, err)
+				recordEvent("update", e, err)
+			}
+
+			for _, e := range nc.creates {
+				logChange(e, "creating")
+				err := pc.CreateEndpoint(e)
+				if err != nil {
+					l.Error(fmt.Sprintf("failed to create record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+				}
+				addResult("create", e, err)
+				recordEvent("create", e, err)
+			}
+
+			return nil
+		})
+	}
+	g.Wait()
+
+	ls.Summarize()
+
+	if j != nil && len(errs) != 0 && j.Len() > 0 {
+		l.Warn(fmt.Sprintf("rolling back %d changes after %d failures", j.Len(), len(errs)))
+		err := p.contextClient(co, p.client).Rollback(j)
 		if err != nil {
-			p.logger.Error(fmt.Sprintf("failed to create record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
-			errs = append(errs, err)
+			l.Error(fmt.Sprintf("failed to roll back changes: %s", err.Error()))
 		}
+		as.RolledBack = true
+	}
+
+	if p.serial.enabled() && !as.RolledBack && as.Created+as.Deleted+as.Updated > 0 {
+		s, err := p.contextClient(co, p.client).BumpSerial(p.serial)
+		if err != nil {
+			l.Warn(fmt.Sprintf("failed to bump serial: %s", err.Error()))
+		}
+		as.Serial = s
+	}
+
+	as.Duration = time.Since(as.Time).String()
+	as.Failed = len(as.Failures)
+	verify := p.verifier != nil && !as.RolledBack && len(created) > 0
+	if verify {
+		as.Verification = &VerifyStatus{Failures: []ApplyFailure{}, Pending: true}
+	}
+	p.statusMutex.Lock()
+	p.applyStatus = as
+	p.statusMutex.Unlock()
+
+	// per-change results are only returned to the caller - keeping the stored status small
+	ras := as
+	ras.Results = rs
+	if as.RolledBack {
+		for i := range ras.Results {
+			if ras.Results[i].Outcome == ApplyOutcomeApplied {
+				ras.Results[i].Outcome = ApplyOutcomeRolledBack
+			}
+		}
+	}
+
+	if verify {
+		// verification is retried for a window - performed in the background to avoid delaying the webhook response
+		go p.verifyChanges(l, as.Time, created)
 	}
 
 	if len(errs) != 0 {
-		return fmt.Errorf("failed to update %d records", len(errs))
+		// each failure is included (as a joined error) - identifying the records that failed
+		return ras, fmt.Errorf("failed to update %d records: %w", len(errs), errors.Join(errs...))
 	}
 
-	return nil
+	return ras, nil
 }
 
-// Performs a health check of provider and client
-// Returns an error if the provider/client are unhealthy
-func (p *provider) Health() error {
-	p.logger.Info("performing health check")
-	err := p.client.Health()
-	if err != nil {
-		err = fmt.Errorf("client health check failed: %w", err)
+// Verifies that created records resolve via routeros' dns server (see [verifier]).
+// Records failing verification are logged, counted and (optionally) reported as kubernetes events.
+// The [ApplyStatus] of the sync started at the given time is updated with the outcome - unless a subsequent sync has replaced it.
+func (p *provider) verifyChanges(l *slog.Logger, t time.Time, es []*endpoint.Endpoint) {
+	fs := p.verifier.Verify(es)
+	vs := VerifyStatus{Failures: []ApplyFailure{}, Verified: len(es) - len(fs)}
+	for _, e := range es {
+		err, ok := fs[e]
+		if !ok {
+			continue
+		}
+		l.Warn(fmt.Sprintf("failed to verify record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+		if p.eventRecorder != nil {
+			p.eventRecorder.Record(e, EventTypeWarning, "RecordUnverified", fmt.Sprintf("routeros dns record %s %s does not resolve: %s", e.RecordType, e.DNSName, err.Error()))
+		}
+		vs.Failures = append(vs.Failures, ApplyFailure{
+			DNSName:    e.DNSName,
+			Error:      err.Error(),
+			Operation:  "verify",
+			RecordType: e.RecordType,
+		})
 	}
-	return err
+	vs.Failed = len(vs.Failures)
+	p.unverifiedCount.Add(uint64(vs.Failed))
+	p.statusMutex.Lock()
+	defer p.statusMutex.Unlock()
+	if p.applyStatus.Time.Equal(t) {
+		p.applyStatus.Verification = &vs
+	}
+}
+
+// Returns the number of created records that failed verification (see [provider.verifyChanges])
+func (p *provider) UnverifiedCount() uint64 {
+	return p.unverifiedCount.Load()
+}
+
+// A change that failed to apply - see [ApplyStatus]
+type ApplyFailure struct {
+	DNSName    string `json:"dnsName"`
+	Error      string `json:"error"`
+	Operation  string `json:"operation"`
+	RecordType string `json:"recordType"`
+}
+
+// The outcome of a single change - see [ApplyStatus.Results]
+type ApplyResult struct {
+	DNSName    string `json:"dnsName"`
+	Error      string `json:"error,omitempty"`
+	Operation  string `json:"operation"`
+	Outcome    string `json:"outcome"`
+	RecordType string `json:"recordType"`
+}
+
+// Outcomes of a change - see [ApplyResult]
+const (
+	ApplyOutcomeApplied       = "applied"
+	ApplyOutcomeCnameLoop     = "cnameLoop"
+	ApplyOutcomeFailed        = "failed"
+	ApplyOutcomeProtected     = "protected"
+	ApplyOutcomeQuotaExceeded = "quotaExceeded"
+	ApplyOutcomeRolledBack    = "rolledBack"
+)
+
+// Describes the outcome of the most recent [provider.ApplyChanges] call
+type ApplyStatus struct {
+	// Number of CNAME records refused as they would create a loop (see [provider.enforceCnameLoops])
+	CnameLoops    int            `json:"cnameLoops"`
+	Created       int            `json:"created"`
+	Deleted       int            `json:"deleted"`
+	Duration      string         `json:"duration"`
+	Failed        int            `json:"failed"`
+	Failures      []ApplyFailure `json:"failures"`
+	Protected     int            `json:"protected"`
+	QuotaExceeded int            `json:"quotaExceeded"`
+	// The outcome of each change - only returned by [provider.ApplyChangesWithStatus]
+	Results []ApplyResult `json:"results,omitempty"`
+	// Whether the changes were rolled back following a failure (see [ProviderOpts.RollbackOnFailure])
+	RolledBack bool `json:"rolledBack"`
+	// The zone serial following the sync (see [ProviderOpts.Serial])
+	Serial  string    `json:"serial,omitempty"`
+	Time    time.Time `json:"time"`
+	Updated int       `json:"updated"`
+	// The outcome of verifying created records (see [ProviderOpts.Verify])
+	Verification *VerifyStatus `json:"verification,omitempty"`
+}
+
+// Describes the outcome of verifying the records created by a sync - see [provider.verifyChanges]
+type VerifyStatus struct {
+	Failed   int            `json:"failed"`
+	Failures []ApplyFailure `json:"failures"`
+	// Whether verification is still in progress
+	Pending  bool `json:"pending"`
+	Verified int  `json:"verified"`
+}
+
+// Returns the load the provider has imposed on each of its routers (see [Client.Stats])
+func (p *provider) Stats() []ClientStats {
+	return p.client.Stats()
+}
+
+// Returns the outcome of the most recent [provider.ApplyChanges] call.
+// Returns a zero [ApplyStatus] if changes have not yet been applied.
+func (p *provider) Status() ApplyStatus {
+	p.statusMutex.RLock()
+	defer p.statusMutex.RUnlock()
+	return p.applyStatus
+}
+
+// The result of [provider.Simulate]
+type SimulateResult struct {
+	Commands []string `json:"commands"`
+	Errors   []string `json:"errors"`
+}
+
+// Determines the routeros api commands that would be executed to apply the given changes - without executing them.
+// Protected names are skipped, and changes are applied sequentially (deletions first, then updates).
+// Read-only commands (e.g., listing records) are executed against routeros.
+func (p *provider) Simulate(c context.Context, ch *plan.Changes) (SimulateResult, error) {
+	p.contextLogger(c).Info("simulating changes")
+
+	sr := SimulateResult{Commands: []string{}, Errors: []string{}}
+	pc := p.contextClient(c, p.client).WithDryRun(func(cmd []string) {
+		sr.Commands = append(sr.Commands, strings.Join(cmd, " "))
+	})
+	ups := getUpdatePairs(ch)
+	updated := map[*endpoint.Endpoint]bool{}
+	for ne, oe := range ups {
+		// updates moving records to a different router delete the replaced endpoint (see [isRouteChange])
+		updated[oe] = !isRouteChange(oe, ne)
+	}
+	for _, e := range append(ch.Delete, ch.UpdateOld...) {
+		if p.isProtected(e) || updated[e] {
+			continue
+		}
+		err := pc.DeleteEndpoint(e)
+		if err != nil {
+			sr.Errors = append(sr.Errors, fmt.Sprintf("delete record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+		}
+	}
+	for _, e := range ch.UpdateNew {
+		if p.isProtected(e) || ups[e] == nil {
+			continue
+		}
+		err := pc.CreateOrUpdateEndpoint(e)
+		if err != nil {
+			sr.Errors = append(sr.Errors, fmt.Sprintf("update record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+		}
+	}
+	for _, e := range append(ch.Create, ch.UpdateNew...) {
+		if p.isProtected(e) || ups[e] != nil {
+			continue
+		}
+		err := pc.CreateEndpoint(e)
+		if err != nil {
+			sr.Errors = append(sr.Errors, fmt.Sprintf("create record %s %s: %s", e.RecordType, e.DNSName, err.Error()))
+		}
+	}
+	return sr, nil
+}
+
+// Fetches details describing the routeros device the provider is connected to
+func (p *provider) Info() (RouterInfo, error) {
+	p.logger.Info("fetching router info")
+	return p.client.Info()
 }
 
 // Gets the domain filters configured when the provider was launched
 func (p *provider) GetDomainFilter() endpoint.DomainFilter {
+	p.settingsMutex.RLock()
+	defer p.settingsMutex.RUnlock()
 	return p.domainFilter
 }
 
+// Settings of a [provider] that can be changed while the provider is running - see [provider.UpdateSettings]
+type ProviderSettings struct {
+	Concurrency    uint
+	DomainFilter   endpoint.DomainFilter
+	LogSampleLimit uint
+}
+
+// Updates the settings of a running [provider].
+// Changes apply to subsequent calls (e.g., an in-progress [provider.ApplyChanges] is unaffected).
+func (p *provider) UpdateSettings(ps ProviderSettings) {
+	c := ps.Concurrency
+	if c == 0 {
+		c = 1
+	}
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
+	p.concurrency = c
+	p.domainFilter = ps.DomainFilter
+	p.logSampleLimit = ps.LogSampleLimit
+}
+
+// Discards in-flight and cached record listings (see [provider.Records]) - called whenever the provider changes records
+func (p *provider) invalidateRecords() {
+	p.recordsGroup.Forget("records")
+	p.recordsCache.invalidate()
+}
+
 // Gets known records attached to this provider
+// Concurrent callers share a single in-flight listing of records.
+// If configured, listings are cached (see [recordsCache]) until they expire or the provider changes records.
 func (p *provider) Records(c context.Context) ([]*endpoint.Endpoint, error) {
-	p.logger.Info("fetching records")
-	return p.client.ListEndpoints()
+	l := p.contextLogger(c)
+	l.Info("fetching records")
+	ces, g, ok := p.recordsCache.get()
+	if ok {
+		l.Debug("using cached records listing")
+		return ces, nil
+	}
+	v, err, sh := p.recordsGroup.Do("records", func() (interface{}, error) {
+		es, err := p.contextClient(c, p.client).ListEndpoints()
+		if err == nil {
+			p.recordsCache.set(g, es)
+		}
+		return es, err
+	})
+	if sh {
+		l.Debug("shared in-flight records listing")
+	}
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	return v.([]*endpoint.Endpoint), nil
 }
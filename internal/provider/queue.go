@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// How long applying a set of (coalesced) changes may take - see [applyQueue.run]
+var applyQueueTimeout = 10 * time.Minute
+
+// Function applying a set of changes - see [provider.applyChanges]
+type applyFunc func(co context.Context, ch *plan.Changes) (ApplyStatus, error)
+
+// Serializes calls to an [applyFunc].
+// Changes enqueued within the debounce window are coalesced into a single set of changes before being applied.
+type applyQueue struct {
+	apply    applyFunc
+	debounce time.Duration
+	logger   *slog.Logger
+	mutex    sync.Mutex
+	pending  []*applyQueueItem
+	running  bool
+}
+
+// A set of changes waiting within an [applyQueue]
+type applyQueueItem struct {
+	changes *plan.Changes
+	context context.Context
+	result  chan applyQueueResult
+}
+
+// The outcome of applying (coalesced) changes - see [applyQueue.Enqueue]
+type applyQueueResult struct {
+	err    error
+	status ApplyStatus
+}
+
+// Creates a new [applyQueue]
+func newApplyQueue(a applyFunc, d time.Duration, l *slog.Logger) *applyQueue {
+	return &applyQueue{
+		apply:    a,
+		debounce: d,
+		logger:   l,
+		pending:  []*applyQueueItem{},
+	}
+}
+
+// Adds changes to the queue and waits for them to be applied.
+// Returns the status and error produced when applying the (coalesced) changes.
+// Returns the context's error if the context is done before the changes are applied - the changes are applied regardless (see [applyQueue.run]).
+func (q *applyQueue) Enqueue(co context.Context, ch *plan.Changes) (ApplyStatus, error) {
+	i := &applyQueueItem{
+		changes: ch,
+		context: co,
+		result:  make(chan applyQueueResult, 1),
+	}
+	q.mutex.Lock()
+	q.pending = append(q.pending, i)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+	q.mutex.Unlock()
+	select {
+	case <-co.Done():
+		return ApplyStatus{}, co.Err()
+	case r := <-i.result:
+		return r.status, r.err
+	}
+}
+
+// Applies queued changes until the queue is empty.
+// Waits for the debounce window before draining the queue - allowing changes to accumulate.
+// Coalesced changes are applied under a context owned by the queue (limited by [applyQueueTimeout]) - so that a caller whose context is done doesn't cancel the changes of others.
+func (q *applyQueue) run() {
+	for {
+		time.Sleep(q.debounce)
+
+		q.mutex.Lock()
+		is := q.pending
+		q.pending = []*applyQueueItem{}
+		if len(is) == 0 {
+			q.running = false
+			q.mutex.Unlock()
+			return
+		}
+		q.mutex.Unlock()
+
+		chs := []*plan.Changes{}
+		for _, i := range is {
+			chs = append(chs, i.changes)
+		}
+		if len(chs) > 1 {
+			q.logger.Info(fmt.Sprintf("coalescing %d queued change sets", len(chs)))
+		}
+		// retains the values (e.g., the request id) of the first caller's context
+		co, cancel := context.WithTimeout(context.WithoutCancel(is[0].context), applyQueueTimeout)
+		as, err := q.apply(co, coalesceChanges(chs))
+		cancel()
+		for _, i := range is {
+			i.result <- applyQueueResult{err: err, status: as}
+		}
+	}
+}
+
+// The coalesced state of a single record type and name - see [coalesceChanges]
+type coalescedChange struct {
+	// The endpoint following the changes - nil if deleted
+	current *endpoint.Endpoint
+	// Whether the record existed prior to the first set of changes
+	existed bool
+	// The endpoint prior to the first set of changes (if it existed)
+	old *endpoint.Endpoint
+}
+
+// Coalesces an ordered list of changes into a single set of changes - transforming the records that existed prior to the first set of changes into the records following the last set of changes.
+// Records that existed beforehand are updated (see [getUpdatePairs]) or deleted, while records that didn't are created - records created and later deleted are omitted.
+func coalesceChanges(chs []*plan.Changes) *plan.Changes {
+	ks := []string{}
+	ccs := map[string]*coalescedChange{}
+	getCoalescedChange := func(e *endpoint.Endpoint, old *endpoint.Endpoint) *coalescedChange {
+		k := fmt.Sprintf("%s::%s", e.RecordType, normalizeDnsName(e.DNSName))
+		cc, ok := ccs[k]
+		if !ok {
+			// first change to the record - the record existed beforehand if it is deleted or updated
+			cc = &coalescedChange{existed: old != nil, old: old}
+			ccs[k] = cc
+			ks = append(ks, k)
+		}
+		return cc
+	}
+
+	for _, ch := range chs {
+		ups := getUpdatePairs(ch)
+		paired := map[*endpoint.Endpoint]bool{}
+		for ne, oe := range ups {
+			paired[ne] = true
+			paired[oe] = true
+		}
+		for _, e := range ch.Delete {
+			getCoalescedChange(e, e).current = nil
+		}
+		for _, e := range ch.UpdateOld {
+			if !paired[e] {
+				getCoalescedChange(e, e).current = nil
+			}
+		}
+		for _, e := range ch.UpdateNew {
+			oe, ok := ups[e]
+			if !ok {
+				getCoalescedChange(e, nil).current = e
+				continue
+			}
+			getCoalescedChange(oe, oe).current = e
+		}
+		for _, e := range ch.Create {
+			getCoalescedChange(e, nil).current = e
+		}
+	}
+
+	ch := &plan.Changes{}
+	for _, k := range ks {
+		cc := ccs[k]
+		switch {
+		case cc.existed && cc.current != nil:
+			ch.UpdateOld = append(ch.UpdateOld, cc.old)
+			ch.UpdateNew = append(ch.UpdateNew, cc.current)
+		case cc.existed:
+			ch.Delete = append(ch.Delete, cc.old)
+		case cc.current != nil:
+			ch.Create = append(ch.Create, cc.current)
+		}
+	}
+	return ch
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestCoalesceChanges(t *testing.T) {
+	a := func(n string, t string) *endpoint.Endpoint { return endpoint.NewEndpoint(n, endpoint.RecordTypeA, t) }
+	a1, a2, a3 := a("a.example.com", "10.0.0.1"), a("a.example.com", "10.0.0.2"), a("a.example.com", "10.0.0.3")
+	b1, b2 := a("b.example.com", "10.0.0.1"), a("b.example.com", "10.0.0.2")
+	c1 := a("c.example.com", "10.0.0.1")
+	tests := []struct {
+		name     string
+		changes  []*plan.Changes
+		expected *plan.Changes
+	}{
+		{
+			name:     "update",
+			changes:  []*plan.Changes{{UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a2}}},
+			expected: &plan.Changes{UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a2}},
+		},
+		{
+			name: "updated twice",
+			changes: []*plan.Changes{
+				{UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a2}},
+				{UpdateOld: []*endpoint.Endpoint{a2}, UpdateNew: []*endpoint.Endpoint{a3}},
+			},
+			expected: &plan.Changes{UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a3}},
+		},
+		{
+			name: "created then updated",
+			changes: []*plan.Changes{
+				{Create: []*endpoint.Endpoint{a1}},
+				{UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a2}},
+			},
+			expected: &plan.Changes{Create: []*endpoint.Endpoint{a2}},
+		},
+		{
+			name: "updated then deleted",
+			changes: []*plan.Changes{
+				{UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a2}},
+				{Delete: []*endpoint.Endpoint{a2}},
+			},
+			expected: &plan.Changes{Delete: []*endpoint.Endpoint{a1}},
+		},
+		{
+			name: "deleted then created",
+			changes: []*plan.Changes{
+				{Delete: []*endpoint.Endpoint{a1}},
+				{Create: []*endpoint.Endpoint{a2}},
+			},
+			expected: &plan.Changes{UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a2}},
+		},
+		{
+			name: "created then deleted",
+			changes: []*plan.Changes{
+				{Create: []*endpoint.Endpoint{a1}},
+				{Delete: []*endpoint.Endpoint{a1}},
+			},
+			expected: &plan.Changes{},
+		},
+		{
+			name: "independent records",
+			changes: []*plan.Changes{
+				{Create: []*endpoint.Endpoint{c1}, UpdateOld: []*endpoint.Endpoint{a1}, UpdateNew: []*endpoint.Endpoint{a2}},
+				{UpdateOld: []*endpoint.Endpoint{b1}, UpdateNew: []*endpoint.Endpoint{b2}},
+			},
+			expected: &plan.Changes{Create: []*endpoint.Endpoint{c1}, UpdateOld: []*endpoint.Endpoint{a1, b1}, UpdateNew: []*endpoint.Endpoint{a2, b2}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := coalesceChanges(test.changes)
+			for _, c := range []struct {
+				name     string
+				actual   []*endpoint.Endpoint
+				expected []*endpoint.Endpoint
+			}{
+				{name: "create", actual: actual.Create, expected: test.expected.Create},
+				{name: "update old", actual: actual.UpdateOld, expected: test.expected.UpdateOld},
+				{name: "update new", actual: actual.UpdateNew, expected: test.expected.UpdateNew},
+				{name: "delete", actual: actual.Delete, expected: test.expected.Delete},
+			} {
+				if !slices.Equal(c.actual, c.expected) {
+					t.Errorf("%s %v, expected %v", c.name, c.actual, c.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyQueueCallerCancelled(t *testing.T) {
+	started := make(chan context.Context, 1)
+	release := make(chan struct{})
+	q := newApplyQueue(func(co context.Context, ch *plan.Changes) (ApplyStatus, error) {
+		started <- co
+		<-release
+		return ApplyStatus{}, co.Err()
+	}, 200*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	enqueue := func(co context.Context, e *endpoint.Endpoint) chan error {
+		ec := make(chan error, 1)
+		go func() {
+			_, err := q.Enqueue(co, &plan.Changes{Create: []*endpoint.Endpoint{e}})
+			ec <- err
+		}()
+		return ec
+	}
+	pending := func() int {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		return len(q.pending)
+	}
+
+	// the first caller's changes are queued before (and so coalesced with) the second's
+	co, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ec1 := enqueue(co, endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1"))
+	for pending() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	ec2 := enqueue(context.Background(), endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.1"))
+	for pending() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	aco := <-started
+	cancel()
+	err := <-ec1
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("first caller error %v, expected %v", err, context.Canceled)
+	}
+	if aco.Err() != nil {
+		t.Errorf("apply context done (%v) when first caller cancelled", aco.Err())
+	}
+	close(release)
+	err = <-ec2
+	if err != nil {
+		t.Errorf("second caller error %v", err)
+	}
+}
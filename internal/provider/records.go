@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// Filters records by name, type and labels - see [RecordFilter.Match]
+type RecordFilter struct {
+	// Label keys ('key') or keys and values ('key=value') records must hold
+	Labels []string
+	// A dns name or glob (e.g., '*.example.com') - matches all records when empty
+	Name string
+	// A record type - matches all records when empty
+	Type string
+}
+
+// Validates the [RecordFilter] - returning an error if the name glob is malformed
+func (f RecordFilter) validate() error {
+	n := normalizeDnsName(f.Name)
+	_, err := path.Match(n, "")
+	if err != nil {
+		return fmt.Errorf("name %s invalid: %w", n, err)
+	}
+	return nil
+}
+
+// Determines whether the given record matches the [RecordFilter]
+func (f RecordFilter) Match(e *endpoint.Endpoint) bool {
+	n := normalizeDnsName(f.Name)
+	if n != "" {
+		m, _ := path.Match(n, normalizeDnsName(e.DNSName))
+		if !m {
+			return false
+		}
+	}
+	if f.Type != "" && e.RecordType != strings.ToUpper(f.Type) {
+		return false
+	}
+	for _, l := range f.Labels {
+		k, v, kv := strings.Cut(l, "=")
+		lv, ok := e.Labels[k]
+		if !ok || (kv && lv != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Options used when listing records via [ListRecords]
+type ListRecordsOpts struct {
+	Filter RecordFilter
+	// Only lists records owned by the given external-dns instance (its '--txt-owner-id') - lists records of all owners when empty
+	Owner string
+	// The '--txt-prefix' used by external-dns
+	TxtPrefix string
+	// The '--txt-suffix' used by external-dns
+	TxtSuffix string
+	// The '--txt-wildcard-replacement' used by external-dns
+	TxtWildcardReplacement string
+}
+
+// Lists the managed records of the routeros devices within [Opts] matching the [ListRecordsOpts] - sorted by name and type.
+// Records are labelled with their owner (see [endpoint.OwnerLabelKey]) using the TXT registry records written by external-dns - which are omitted from the listing.
+func ListRecords(o *Opts, lo *ListRecordsOpts) ([]*endpoint.Endpoint, error) {
+	l := o.Logger
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	err := lo.Filter.validate()
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	pc, err := NewRoutedClient(&ClientOpts{
+		Address:         o.RouterOSAddress,
+		Backend:         o.Backend,
+		CertFingerprint: o.RouterOSCertFingerprint,
+		FallbackIP:      o.RouterOSFallbackIP,
+		Logger:          l.With("name", "client"),
+		Menu:            o.RouterOSMenu,
+		MetadataStore:   o.MetadataStore,
+		Password:        o.RouterOSPassword,
+		Resolver:        o.RouterOSResolver,
+		Trace:           o.TraceRouterOS,
+		Username:        o.RouterOSUsername,
+	}, o.Routes)
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	p, err := NewProvider(&ProviderOpts{Client: pc, Logger: l.With("name", "provider")})
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	// the owner id is only used when writing registry records
+	r, err := registry.NewTXTRegistry(p, lo.TxtPrefix, lo.TxtSuffix, "external-dns-routeros-provider", 0, lo.TxtWildcardReplacement, nil, nil, false, nil)
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	es, err := r.Records(context.Background())
+	if err != nil {
+		return []*endpoint.Endpoint{}, err
+	}
+	mes := []*endpoint.Endpoint{}
+	for _, e := range es {
+		if !lo.Filter.Match(e) {
+			continue
+		}
+		if lo.Owner != "" && e.Labels[endpoint.OwnerLabelKey] != lo.Owner {
+			continue
+		}
+		mes = append(mes, e)
+	}
+	slices.SortFunc(mes, func(a *endpoint.Endpoint, b *endpoint.Endpoint) int {
+		return strings.Compare(a.DNSName+" "+a.RecordType, b.DNSName+" "+b.RecordType)
+	})
+	return mes, nil
+}
+
+// Options used when deleting a record via [DeleteRecord]
+type DeleteRecordOpts struct {
+	// Report the record that would be deleted without deleting it
+	DryRun bool
+	// The dns name of the record to delete - matched exactly (e.g., '*.example.com' only matches the wildcard record)
+	Name string
+	// Only deletes the given targets of the record - deletes all targets when empty
+	Targets []string
+	// The record type of the record to delete
+	Type string
+}
+
+// Deletes a managed record of the routeros devices within [Opts] - e.g., for emergency cleanup while external-dns is down.
+// As a safety measure, only a single record (identified by its exact name and type) is deleted, unmanaged records (lacking the provider's metadata) are never deleted and protected names (see [Opts.ProtectedNames], [Opts.ProtectedRegex]) are refused.
+// Returns the deleted (or, in dry-run mode, deletable) record.
+func DeleteRecord(o *Opts, do *DeleteRecordOpts) (*endpoint.Endpoint, error) {
+	l := o.Logger
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	n := normalizeDnsName(do.Name)
+	rt := strings.ToUpper(do.Type)
+	if n == "" || rt == "" {
+		return nil, fmt.Errorf("name and type of the record to delete required")
+	}
+	pc, err := NewRoutedClient(&ClientOpts{
+		Address:         o.RouterOSAddress,
+		Backend:         o.Backend,
+		CertFingerprint: o.RouterOSCertFingerprint,
+		FallbackIP:      o.RouterOSFallbackIP,
+		Logger:          l.With("name", "client"),
+		Menu:            o.RouterOSMenu,
+		MetadataStore:   o.MetadataStore,
+		Password:        o.RouterOSPassword,
+		Resolver:        o.RouterOSResolver,
+		Trace:           o.TraceRouterOS,
+		Username:        o.RouterOSUsername,
+	}, o.Routes)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewProvider(&ProviderOpts{
+		Client:         pc,
+		Logger:         l.With("name", "provider"),
+		ProtectedNames: o.ProtectedNames,
+		ProtectedRegex: o.ProtectedRegex,
+	})
+	if err != nil {
+		return nil, err
+	}
+	es, err := pc.ListEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(es, func(e *endpoint.Endpoint) bool {
+		return e.RecordType == rt && normalizeDnsName(e.DNSName) == n
+	})
+	if i == -1 {
+		return nil, fmt.Errorf("managed record %s %s not found (unmanaged records are never deleted)", rt, n)
+	}
+	e := es[i]
+	if p.isProtected(e) {
+		return nil, fmt.Errorf("record %s %s protected", rt, n)
+	}
+	if len(do.Targets) > 0 {
+		ts := []string{}
+		for _, t := range do.Targets {
+			if !slices.ContainsFunc(e.Targets, func(et string) bool { return normalizeTarget(rt, et) == normalizeTarget(rt, t) }) {
+				return nil, fmt.Errorf("record %s %s lacks target %s", rt, n, t)
+			}
+			ts = append(ts, t)
+		}
+		e.Targets = ts
+	}
+	c := pc
+	if do.DryRun {
+		c = pc.WithDryRun(func(cmd []string) {})
+	}
+	err = c.DeleteEndpoint(e)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
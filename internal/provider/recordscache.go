@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Caches the endpoints listed by [provider.Records] for a short duration - keeping frequent listings (e.g., when external-dns runs with '--events') cheap.
+// The cache is invalidated whenever the provider changes records - a listing started before the invalidation is never cached (see [recordsCache.set]).
+// Safe for concurrent use.
+type recordsCache struct {
+	endpoints  []*endpoint.Endpoint
+	generation uint64
+	mutex      sync.Mutex
+	time       time.Time
+	ttl        time.Duration
+}
+
+// Returns copies of the cached endpoints - and false if caching is disabled or the cached endpoints are missing or expired.
+// Additionally returns the cache's generation - which must be passed to [recordsCache.set] when caching a fresh listing.
+func (rc *recordsCache) get() ([]*endpoint.Endpoint, uint64, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if rc.ttl == 0 || rc.endpoints == nil || time.Since(rc.time) > rc.ttl {
+		return nil, rc.generation, false
+	}
+	es := []*endpoint.Endpoint{}
+	for _, e := range rc.endpoints {
+		es = append(es, e.DeepCopy())
+	}
+	return es, rc.generation, true
+}
+
+// Caches listed endpoints - unless the cache was invalidated since the given generation was returned by [recordsCache.get]
+func (rc *recordsCache) set(g uint64, es []*endpoint.Endpoint) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if rc.ttl == 0 || g != rc.generation {
+		return
+	}
+	rc.endpoints = []*endpoint.Endpoint{}
+	for _, e := range es {
+		rc.endpoints = append(rc.endpoints, e.DeepCopy())
+	}
+	rc.time = time.Now()
+}
+
+// Discards the cached endpoints
+func (rc *recordsCache) invalidate() {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.endpoints = nil
+	rc.generation += 1
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestRecordsCacheInvalidatedDuringListing(t *testing.T) {
+	rc := &recordsCache{ttl: time.Minute}
+	stale := []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")}
+	fresh := []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.2")}
+
+	// a listing starts - missing the cache
+	_, g, ok := rc.get()
+	if ok {
+		t.Fatal("empty cache hit")
+	}
+	// records are changed (invalidating the cache) while the listing is in flight
+	rc.invalidate()
+	// the listing (holding records prior to the change) completes - and must not be cached
+	rc.set(g, stale)
+	_, ng, ok := rc.get()
+	if ok {
+		t.Fatal("listing started before invalidation was cached")
+	}
+	if ng == g {
+		t.Fatal("generation unchanged by invalidation")
+	}
+
+	// a listing started after the invalidation is cached
+	rc.set(ng, fresh)
+	es, _, ok := rc.get()
+	if !ok {
+		t.Fatal("listing started after invalidation not cached")
+	}
+	if len(es) != 1 || es[0].Targets[0] != "10.0.0.2" {
+		t.Fatalf("cached %v, expected %v", es, fresh)
+	}
+
+	// cached endpoints are copies
+	es[0].Targets[0] = "10.0.0.3"
+	es, _, _ = rc.get()
+	if es[0].Targets[0] != "10.0.0.2" {
+		t.Errorf("cached endpoint modified via returned copy")
+	}
+}
+
+func TestRecordsCacheExpiry(t *testing.T) {
+	es := []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")}
+
+	rc := &recordsCache{}
+	_, g, _ := rc.get()
+	rc.set(g, es)
+	_, _, ok := rc.get()
+	if ok {
+		t.Error("cached with caching disabled")
+	}
+
+	rc = &recordsCache{ttl: time.Minute}
+	_, g, _ = rc.get()
+	rc.set(g, es)
+	rc.time = time.Now().Add(-2 * time.Minute)
+	_, _, ok = rc.get()
+	if ok {
+		t.Error("expired listing returned")
+	}
+}
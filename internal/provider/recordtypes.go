@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Describes how the targets of a record type map to routeros record attributes.
+// To support a new record type, add an entry to [recordTypes] (and any new attributes to [dnsRecord]).
+type recordType struct {
+	// Sets the routeros attributes representing a target on a [map[string]string] that has the same shape as a routeros ip dns record.
+	// Returns an error if the target is malformed.
+	encode func(t string, v map[string]string) error
+	// Produces the target represented by a routeros record
+	decode func(r dnsRecord) string
+	// Returns the dns name a target refers to (e.g., the target of a CNAME record) - used to order dependent records (see [endpointDependencies]).
+	// Targets don't refer to dns names when nil (or when an empty string is returned)
+	dependency func(t string) string
+	// Normalizes a target (see [normalizeTarget]) - targets are used as-is when nil
+	normalize func(t string) string
+	// Validates a target - returning the reason the target is invalid (see [validateTarget]).
+	// Targets are not validated when nil
+	validate func(t string) error
+}
+
+// Wrapped by errors describing record types missing from [recordTypes]
+var ErrUnsupportedType = errors.New("unsupported record type")
+
+// Record types supported by the provider - keyed by record type
+var recordTypes = map[string]recordType{
+	"A":     addressRecordType,
+	"AAAA":  addressRecordType,
+	"CNAME": dnsNameRecordType("cname", func(r dnsRecord) string { return r.CName }),
+	"FWD": {
+		encode: func(t string, v map[string]string) error {
+			v["forward-to"] = t
+			return nil
+		},
+		decode: func(r dnsRecord) string { return r.ForwardTo },
+		validate: func(t string) error {
+			if net.ParseIP(t) != nil {
+				return nil
+			}
+			err := validateDnsName(t)
+			if err != nil {
+				return fmt.Errorf("not an ip address or %s", err.Error())
+			}
+			return nil
+		},
+	},
+	"MX": {
+		encode: func(t string, v map[string]string) error {
+			ps := strings.Fields(t)
+			if len(ps) != 2 {
+				return fmt.Errorf("malformed mx record %s", t)
+			}
+			v["mx-preference"] = ps[0]
+			v["mx-exchange"] = ps[1]
+			return nil
+		},
+		decode: func(r dnsRecord) string {
+			return fmt.Sprintf("%s %s", r.MxPreference, r.MxExchange)
+		},
+		dependency: func(t string) string {
+			ps := strings.Fields(t)
+			if len(ps) != 2 {
+				return ""
+			}
+			return ps[1]
+		},
+		normalize: func(t string) string {
+			ps := strings.Fields(t)
+			if len(ps) != 2 {
+				return t
+			}
+			ps[1] = normalizeDnsName(ps[1])
+			return strings.Join(ps, " ")
+		},
+		validate: func(t string) error {
+			ps := strings.Fields(t)
+			if len(ps) != 2 {
+				return fmt.Errorf("not of form <preference> <exchange>")
+			}
+			err := validateUint16(ps[0])
+			if err != nil {
+				return fmt.Errorf("preference %s", err.Error())
+			}
+			err = validateDnsName(ps[1])
+			if err != nil {
+				return fmt.Errorf("exchange %s", err.Error())
+			}
+			return nil
+		},
+	},
+	"NS": dnsNameRecordType("ns", func(r dnsRecord) string { return r.Ns }),
+	"SRV": {
+		encode: func(t string, v map[string]string) error {
+			ps := strings.Fields(t)
+			if len(ps) != 4 {
+				return fmt.Errorf("malformed srv record %s", t)
+			}
+			v["srv-priority"] = ps[0]
+			v["srv-weight"] = ps[1]
+			v["srv-port"] = ps[2]
+			v["srv-target"] = ps[3]
+			return nil
+		},
+		decode: func(r dnsRecord) string {
+			return fmt.Sprintf("%s %s %s %s", r.SrvPriority, r.SrvWeight, r.SrvPort, r.SrvTarget)
+		},
+		dependency: func(t string) string {
+			ps := strings.Fields(t)
+			if len(ps) != 4 || ps[3] == "." {
+				return ""
+			}
+			return ps[3]
+		},
+		normalize: func(t string) string {
+			ps := strings.Fields(t)
+			if len(ps) != 4 {
+				return t
+			}
+			ps[3] = normalizeDnsName(ps[3])
+			return strings.Join(ps, " ")
+		},
+		validate: func(t string) error {
+			ps := strings.Fields(t)
+			if len(ps) != 4 {
+				return fmt.Errorf("not of form <priority> <weight> <port> <target>")
+			}
+			for i, n := range []string{"priority", "weight", "port"} {
+				err := validateUint16(ps[i])
+				if err != nil {
+					return fmt.Errorf("%s %s", n, err.Error())
+				}
+			}
+			if ps[3] == "." {
+				// the service is explicitly unavailable
+				return nil
+			}
+			err := validateDnsName(ps[3])
+			if err != nil {
+				return fmt.Errorf("target %s", err.Error())
+			}
+			return nil
+		},
+	},
+	"TXT": {
+		encode: func(t string, v map[string]string) error {
+			v["text"] = t
+			return nil
+		},
+		decode: func(r dnsRecord) string { return r.Text },
+	},
+}
+
+// A [recordType] whose targets are ip addresses (stored in the 'address' attribute).
+// Both A and AAAA records accept ipv4 and ipv6 addresses - see [getAddressRecordType].
+var addressRecordType = recordType{
+	encode: func(t string, v map[string]string) error {
+		v["address"] = t
+		return nil
+	},
+	decode: func(r dnsRecord) string { return r.Address },
+	normalize: func(t string) string {
+		ip := net.ParseIP(t)
+		if ip == nil {
+			return t
+		}
+		return ip.String()
+	},
+	validate: func(t string) error {
+		if net.ParseIP(t) == nil {
+			return fmt.Errorf("not an ip address")
+		}
+		return nil
+	},
+}
+
+// Creates a [recordType] whose targets are dns names stored in the given attribute
+func dnsNameRecordType(a string, decode func(r dnsRecord) string) recordType {
+	return recordType{
+		encode: func(t string, v map[string]string) error {
+			v[a] = t
+			return nil
+		},
+		decode:     decode,
+		dependency: func(t string) string { return t },
+		normalize:  normalizeDnsName,
+		validate:   validateDnsName,
+	}
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Parses a routeros address of the form '<host>:<port>' - where the host is an ip address (ipv6 addresses are bracketed - e.g., '[fd00::1]:8728') or a hostname.
+// Returns an error if the address is malformed.
+func parseRouterAddress(a string) (string, string, error) {
+	h, p, err := net.SplitHostPort(a)
+	if err != nil {
+		return "", "", fmt.Errorf("address %s not <host>:<port> format: %w", a, err)
+	}
+	pn, err := strconv.ParseUint(p, 10, 16)
+	if err != nil || pn == 0 {
+		return "", "", fmt.Errorf("address %s port invalid", a)
+	}
+	if net.ParseIP(h) == nil {
+		err := validateDnsName(h)
+		if err != nil {
+			return "", "", fmt.Errorf("address %s host not an ip address or hostname: %w", a, err)
+		}
+	}
+	return h, p, nil
+}
+
+// Creates a [net.Resolver] querying the dns server at the given address ('<host>:<port>').
+// Returns the system resolver if the address is empty.
+func newResolver(a string) (*net.Resolver, error) {
+	if a == "" {
+		return net.DefaultResolver, nil
+	}
+	h, _, err := net.SplitHostPort(a)
+	if err != nil || net.ParseIP(h) == nil {
+		// a hostname would require resolution itself
+		return nil, fmt.Errorf("resolver %s not <ip>:<port> format", a)
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, network, a)
+		},
+	}, nil
+}
+
+// Opens a tcp connection to the [client]'s router.
+// Hostnames are resolved via the client's resolver (see [ClientOpts.Resolver]) - should resolution fail, the fallback ip (see [ClientOpts.FallbackIP]) is used instead.
+// Resolved addresses are tried in order until a connection succeeds.
+// Resolution and connection attempts are abandoned once the context is done.
+func (c *client) dialRouter(co context.Context) (net.Conn, error) {
+	h, p, err := net.SplitHostPort(c.address)
+	if err != nil {
+		return nil, err
+	}
+	d := net.Dialer{}
+	if net.ParseIP(h) != nil {
+		return d.DialContext(co, "tcp", c.address)
+	}
+	as, err := c.resolver.LookupHost(co, h)
+	if err != nil {
+		if c.fallbackIP == "" {
+			return nil, fmt.Errorf("failed to resolve %s: %w", h, err)
+		}
+		c.logger.Warn(fmt.Sprintf("failed to resolve %s - using fallback ip %s: %s", h, c.fallbackIP, err.Error()))
+		as = []string{c.fallbackIP}
+	}
+	errs := []error{}
+	for _, a := range as {
+		conn, err := d.DialContext(co, "tcp", net.JoinHostPort(a, p))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
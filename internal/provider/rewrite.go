@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// A rule rewriting dns names matching [RewriteRule.Regex] - see [provider.AdjustEndpoints]
+// The replacement may reference capture groups (e.g., '$1') - see [regexp.Regexp.ReplaceAllString].
+type RewriteRule struct {
+	Regex       *regexp.Regexp
+	Replacement string
+}
+
+// Parses a rewrite rule of the form '<regex>=<replacement>' (e.g., '^(.+)\.svc\.cluster\.local$=$1.site-a.lan')
+func ParseRewriteRule(s string) (RewriteRule, error) {
+	rs, r, ok := strings.Cut(s, "=")
+	if !ok {
+		return RewriteRule{}, fmt.Errorf("rewrite rule %s not of form <regex>=<replacement>", s)
+	}
+	re, err := regexp.Compile(rs)
+	if err != nil {
+		return RewriteRule{}, err
+	}
+	return RewriteRule{Regex: re, Replacement: r}, nil
+}
+
+// Rewrites the endpoint's dns name by applying each rule, in order, to the result of the previous rule
+func rewriteEndpoint(e *endpoint.Endpoint, rs []RewriteRule) {
+	for _, r := range rs {
+		e.DNSName = r.Regex.ReplaceAllString(e.DNSName, r.Replacement)
+	}
+	normalizeEndpoint(e)
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// The routeros user group policies required by the provider.
+// 'api' permits logging in via the api, 'read' permits listing records (and the router's identity) and 'write' permits changing records.
+var routerConfigPolicies = []string{"api", "read", "write"}
+
+// Options used when generating routeros configuration via [GenerateRouterConfig]
+type RouterConfigOpts struct {
+	// Restricts the user to connections from the given address or cidr (e.g., that of the cluster's nodes) - unrestricted when empty
+	Address string
+	// The name of the user group - defaults to 'external-dns'
+	Group string
+	// The user's password - a random password is generated when empty
+	Password string
+	// The name of the user - defaults to 'external-dns'
+	Username string
+}
+
+// Quotes a value for use within a routeros command - escaping characters routeros interprets within quoted strings
+func quoteRouterValue(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return fmt.Sprintf(`"%s"`, r.Replace(v))
+}
+
+// Generates the routeros commands creating a least-privilege user for the provider - a user group limited to the policies the provider requires (see [routerConfigPolicies]) and a user within it.
+// Returns an error if the options are invalid.
+func GenerateRouterConfig(o *RouterConfigOpts) ([]string, error) {
+	g := o.Group
+	if g == "" {
+		g = "external-dns"
+	}
+	u := o.Username
+	if u == "" {
+		u = "external-dns"
+	}
+	pw := o.Password
+	if pw == "" {
+		b := make([]byte, 16)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate password: %w", err)
+		}
+		pw = hex.EncodeToString(b)
+	}
+	if o.Address != "" && net.ParseIP(o.Address) == nil {
+		_, _, err := net.ParseCIDR(o.Address)
+		if err != nil {
+			return nil, fmt.Errorf("address %s is neither an ip address nor a cidr", o.Address)
+		}
+	}
+
+	c := quoteRouterValue("external-dns-routeros-provider")
+	ua := fmt.Sprintf("/user add name=%s group=%s password=%s comment=%s", quoteRouterValue(u), quoteRouterValue(g), quoteRouterValue(pw), c)
+	if o.Address != "" {
+		ua += fmt.Sprintf(" address=%s", o.Address)
+	}
+	return []string{
+		fmt.Sprintf("/user group add name=%s policy=%s comment=%s", quoteRouterValue(g), strings.Join(routerConfigPolicies, ","), c),
+		ua,
+	}, nil
+}
@@ -0,0 +1,383 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Configuration of a routing rule - sending records whose names match its filter to a different router.
+// Records matching no route are sent to the default router.
+// Fields that are set override their equivalent [ClientOpts] fields.
+type RouteConfig struct {
+	FilterExclude           []string `yaml:"filterExclude"`
+	FilterInclude           []string `yaml:"filterInclude"`
+	FilterRegexExclude      string   `yaml:"filterRegexExclude"`
+	FilterRegexInclude      string   `yaml:"filterRegexInclude"`
+	Name                    string   `yaml:"name"`
+	RouterOSAddress         string   `yaml:"routerosAddress"`
+	RouterOSCertFingerprint string   `yaml:"routerosCertFingerprint"`
+	RouterOSFallbackIP      string   `yaml:"routerosFallbackIp"`
+	RouterOSMenu            string   `yaml:"routerosMenu"`
+	RouterOSPassword        string   `yaml:"routerosPassword"`
+	RouterOSUsername        string   `yaml:"routerosUsername"`
+}
+
+// The shape of the yaml file read by [ReadRoutesFile]
+type routesFile struct {
+	Routes []RouteConfig `yaml:"routes"`
+}
+
+// Reads a list of [RouteConfig] from the yaml file at the given path.
+// Returns an error if the file cannot be read or parsed.
+func ReadRoutesFile(p string) ([]RouteConfig, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		return []RouteConfig{}, err
+	}
+	f := routesFile{}
+	err = yaml.UnmarshalStrict(d, &f)
+	if err != nil {
+		return []RouteConfig{}, fmt.Errorf("routes file %s invalid: %w", p, err)
+	}
+	return f.Routes, nil
+}
+
+// Returns the domain filter selecting the records sent to the route's router.
+// Returns an error if the route has no filter or if a regex filter fails to compile.
+func (rc RouteConfig) domainFilter() (endpoint.DomainFilter, error) {
+	if rc.FilterRegexExclude != "" || rc.FilterRegexInclude != "" {
+		var fre, fri *regexp.Regexp
+		var err error
+		if rc.FilterRegexExclude != "" {
+			fre, err = regexp.Compile(rc.FilterRegexExclude)
+			if err != nil {
+				return endpoint.DomainFilter{}, err
+			}
+		}
+		if rc.FilterRegexInclude != "" {
+			fri, err = regexp.Compile(rc.FilterRegexInclude)
+			if err != nil {
+				return endpoint.DomainFilter{}, err
+			}
+		}
+		return endpoint.NewRegexDomainFilter(fri, fre), nil
+	}
+	if len(rc.FilterInclude) == 0 {
+		// an empty include filter matches every record - hiding the default router
+		return endpoint.DomainFilter{}, fmt.Errorf("filter unset")
+	}
+	return endpoint.NewDomainFilterWithExclusions(rc.FilterInclude, rc.FilterExclude), nil
+}
+
+// Returns a copy of the provided [ClientOpts] overridden by fields set within the [RouteConfig].
+// As the fallback ip is specific to a router, it isn't inherited by routes overriding the address.
+func (rc RouteConfig) apply(o ClientOpts) ClientOpts {
+	if rc.RouterOSAddress != "" {
+		o.Address = rc.RouterOSAddress
+		o.FallbackIP = ""
+	}
+	if rc.RouterOSCertFingerprint != "" {
+		o.CertFingerprint = rc.RouterOSCertFingerprint
+	}
+	if rc.RouterOSFallbackIP != "" {
+		o.FallbackIP = rc.RouterOSFallbackIP
+	}
+	if rc.RouterOSMenu != "" {
+		o.Menu = rc.RouterOSMenu
+	}
+	if rc.RouterOSPassword != "" {
+		o.Password = rc.RouterOSPassword
+	}
+	if rc.RouterOSUsername != "" {
+		o.Username = rc.RouterOSUsername
+	}
+	return o
+}
+
+// A [Client] dispatching records to one of several routers by name.
+// Each record is sent to the router of the route it names (see [providerSpecificRoute]) - otherwise, to the router of the first route whose filter matches its name, or to the default router (the last client).
+type routedClient struct {
+	clients []Client
+	filters []endpoint.DomainFilter
+	names   []string
+}
+
+// Creates a [Client] for the router within the provided [ClientOpts] and for each [RouteConfig].
+// Returns the default router's [client] if no routes are configured.
+// Returns an error if route names are missing or duplicated, if routes share a router, or if a client fails to initialize.
+func NewRoutedClient(o *ClientOpts, rcs []RouteConfig) (Client, error) {
+	dc, err := NewClient(o)
+	if err != nil {
+		return nil, err
+	}
+	if len(rcs) == 0 {
+		return dc, nil
+	}
+	l := o.Logger
+	if l == nil {
+		l = dc.logger
+	}
+	rc := routedClient{clients: []Client{}, filters: []endpoint.DomainFilter{}, names: []string{}}
+	as := map[string]string{o.Address: "default"}
+	for _, r := range rcs {
+		if r.Name == "" {
+			return nil, fmt.Errorf("route name unset")
+		}
+		if r.Name == "default" {
+			return nil, fmt.Errorf("route name %s reserved", r.Name)
+		}
+		for _, n := range rc.names {
+			if n == r.Name {
+				return nil, fmt.Errorf("route %s defined multiple times", r.Name)
+			}
+		}
+		df, err := r.domainFilter()
+		if err != nil {
+			return nil, fmt.Errorf("route %s invalid: %w", r.Name, err)
+		}
+		co := r.apply(*o)
+		co.Logger = l.With("route", r.Name)
+		// journal entries identify the router (see [journalEntry]) that undoes them by address
+		rn, ok := as[co.Address]
+		if ok {
+			return nil, fmt.Errorf("routes %s and %s share router %s", rn, r.Name, co.Address)
+		}
+		as[co.Address] = r.Name
+		c, err := NewClient(&co)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", r.Name, err)
+		}
+		rc.clients = append(rc.clients, c)
+		rc.filters = append(rc.filters, df)
+		rc.names = append(rc.names, r.Name)
+	}
+	rc.clients = append(rc.clients, dc)
+	rc.names = append(rc.names, "default")
+	return &rc, nil
+}
+
+// Returns the index of the client that the record with the given name is sent to
+func (rc *routedClient) route(n string) int {
+	n = normalizeDnsName(n)
+	for i, f := range rc.filters {
+		if f.Match(n) {
+			return i
+		}
+	}
+	return len(rc.clients) - 1
+}
+
+// Returns the index of the client that the endpoint is sent to - the route named by the endpoint (see [providerSpecificRoute]), otherwise the route its name is routed to (see [routedClient.route]).
+// Returns an error (alongside the route its name is routed to) if the endpoint names an unknown route.
+func (rc *routedClient) routeEndpoint(e *endpoint.Endpoint) (int, error) {
+	r, ok := e.GetProviderSpecificProperty(providerSpecificRoute)
+	if !ok || r == "" {
+		return rc.route(e.DNSName), nil
+	}
+	i := slices.Index(rc.names, r)
+	if i == -1 {
+		return rc.route(e.DNSName), fmt.Errorf("route %s unknown (expected one of %s)", r, strings.Join(rc.names, ", "))
+	}
+	return i, nil
+}
+
+// Returns a copy of the [routedClient] with each of its clients transformed by the callback
+func (rc *routedClient) with(cb func(c Client) Client) Client {
+	cc := *rc
+	cc.clients = []Client{}
+	for _, c := range rc.clients {
+		cc.clients = append(cc.clients, cb(c))
+	}
+	return &cc
+}
+
+// Performs a health check of each router (see [client.Health]) - returning an error if any router is unhealthy.
+// The report describes the default router - the reports of other routers are keyed by route name (see [HealthReport.Routes]).
+func (rc *routedClient) Health(co context.Context) (HealthReport, error) {
+	hr := HealthReport{}
+	rhrs := map[string]HealthReport{}
+	errs := []error{}
+	for i, c := range rc.clients {
+		chr, err := c.Health(co)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", rc.names[i], err))
+		}
+		if i == len(rc.clients)-1 {
+			hr = chr
+			continue
+		}
+		rhrs[rc.names[i]] = chr
+	}
+	hr.Routes = rhrs
+	return hr, errors.Join(errs...)
+}
+
+// Checks that every router supports its client's backend (see [client.CheckBackend])
+func (rc *routedClient) CheckBackend() error {
+	errs := []error{}
+	for i, c := range rc.clients {
+		err := c.CheckBackend()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", rc.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Queries the default router for details describing the device (see [client.Info])
+func (rc *routedClient) Info() (RouterInfo, error) {
+	return rc.clients[len(rc.clients)-1].Info()
+}
+
+// Lists the endpoints of every router - merging them into a single list.
+// Endpoints found on a router that they are no longer routed to (see [routedClient.routeEndpoint]) - e.g., following a change to a route's filter - are attributed to the router holding them (via [providerSpecificRoute]).
+// As the attributed route differs from that of the desired endpoint, external-dns updates (moving the endpoint - see [isRouteChange]) or deletes the endpoint on the router holding it.
+func (rc *routedClient) ListEndpoints() ([]*endpoint.Endpoint, error) {
+	es := []*endpoint.Endpoint{}
+	for i, c := range rc.clients {
+		ces, err := c.ListEndpoints()
+		if err != nil {
+			return []*endpoint.Endpoint{}, fmt.Errorf("route %s: %w", rc.names[i], err)
+		}
+		for _, e := range ces {
+			ri, err := rc.routeEndpoint(e)
+			if err != nil || ri != i {
+				e.SetProviderSpecificProperty(providerSpecificRoute, rc.names[i])
+			}
+			es = append(es, e)
+		}
+	}
+	return es, nil
+}
+
+// Creates the endpoint on the router it is routed to (see [routedClient.routeEndpoint]).
+// Returns an error if the endpoint names an unknown route.
+func (rc *routedClient) CreateEndpoint(e *endpoint.Endpoint) error {
+	i, err := rc.routeEndpoint(e)
+	if err != nil {
+		return err
+	}
+	return rc.clients[i].CreateEndpoint(e)
+}
+
+// Creates or updates the endpoint (see [client.CreateOrUpdateEndpoint]) on the router it is routed to (see [routedClient.routeEndpoint]).
+// Updates moving the endpoint to a different router delete the endpoint it replaces from the router holding it separately (see [isRouteChange]).
+// Returns an error if the endpoint names an unknown route.
+func (rc *routedClient) CreateOrUpdateEndpoint(e *endpoint.Endpoint) error {
+	i, err := rc.routeEndpoint(e)
+	if err != nil {
+		return err
+	}
+	return rc.clients[i].CreateOrUpdateEndpoint(e)
+}
+
+// Returns whether an update changes the route (see [providerSpecificRoute]) of an endpoint - moving its records to a different router.
+// Listed endpoints name the route of the router holding them when their name is routed elsewhere (see [routedClient.ListEndpoints]) - the listing external-dns plans its updates against.
+func isRouteChange(oe *endpoint.Endpoint, ne *endpoint.Endpoint) bool {
+	or, _ := oe.GetProviderSpecificProperty(providerSpecificRoute)
+	nr, _ := ne.GetProviderSpecificProperty(providerSpecificRoute)
+	return or != nr
+}
+
+// Deletes the endpoint from the router it is routed to (see [routedClient.routeEndpoint])
+func (rc *routedClient) DeleteEndpoint(e *endpoint.Endpoint) error {
+	i, _ := rc.routeEndpoint(e)
+	return rc.clients[i].DeleteEndpoint(e)
+}
+
+// Deletes the endpoints from the routers they are routed to (see [routedClient.routeEndpoint]) - via a single api call per router (see [client.DeleteEndpoints])
+func (rc *routedClient) DeleteEndpoints(es []*endpoint.Endpoint) error {
+	ess := make([][]*endpoint.Endpoint, len(rc.clients))
+	for _, e := range es {
+		i, _ := rc.routeEndpoint(e)
+		ess[i] = append(ess[i], e)
+	}
+	for i, c := range rc.clients {
+		if len(ess[i]) == 0 {
+			continue
+		}
+		err := c.DeleteEndpoints(ess[i])
+		if err != nil {
+			return fmt.Errorf("route %s: %w", rc.names[i], err)
+		}
+	}
+	return nil
+}
+
+// Adopts matched records (see [client.AdoptRecords]) on the router their names are routed to
+func (rc *routedClient) AdoptRecords(match AdoptMatchFunc) ([]*endpoint.Endpoint, error) {
+	es := []*endpoint.Endpoint{}
+	for i, c := range rc.clients {
+		ces, err := c.AdoptRecords(func(n string) bool {
+			return rc.route(n) == i && match(n)
+		})
+		es = append(es, ces...)
+		if err != nil {
+			return es, fmt.Errorf("route %s: %w", rc.names[i], err)
+		}
+	}
+	return es, nil
+}
+
+// Checks the integrity of the records of every router (see [client.CheckIntegrity])
+func (rc *routedClient) CheckIntegrity(repair bool) ([]*endpoint.Endpoint, error) {
+	es := []*endpoint.Endpoint{}
+	for i, c := range rc.clients {
+		ces, err := c.CheckIntegrity(repair)
+		es = append(es, ces...)
+		if err != nil {
+			return es, fmt.Errorf("route %s: %w", rc.names[i], err)
+		}
+	}
+	return es, nil
+}
+
+// Undoes the changes recorded within the [Journal] on every router (see [client.Rollback])
+func (rc *routedClient) Rollback(j *Journal) error {
+	errs := []error{}
+	for i, c := range rc.clients {
+		err := c.Rollback(j)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("route %s: %w", rc.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Returns a copy of the [routedClient] whose clients record changes to the given [Journal]
+func (rc *routedClient) WithJournal(j *Journal) Client {
+	return rc.with(func(c Client) Client { return c.WithJournal(j) })
+}
+
+// Returns a copy of the [routedClient] whose clients are in dry-run mode (see [client.WithDryRun])
+func (rc *routedClient) WithDryRun(cb DryRunCallback) Client {
+	return rc.with(func(c Client) Client { return c.WithDryRun(cb) })
+}
+
+// Returns a copy of the [routedClient] whose clients log with the provided attributes
+func (rc *routedClient) WithLogAttrs(args ...any) Client {
+	return rc.with(func(c Client) Client { return c.WithLogAttrs(args...) })
+}
+
+// Returns a copy of the [routedClient] whose clients log using the provided logger - retaining each client's route
+func (rc *routedClient) WithLogger(l *slog.Logger) Client {
+	cc := *rc
+	cc.clients = []Client{}
+	for i, c := range rc.clients {
+		cl := l
+		if i != len(rc.clients)-1 {
+			cl = l.With("route", rc.names[i])
+		}
+		cc.clients = append(cc.clients, c.WithLogger(cl))
+	}
+	return &cc
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestRoutedClientListEndpoints(t *testing.T) {
+	lc := newStubClient(t, ClientOpts{}, newStubRouter())
+	dc := newStubClient(t, ClientOpts{}, newStubRouter())
+	rc := &routedClient{
+		clients: []Client{lc, dc},
+		filters: []endpoint.DomainFilter{endpoint.NewDomainFilter([]string{"lan.example.com"})},
+		names:   []string{"lan", "default"},
+	}
+	// created directly on each router - bypassing routing (e.g., prior to a change of the route's filter)
+	for _, c := range []struct {
+		client *client
+		name   string
+	}{
+		{client: lc, name: "a.lan.example.com"},
+		{client: lc, name: "b.example.com"},
+		{client: dc, name: "c.example.com"},
+		{client: dc, name: "d.lan.example.com"},
+	} {
+		err := c.client.CreateEndpoint(endpoint.NewEndpoint(c.name, endpoint.RecordTypeA, "10.0.0.1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	es, err := rc.ListEndpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"a.lan.example.com": "",
+		"b.example.com":     "lan",
+		"c.example.com":     "",
+		"d.lan.example.com": "default",
+	}
+	if len(es) != len(expected) {
+		t.Fatalf("listed %d endpoints, expected %d", len(es), len(expected))
+	}
+	for _, e := range es {
+		r, _ := e.GetProviderSpecificProperty(providerSpecificRoute)
+		er, ok := expected[e.DNSName]
+		if !ok {
+			t.Errorf("unexpected endpoint %s", e.DNSName)
+			continue
+		}
+		if r != er {
+			t.Errorf("endpoint %s attributed to route %q, expected %q", e.DNSName, r, er)
+		}
+	}
+
+	// deleting an endpoint attributed to a route deletes it from the router holding it
+	for _, e := range es {
+		if e.DNSName == "b.example.com" {
+			err = rc.DeleteEndpoint(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	les, err := lc.ListEndpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(les) != 1 || les[0].DNSName != "a.lan.example.com" {
+		t.Errorf("route lan holds %v, expected a.lan.example.com", les)
+	}
+}
+
+func TestRoutedClientApplyMoves(t *testing.T) {
+	lr := newStubRouter()
+	dr := newStubRouter()
+	lc := newStubClient(t, ClientOpts{}, lr)
+	dc := newStubClient(t, ClientOpts{}, dr)
+	rc := &routedClient{
+		clients: []Client{lc, dc},
+		filters: []endpoint.DomainFilter{endpoint.NewDomainFilter([]string{"lan.example.com"})},
+		names:   []string{"lan", "default"},
+	}
+	p, err := NewProvider(&ProviderOpts{Client: rc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b.example.com is held by the lan router (e.g., prior to a change of the route's filter)
+	err = lc.CreateEndpoint(endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dc.CreateEndpoint(endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := rc.ListEndpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oes := map[string]*endpoint.Endpoint{}
+	for _, e := range es {
+		oes[e.DNSName] = e
+	}
+
+	// updates that don't change the route leave other routers untouched
+	lcs := lr.commands
+	err = p.ApplyChanges(context.Background(), &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{oes["c.example.com"]},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "10.0.0.2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lr.commands != lcs {
+		t.Errorf("route lan received %d commands, expected none", lr.commands-lcs)
+	}
+
+	// updates that change the route move the endpoint
+	err = p.ApplyChanges(context.Background(), &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{oes["b.example.com"]},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		client   *client
+		expected []string
+	}{
+		{client: lc, expected: []string{}},
+		{client: dc, expected: []string{"b.example.com", "c.example.com"}},
+	} {
+		ces, err := test.client.ListEndpoints()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ns := []string{}
+		for _, e := range ces {
+			ns = append(ns, e.DNSName)
+		}
+		slices.Sort(ns)
+		if !slices.Equal(ns, test.expected) {
+			t.Errorf("router holds %v, expected %v", ns, test.expected)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Options used when running a self-test via [SelfTest]
+type SelfTestOpts struct {
+	// Domain beneath which the test record is created - defaults to 'local'
+	Domain string
+	// How long the test record is retried until it resolves - defaults to 10s
+	Timeout time.Duration
+}
+
+// A completed step of a self-test - see [SelfTest]
+type SelfTestStep struct {
+	Duration time.Duration
+	Error    error
+	Name     string
+}
+
+// Verifies the full write/read/resolve path against the routeros device within [Opts] - e.g., after a router upgrade.
+// Creates a uniquely named A record, lists it, resolves it via routeros' dns server (see [verifier]) and deletes it.
+// The test record is deleted even if a prior step fails.
+// Returns the steps performed - and an error if any step fails.
+func SelfTest(o *Opts, so *SelfTestOpts) ([]SelfTestStep, error) {
+	l := o.Logger
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	d := so.Domain
+	if d == "" {
+		d = "local"
+	}
+	t := so.Timeout
+	if t == 0 {
+		t = 10 * time.Second
+	}
+	c, err := NewClient(&ClientOpts{
+		Address:         o.RouterOSAddress,
+		Backend:         o.Backend,
+		CertFingerprint: o.RouterOSCertFingerprint,
+		FallbackIP:      o.RouterOSFallbackIP,
+		Logger:          l.With("name", "client"),
+		Menu:            o.RouterOSMenu,
+		MetadataStore:   o.MetadataStore,
+		Password:        o.RouterOSPassword,
+		Resolver:        o.RouterOSResolver,
+		Trace:           o.TraceRouterOS,
+		Username:        o.RouterOSUsername,
+	})
+	if err != nil {
+		return []SelfTestStep{}, err
+	}
+	v := newVerifier(VerifyOpts{Address: o.verifyAddress(), Window: t}, l.With("name", "verifier"))
+
+	// the target is within a documentation-only address range (see RFC 5737)
+	e := endpoint.NewEndpoint(fmt.Sprintf("selftest-%x.%s", time.Now().UnixNano(), d), "A", "192.0.2.1")
+	sts := []SelfTestStep{}
+	step := func(n string, cb func() error) error {
+		l.Info(fmt.Sprintf("self-test: %s record %s", n, e.DNSName))
+		st := time.Now()
+		err := cb()
+		sts = append(sts, SelfTestStep{Duration: time.Since(st), Error: err, Name: n})
+		if err != nil {
+			return fmt.Errorf("self-test step %s failed: %w", n, err)
+		}
+		return nil
+	}
+	listed := func(p bool) func() error {
+		return func() error {
+			es, err := c.ListEndpoints()
+			if err != nil {
+				return err
+			}
+			f := false
+			for _, le := range es {
+				if le.RecordType == e.RecordType && normalizeDnsName(le.DNSName) == e.DNSName {
+					f = true
+				}
+			}
+			if f != p {
+				return fmt.Errorf("record listed: %t, expected: %t", f, p)
+			}
+			return nil
+		}
+	}
+
+	err = step("create", func() error { return c.CreateEndpoint(e) })
+	if err != nil {
+		return sts, err
+	}
+	deleted := false
+	defer func() {
+		if deleted {
+			return
+		}
+		l.Info(fmt.Sprintf("self-test: cleaning up record %s", e.DNSName))
+		err := c.DeleteEndpoint(e)
+		if err != nil {
+			l.Warn(fmt.Sprintf("failed to clean up self-test record %s: %s", e.DNSName, err.Error()))
+		}
+	}()
+	err = step("list", listed(true))
+	if err != nil {
+		return sts, err
+	}
+	err = step("resolve", func() error {
+		return v.Verify([]*endpoint.Endpoint{e})[e]
+	})
+	if err != nil {
+		return sts, err
+	}
+	err = step("delete", func() error { return c.DeleteEndpoint(e) })
+	if err != nil {
+		return sts, err
+	}
+	deleted = true
+	err = step("list-deleted", listed(false))
+	if err != nil {
+		return sts, err
+	}
+	return sts, nil
+}
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Options describing how a zone serial is bumped after a sync changes records - see [Client.BumpSerial]
+type SerialOpts struct {
+	// Name of a TXT record holding the serial - created if absent
+	Record string
+	// Name of a routeros script (see '/system/script') run after the serial is bumped
+	Script string
+}
+
+// Whether bumping a serial has been configured
+func (so SerialOpts) enabled() bool {
+	return so.Record != "" || so.Script != ""
+}
+
+// Returns the serial following the current serial in the date-based 'YYYYMMDDnn' format (see RFC 1912).
+// The returned serial is always greater than the current serial - once a day's 100 revisions are exhausted, serials borrow from the following day.
+// Current serials that aren't numeric are ignored.
+func nextSerial(cur string, now time.Time) string {
+	y, m, d := now.UTC().Date()
+	s := uint64(y)*1000000 + uint64(m)*10000 + uint64(d)*100
+	cs, err := strconv.ParseUint(cur, 10, 64)
+	if err == nil && cs >= s {
+		s = cs + 1
+	}
+	return strconv.FormatUint(s, 10)
+}
+
+// Bumps the zone serial (see [SerialOpts]) - returning the new serial (if a serial record is configured).
+// The serial record is not managed by external-dns (it lacks metadata) - it is written directly via the [client]'s [recordBackend] and is not journaled.
+// Returns an error if any api call fails.
+func (c *client) BumpSerial(o SerialOpts) (string, error) {
+	s := ""
+	err := c.withClient(func() error {
+		if o.Record != "" {
+			n := normalizeDnsName(o.Record)
+			rs, err := c.backend.List(c.run)
+			if err != nil {
+				return err
+			}
+			var sr *dnsRecord
+			for _, r := range rs {
+				if r.Type == "TXT" && normalizeDnsName(r.Name) == n {
+					sr = &r
+					break
+				}
+			}
+			if sr == nil {
+				s = nextSerial("", time.Now())
+				c.logger.Debug(fmt.Sprintf("create serial record %s (%s)", n, s))
+				_, err = c.backend.Create(c.run, map[string]string{"name": n, "text": s, "type": "TXT"})
+			} else {
+				s = nextSerial(sr.Text, time.Now())
+				c.logger.Debug(fmt.Sprintf("update serial record %s (%s -> %s)", n, sr.Text, s))
+				err = c.backend.Update(c.run, sr.Id, map[string]string{"text": s})
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if o.Script != "" {
+			c.logger.Debug(fmt.Sprintf("run script %s", o.Script))
+			attr, err := makeAttributeWord("number", o.Script)
+			if err != nil {
+				return err
+			}
+			_, err = c.run([]string{"/system/script/run", attr})
+			if err != nil {
+				return fmt.Errorf("script %s failed: %w", o.Script, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// Bumps the zone serial (see [client.BumpSerial]) on every router - returning the serial of the default router
+func (rc *routedClient) BumpSerial(o SerialOpts) (string, error) {
+	s := ""
+	for i, c := range rc.clients {
+		cs, err := c.BumpSerial(o)
+		if err != nil {
+			return "", fmt.Errorf("route %s: %w", rc.names[i], err)
+		}
+		s = cs
+	}
+	return s, nil
+}
@@ -0,0 +1,30 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextSerial(t *testing.T) {
+	now := time.Date(2024, time.March, 5, 23, 0, 0, 0, time.FixedZone("", -2*60*60))
+	tests := []struct {
+		name     string
+		current  string
+		expected string
+	}{
+		{name: "absent", current: "", expected: "2024030600"},
+		{name: "non-numeric", current: "serial", expected: "2024030600"},
+		{name: "older", current: "2024030499", expected: "2024030600"},
+		{name: "same day", current: "2024030600", expected: "2024030601"},
+		{name: "exhausted", current: "2024030699", expected: "2024030700"},
+		{name: "newer", current: "2025010100", expected: "2025010101"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := nextSerial(test.current, now)
+			if actual != test.expected {
+				t.Errorf("nextSerial(%q) = %s, expected %s", test.current, actual, test.expected)
+			}
+		})
+	}
+}
@@ -2,11 +2,22 @@ package provider
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	slogecho "github.com/samber/slog-echo"
@@ -16,13 +27,27 @@ import (
 
 // Internal server data struct that binds a [Provider] to endpoint functions
 type server struct {
-	echo     *echo.Echo
-	host     string
-	logger   *slog.Logger
-	port     uint
-	provider Provider
+	echo             *echo.Echo
+	host             string
+	internal         *echo.Echo
+	internalHost     string
+	internalListener net.Listener
+	internalPort     uint
+	listener         net.Listener
+	logger           *slog.Logger
+	logLevel         *LogLevel
+	maintenance      atomic.Bool
+	onReady          ReadyCallback
+	port             uint
+	probeFiles       ProbeFileOpts
+	provider         Provider
+	standby          bool
 }
 
+// Callback invoked once the [server] is listening for connections.
+// Receives the address the server is bound to.
+type ReadyCallback func(a net.Addr)
+
 // Function that handles parsing a request into the given [interface{}] object
 type requestReader func(data interface{}) error
 
@@ -47,16 +72,90 @@ func (s *server) getRequestReader(c echo.Context) (requestReader, error) {
 // Function that handles creating a response with the given data and HTTP status code
 type responseWriter func(code int, data interface{}) error
 
-// Uses a request's 'Accept' header to produce a matching [responseWriter] function.
+// The media type of webhook (rather than plain json) responses
+const webhookMediaType = "application/external.dns.webhook+json;version=1"
+
+// A media type listed within a request's 'Accept' header - see [parseAccept]
+type acceptedMediaType struct {
+	mediaType string
+	params    map[string]string
+	quality   float64
+}
+
+// Parses the media types listed within a request's 'Accept' header (see [mime.ParseMediaType]) - whitespace and parameters (e.g., 'application/json; charset=utf-8') are tolerated.
+// Media types are returned in the order listed - each with its quality ('q') value (defaulting to 1).
+// Malformed media types (and those with invalid quality values) are skipped.
+func parseAccept(h string) []acceptedMediaType {
+	amts := []acceptedMediaType{}
+	for _, v := range strings.Split(h, ",") {
+		mt, ps, err := mime.ParseMediaType(v)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		qs, ok := ps["q"]
+		if ok {
+			q, err = strconv.ParseFloat(qs, 64)
+			if err != nil {
+				continue
+			}
+		}
+		amts = append(amts, acceptedMediaType{mediaType: mt, params: ps, quality: q})
+	}
+	return amts
+}
+
+// Returns whether a request's 'Accept' header accepts the given media type (see [parseAccept]) - i.e., lists it with a non-zero quality value.
+func acceptsMediaType(h string, mt string) bool {
+	for _, amt := range parseAccept(h) {
+		if amt.mediaType == mt && amt.quality > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Selects the media type of a response from a request's 'Accept' header (see [parseAccept]) - the recognized media type with the highest quality ('q') value, preferring those listed first.
+// Defaults to 'application/json' when the header is absent or accepts any media type (e.g., '*/*', as sent by curl).
+// Returns the header as-is when no media type is recognized.
+func negotiateMediaType(h string) string {
+	if strings.TrimSpace(h) == "" {
+		return "application/json"
+	}
+	smt := ""
+	sq := 0.0
+	for _, amt := range parseAccept(h) {
+		rmt := ""
+		switch amt.mediaType {
+		case "application/external.dns.webhook+json":
+			if amt.params["version"] == "1" {
+				rmt = webhookMediaType
+			}
+		case "*/*", "application/*", "application/json":
+			rmt = "application/json"
+		}
+		if rmt == "" || amt.quality <= sq {
+			continue
+		}
+		smt = rmt
+		sq = amt.quality
+	}
+	if smt == "" {
+		return h
+	}
+	return smt
+}
+
+// Uses a request's 'Accept' header to produce a matching [responseWriter] function (see [negotiateMediaType]).
 // Raises an [error] if the 'Accept' header is unrecognized.
 func (s *server) getResponseWriter(c echo.Context) (responseWriter, error) {
-	value := c.Request().Header.Get("Accept")
+	value := negotiateMediaType(c.Request().Header.Get("Accept"))
 	switch value {
 	case "application/json":
 		return c.JSON, nil
-	case "application/external.dns.webhook+json;version=1":
+	case webhookMediaType:
 		return func(code int, data interface{}) error {
-			c.Response().Header().Set(echo.HeaderContentType, "application/external.dns.webhook+json;version=1")
+			c.Response().Header().Set(echo.HeaderContentType, webhookMediaType)
 			c.Response().WriteHeader(http.StatusOK)
 			bs, err := json.Marshal(data)
 			if err != nil {
@@ -92,66 +191,435 @@ func (s *server) adjustEndpoints(c echo.Context) error {
 	return rw(http.StatusOK, es)
 }
 
-// Webhook endpoint function calling [Provider.ApplyChanges]
-func (s *server) applyChanges(c echo.Context) error {
+// The body of the 400 response to a webhook request whose changes are invalid (see [validateChanges])
+type invalidChangesResponse struct {
+	Errors  []FieldError `json:"errors"`
+	Message string       `json:"message"`
+}
+
+// Reads and validates (see [validateChanges]) the changes within a webhook request's body.
+// Returns a 400 [echo.HTTPError] listing each invalid field if the changes are invalid.
+func (s *server) readChanges(c echo.Context) (*plan.Changes, error) {
 	rr, err := s.getRequestReader(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	body := plan.Changes{}
 	err = rr(&body)
 	if err != nil {
-		return err
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("body invalid: %s", err.Error())).SetInternal(err)
+	}
+	fes := validateChanges(&body)
+	if len(fes) > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, invalidChangesResponse{Errors: fes, Message: fmt.Sprintf("%d invalid fields", len(fes))})
+	}
+	return &body, nil
+}
+
+// Media type requested (via the 'Accept' header) by clients wanting the outcome of each change from [server.applyChanges]
+const mediaTypeApplyResults = "application/vnd.external-dns-routeros.results+json"
+
+// The body of a [server.applyChanges] response listing the outcome of each change
+type applyResultsResponse struct {
+	ApplyStatus
+	// Set when the sync failed
+	Message string `json:"message,omitempty"`
+}
+
+// Webhook endpoint function calling [Provider.ApplyChanges]
+// Responds with 204 - or, when the 'Accept' header accepts [mediaTypeApplyResults] (see [acceptsMediaType]), with 200 and the outcome of the sync (including each change - see [Provider.ApplyChangesWithStatus]).
+// External-dns doesn't request [mediaTypeApplyResults] - and always receives 204.
+// Responds with 400 if the changes are invalid (see [server.readChanges]).
+// Responds with 503 (and a 'Retry-After' header) while the [server] is in maintenance mode.
+func (s *server) applyChanges(c echo.Context) error {
+	if s.maintenance.Load() {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "provider in maintenance mode")
 	}
-	err = s.provider.ApplyChanges(context.Background(), &body)
+	body, err := s.readChanges(c)
 	if err != nil {
 		return err
 	}
+	if acceptsMediaType(c.Request().Header.Get("Accept"), mediaTypeApplyResults) {
+		as, err := s.provider.ApplyChangesWithStatus(c.Request().Context(), body)
+		r := applyResultsResponse{ApplyStatus: as}
+		code := http.StatusOK
+		if err != nil {
+			r.Message = err.Error()
+			code = http.StatusInternalServerError
+		}
+		c.Response().Header().Set(echo.HeaderContentType, mediaTypeApplyResults)
+		return c.JSON(code, r)
+	}
+	err = s.provider.ApplyChanges(c.Request().Context(), body)
+	if err != nil {
+		// the error (e.g., listing each failed record) is returned within the response body
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+	}
 	return c.NoContent(http.StatusNoContent)
 }
 
+// Endpoint function calling [Provider.Simulate]
+// Accepts the same body as [server.applyChanges] - responding with the routeros api commands that would be executed.
+func (s *server) simulate(c echo.Context) error {
+	body, err := s.readChanges(c)
+	if err != nil {
+		return err
+	}
+	sr, err := s.provider.Simulate(c.Request().Context(), body)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, sr)
+}
+
+// Endpoint function calling [Provider.Resync]
+// Responds with 503 (and a 'Retry-After' header) while the [server] is in maintenance mode - as resyncing may repair (i.e., delete) records.
+func (s *server) resync(c echo.Context) error {
+	if s.maintenance.Load() {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "provider in maintenance mode")
+	}
+	rr, err := s.provider.Resync(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rr)
+}
+
+// Endpoint function calling [Provider.Status]
+func (s *server) status(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.provider.Status())
+}
+
+// Debug endpoint function calling [Provider.Stats]
+func (s *server) stats(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.provider.Stats())
+}
+
+// The body of a verbose [server.health] response - a [HealthReport] with human-readable latencies (e.g., '1.5ms')
+type healthResponse struct {
+	Identity string                    `json:"identity"`
+	Latency  string                    `json:"latency"`
+	Routes   map[string]healthResponse `json:"routes,omitempty"`
+	Uptime   string                    `json:"uptime"`
+	Version  string                    `json:"version"`
+}
+
+// Creates the [healthResponse] describing the [HealthReport] (and those of its routes)
+func newHealthResponse(hr HealthReport) healthResponse {
+	r := healthResponse{Identity: hr.Identity, Latency: hr.Latency.String(), Uptime: hr.Uptime, Version: hr.Version}
+	if len(hr.Routes) > 0 {
+		r.Routes = map[string]healthResponse{}
+		for rn, rhr := range hr.Routes {
+			r.Routes[rn] = newHealthResponse(rhr)
+		}
+	}
+	return r
+}
+
 // Webhook endpoint function calling [Provider.Health]
+// When the 'verbose' query parameter is set to '1', additionally responds with the [HealthReport] (see [healthResponse])
 func (s *server) health(c echo.Context) error {
-	err := s.provider.Health()
+	hr, err := s.provider.Health(c.Request().Context())
 	if err != nil {
 		return err
 	}
-	return c.NoContent(http.StatusOK)
+	if c.QueryParam("verbose") != "1" {
+		return c.NoContent(http.StatusOK)
+	}
+	return c.JSON(http.StatusOK, newHealthResponse(hr))
+}
+
+// Internal endpoint function serving [Provider.Metrics] for Prometheus to scrape (see [formatPrometheus])
+func (s *server) metrics(c echo.Context) error {
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", formatPrometheus(s.provider.Metrics()))
 }
 
 // Webhook endpoint function calling [Provider.Records]
+// Sets an 'ETag' header derived from the record set.
+// Responds with 304 (and no body) if the request's 'If-None-Match' header matches the current 'ETag'.
 func (s *server) records(c echo.Context) error {
 	rw, err := s.getResponseWriter(c)
 	if err != nil {
 		return err
 	}
-	rs, err := s.provider.Records(context.Background())
+	rs, err := s.provider.Records(c.Request().Context())
 	if err != nil {
 		return err
 	}
+	et, err := s.getETag(rs)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set("ETag", et)
+	if c.Request().Header.Get("If-None-Match") == et {
+		return c.NoContent(http.StatusNotModified)
+	}
 	return rw(http.StatusOK, rs)
 }
 
-// Webhook endpoint function calling [Provider.GetDomainFilter]
-func (s *server) getDomainFilter(c echo.Context) error {
+// Endpoint function searching the records returned by [Provider.Records] - responding with matching records (including their labels).
+// Records are filtered (see [RecordFilter]) by the following (optional) query parameters:
+//   - 'name': a dns name or glob (e.g., '*.example.com')
+//   - 'type': a record type
+//   - 'label': a label key ('key') or key and value ('key=value') - can be used multiple times
+//
+// Responds with 400 if a query parameter is invalid.
+func (s *server) searchRecords(c echo.Context) error {
+	f := RecordFilter{Labels: c.QueryParams()["label"], Name: c.QueryParam("name"), Type: c.QueryParam("type")}
+	err := f.validate()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	rs, err := s.provider.Records(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	mrs := []*endpoint.Endpoint{}
+	for _, r := range rs {
+		if f.Match(r) {
+			mrs = append(mrs, r)
+		}
+	}
+	return c.JSON(http.StatusOK, mrs)
+}
+
+// Computes a (strong) ETag for the given data by hashing its json representation
+func (s *server) getETag(data interface{}) (string, error) {
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("\"%x\"", sha256.Sum256(bs)), nil
+}
+
+// Webhook negotiation endpoint function calling [Provider.Capabilities].
+// The response is a superset of the domain filter expected by external-dns (see [Capabilities]).
+func (s *server) negotiate(c echo.Context) error {
 	rw, err := s.getResponseWriter(c)
 	if err != nil {
 		return err
 	}
-	df := s.provider.GetDomainFilter()
-	return rw(http.StatusOK, df)
+	cs := s.provider.Capabilities()
+	return rw(http.StatusOK, cs)
+}
+
+// Middleware that assigns a request id to each webhook request.
+// Re-uses the 'X-Request-Id' header if provided, otherwise generates a new id.
+// The id is echoed in the response and attached to the request's [context.Context] via [ContextRequestId].
+func (s *server) requestId(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		id := r.Header.Get(echo.HeaderXRequestID)
+		if id == "" {
+			b := make([]byte, 16)
+			_, err := rand.Read(b)
+			if err != nil {
+				return err
+			}
+			id = hex.EncodeToString(b)
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		c.SetRequest(r.WithContext(context.WithValue(r.Context(), ContextRequestId{}, id)))
+		return next(c)
+	}
+}
+
+// Middleware that records the routeros api commands executed on behalf of each webhook request (see [CommandStats]).
+// The number of commands, their total duration and the identities of the routeros devices executing them are added to the request's log entry.
+func (s *server) commandStats(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cs := &CommandStats{}
+		r := c.Request()
+		c.SetRequest(r.WithContext(NewContextWithCommandStats(r.Context(), cs)))
+		err := next(c)
+		slogecho.AddCustomAttributes(c, slog.Uint64("routeros-commands", cs.Count()))
+		slogecho.AddCustomAttributes(c, slog.Duration("routeros-duration", cs.Duration()))
+		slogecho.AddCustomAttributes(c, slog.String("routeros-identities", strings.Join(cs.Routers(), ",")))
+		return err
+	}
+}
+
+// Returns whether the request is served by a [server] in standby mode - see [server.standbyGuard].
+// Only health checks and metrics are served - all other requests (including reads) are refused.
+func isStandbyServed(c echo.Context) bool {
+	if c.Request().Method != http.MethodGet {
+		return false
+	}
+	switch c.Path() {
+	case "/healthz", "/metrics":
+		return true
+	}
+	return false
+}
+
+// Middleware that, when the [server] is in standby mode, responds to all requests other than health checks and metrics with 503 - see [isStandbyServed].
+// Used by standby replicas (e.g., behind leader election) to make their role explicit to external-dns.
+func (s *server) standbyGuard(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.standby && !isStandbyServed(c) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "provider in standby mode")
+		}
+		return next(c)
+	}
+}
+
+// The number of seconds clients are asked to wait (via 'Retry-After') before retrying changes during maintenance mode
+var maintenanceRetryAfter = 60
+
+// Enables or disables maintenance mode.
+// While in maintenance mode, records can be read but changes are refused.
+func (s *server) SetMaintenance(m bool) {
+	s.logger.Info(fmt.Sprintf("maintenance mode: %t", m))
+	s.maintenance.Store(m)
+}
+
+// Toggles maintenance mode - see [server.SetMaintenance]
+func (s *server) ToggleMaintenance() {
+	s.SetMaintenance(!s.maintenance.Load())
+}
+
+// Status of maintenance mode returned by maintenance endpoints
+type maintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Endpoint function returning the maintenance mode status
+func (s *server) getMaintenance(c echo.Context) error {
+	return c.JSON(http.StatusOK, maintenanceStatus{Enabled: s.maintenance.Load()})
+}
+
+// Endpoint function enabling maintenance mode
+func (s *server) enableMaintenance(c echo.Context) error {
+	s.SetMaintenance(true)
+	return s.getMaintenance(c)
+}
+
+// Endpoint function disabling maintenance mode
+func (s *server) disableMaintenance(c echo.Context) error {
+	s.SetMaintenance(false)
+	return s.getMaintenance(c)
+}
+
+// Endpoint function returning the current logging level (see [LogLevel])
+func (s *server) getLogLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.logLevel.Status())
+}
+
+// Endpoint function changing the logging level (see [LogLevel.Set]) using the following query parameters:
+//   - 'level': the logging level ('error' | 'warn' | 'info' | 'debug')
+//   - 'duration': (optional) a duration after which the initial logging level is restored (e.g., '10m')
+//
+// Responds with 400 if a query parameter is invalid.
+func (s *server) setLogLevel(c echo.Context) error {
+	lv := c.QueryParam("level")
+	if lv == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "level unset")
+	}
+	l, err := ParseLogLevel(lv)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	d := time.Duration(0)
+	if ds := c.QueryParam("duration"); ds != "" {
+		d, err = time.ParseDuration(ds)
+		if err != nil || d < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("duration %s invalid", ds))
+		}
+	}
+	s.logger.Info(fmt.Sprintf("log level: %s (duration %s)", lv, d))
+	s.logLevel.Set(l, d)
+	return s.getLogLevel(c)
+}
+
+// Endpoint function restoring the initial logging level (see [LogLevel.Reset])
+func (s *server) resetLogLevel(c echo.Context) error {
+	s.logLevel.Reset()
+	s.logger.Info("log level reset")
+	return s.getLogLevel(c)
 }
 
 // Options provided to [NewServer]
 type ServerOpts struct {
-	Host     string
-	Logger   *slog.Logger
-	Port     uint
-	Provider Provider
+	EnablePprof      bool
+	Host             string
+	InternalHost     string
+	InternalListener net.Listener
+	InternalPort     uint
+	Listener         net.Listener
+	Logger           *slog.Logger
+	LogLevel         *LogLevel
+	OnReady          ReadyCallback
+	Port             uint
+	ProbeFiles       ProbeFileOpts
+	Provider         Provider
+	Standby          bool
+}
+
+// Whether the [ServerOpts] enable the internal listener - see [NewServer]
+func (o *ServerOpts) internalEnabled() bool {
+	return o.InternalPort != 0 || o.InternalListener != nil
+}
+
+// Validates a listener's host - returning an error if it is neither an ip address nor a hostname
+func validateServerHost(h string) error {
+	if h == "" || net.ParseIP(h) != nil {
+		return nil
+	}
+	hh, p, err := net.SplitHostPort(h)
+	if err == nil {
+		return fmt.Errorf("host %s includes port %s (set the port separately, and the host to %s)", h, p, hh)
+	}
+	err = validateDnsName(h)
+	if err != nil {
+		return fmt.Errorf("host %s not an ip address or hostname: %w", h, err)
+	}
+	return nil
 }
 
-// Constructs a [server] using the provided options within [ServerOpts]
+// Validates the [ServerOpts] - returning an error describing every invalid option
+func (o *ServerOpts) validate() error {
+	errs := []error{}
+	if o.Provider == nil {
+		errs = append(errs, fmt.Errorf("provider unset"))
+	}
+	if o.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d out of range (0-65535)", o.Port))
+	}
+	err := validateServerHost(o.Host)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if o.InternalPort > 65535 {
+		errs = append(errs, fmt.Errorf("internal port %d out of range (0-65535)", o.InternalPort))
+	}
+	err = validateServerHost(o.InternalHost)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("internal %w", err))
+	}
+	if o.InternalPort != 0 && o.InternalPort == o.Port {
+		errs = append(errs, fmt.Errorf("internal port %d same as port", o.InternalPort))
+	}
+	if o.EnablePprof && !o.internalEnabled() {
+		errs = append(errs, fmt.Errorf("pprof enabled without an internal port"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("server options invalid: %w", errors.Join(errs...))
+}
+
+// Constructs a [server] using the provided options within [ServerOpts].
+// The server binds its host and port - defaulting to '127.0.0.1:8888'.
+// When a listener is provided (see [ServerOpts.Listener]), the server accepts connections from it instead - e.g., to bind an ephemeral port ('127.0.0.1:0').
+// When an internal port (or listener) is set, the server additionally binds an internal listener (host defaulting to '127.0.0.1') serving health checks, metrics, administrative endpoints, record searches and, if enabled, profiles - which shouldn't be exposed alongside the webhook.
+// Returns an error if the options are invalid (see [ServerOpts.validate]).
 func NewServer(o *ServerOpts) (*server, error) {
+	err := o.validate()
+	if err != nil {
+		return nil, err
+	}
 	l := o.Logger
 	if l == nil {
 		l = slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -164,28 +632,125 @@ func NewServer(o *ServerOpts) (*server, error) {
 	if p == 0 {
 		p = 8888
 	}
-	e := echo.New()
-	e.HideBanner = true
-	e.HidePort = true
+	ih := o.InternalHost
+	if ih == "" {
+		ih = "127.0.0.1"
+	}
 	s := server{
-		echo:     e,
-		host:     h,
-		logger:   l,
-		port:     p,
-		provider: o.Provider,
-	}
-	e.Use(slogecho.New(l))
-	e.GET("/", s.getDomainFilter)
+		host:             h,
+		internalHost:     ih,
+		internalListener: o.InternalListener,
+		internalPort:     o.InternalPort,
+		listener:         o.Listener,
+		logger:           l,
+		logLevel:         o.LogLevel,
+		onReady:          o.OnReady,
+		port:             p,
+		probeFiles:       o.ProbeFiles,
+		provider:         o.Provider,
+		standby:          o.Standby,
+	}
+	e := s.newEcho()
+	s.echo = e
+	e.GET("/", s.negotiate)
 	e.POST("/adjustendpoints", s.adjustEndpoints)
 	e.GET("/healthz", s.health)
+	e.GET("/maintenance", s.getMaintenance)
+	e.POST("/maintenance", s.enableMaintenance)
+	e.DELETE("/maintenance", s.disableMaintenance)
 	e.GET("/records", s.records)
 	e.POST("/records", s.applyChanges)
+	e.POST("/simulate", s.simulate)
+	e.GET("/stats", s.stats)
+	e.GET("/status", s.status)
+	if o.internalEnabled() {
+		ie := s.newEcho()
+		s.internal = ie
+		if o.LogLevel != nil {
+			ie.GET("/admin/log-level", s.getLogLevel)
+			ie.POST("/admin/log-level", s.setLogLevel)
+			ie.DELETE("/admin/log-level", s.resetLogLevel)
+		}
+		ie.POST("/admin/resync", s.resync)
+		ie.GET("/healthz", s.health)
+		ie.GET("/metrics", s.metrics)
+		ie.GET("/records/search", s.searchRecords)
+		if o.EnablePprof {
+			ie.GET("/debug/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+			ie.GET("/debug/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+			ie.GET("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+			ie.GET("/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+			// serves the index and named profiles (e.g., '/debug/pprof/heap')
+			ie.GET("/debug/pprof/*", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+		}
+	}
 	return &s, nil
 }
 
+// Creates an [echo.Echo] using the [server]'s middleware - shared by its webhook and internal listeners
+func (s *server) newEcho() *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Use(s.requestId)
+	e.Use(slogecho.New(s.logger))
+	// registered after the logging middleware - adding attributes before the request is logged
+	e.Use(s.commandStats)
+	e.Use(s.standbyGuard)
+	return e
+}
+
+// Binds the given listener - unless already provided
+func (s *server) listen(ln net.Listener, n string, h string, p uint) (net.Listener, error) {
+	if ln != nil {
+		return ln, nil
+	}
+	a := net.JoinHostPort(h, strconv.FormatUint(uint64(p), 10))
+	s.logger.Info(fmt.Sprintf("starting %s: %s", n, a))
+	return net.Listen("tcp", a)
+}
+
 // Runs the [server] using its internal configuration
+// Binds the listeners (unless provided - see [ServerOpts.Listener]) prior to serving requests so that the configured [ReadyCallback] (if any) is only invoked once connections can be accepted.
+// The bound address (e.g., an ephemeral port) is logged and passed to the [ReadyCallback].
+// Probe files (see [ProbeFileOpts]) are written while the server runs.
+// If the internal listener fails, the server is stopped.
 func (s *server) Run() error {
-	a := fmt.Sprintf("%s:%d", s.host, s.port)
-	s.logger.Info(fmt.Sprintf("starting server: %s", a))
-	return s.echo.Start(a)
+	ln, err := s.listen(s.listener, "server", s.host, s.port)
+	if err != nil {
+		return err
+	}
+	s.echo.Listener = ln
+	if s.internal != nil {
+		iln, err := s.listen(s.internalListener, "internal server", s.internalHost, s.internalPort)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		s.internal.Listener = iln
+		s.logger.Info(fmt.Sprintf("internal server listening: %s", iln.Addr().String()))
+		go func() {
+			err := s.internal.Start(iln.Addr().String())
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Error(fmt.Sprintf("internal server failed: %s", err.Error()))
+				s.echo.Close()
+			}
+		}()
+	}
+	s.logger.Info(fmt.Sprintf("server listening: %s", ln.Addr().String()))
+	if s.onReady != nil {
+		s.onReady(ln.Addr())
+	}
+	stop := s.runProbeFiles()
+	defer stop()
+	return s.echo.Start(ln.Addr().String())
+}
+
+// Stops the [server] (including its internal listener) - causing [server.Run] to return [http.ErrServerClosed]
+func (s *server) Close() error {
+	errs := []error{s.echo.Close()}
+	if s.internal != nil {
+		errs = append(errs, s.internal.Close())
+	}
+	return errors.Join(errs...)
 }
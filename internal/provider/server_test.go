@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{name: "absent", accept: "", expected: "application/json"},
+		{name: "json", accept: "application/json", expected: "application/json"},
+		{name: "webhook", accept: "application/external.dns.webhook+json;version=1", expected: webhookMediaType},
+		{name: "webhook with spaces", accept: "application/external.dns.webhook+json; version=1", expected: webhookMediaType},
+		{name: "webhook with quoted version", accept: `application/external.dns.webhook+json; version="1"`, expected: webhookMediaType},
+		{name: "webhook unsupported version", accept: "application/external.dns.webhook+json;version=2", expected: "application/external.dns.webhook+json;version=2"},
+		{name: "json with charset", accept: "application/json; charset=utf-8", expected: "application/json"},
+		{name: "any", accept: "*/*", expected: "application/json"},
+		{name: "application wildcard", accept: "application/*", expected: "application/json"},
+		{name: "first listed", accept: "application/external.dns.webhook+json;version=1, application/json", expected: webhookMediaType},
+		{name: "unrecognized skipped", accept: "text/html, application/json", expected: "application/json"},
+		{name: "higher quality", accept: "application/json;q=0.5, application/external.dns.webhook+json;version=1", expected: webhookMediaType},
+		{name: "lower quality", accept: "application/external.dns.webhook+json;version=1;q=0.1, application/json;q=0.9", expected: "application/json"},
+		{name: "quality with spaces", accept: "application/external.dns.webhook+json; version=1; q=0.2 , */* ; q=0.8", expected: "application/json"},
+		{name: "refused", accept: "application/json;q=0", expected: "application/json;q=0"},
+		{name: "invalid quality", accept: "application/json;q=high", expected: "application/json;q=high"},
+		{name: "unrecognized", accept: "text/html", expected: "text/html"},
+		{name: "malformed", accept: "application/json;;", expected: "application/json;;"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := negotiateMediaType(test.accept)
+			if actual != test.expected {
+				t.Errorf("negotiateMediaType(%q) = %q, expected %q", test.accept, actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestAcceptsMediaType(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		expected bool
+	}{
+		{name: "absent", accept: "", expected: false},
+		{name: "listed", accept: mediaTypeApplyResults, expected: true},
+		{name: "listed after others", accept: "application/json, " + mediaTypeApplyResults, expected: true},
+		{name: "with parameters", accept: mediaTypeApplyResults + "; charset=utf-8; q=0.5", expected: true},
+		{name: "refused", accept: mediaTypeApplyResults + ";q=0", expected: false},
+		{name: "invalid quality", accept: mediaTypeApplyResults + ";q=high", expected: false},
+		{name: "wildcard", accept: "*/*", expected: false},
+		{name: "unlisted", accept: "application/json", expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := acceptsMediaType(test.accept, mediaTypeApplyResults)
+			if actual != test.expected {
+				t.Errorf("acceptsMediaType(%q) = %t, expected %t", test.accept, actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestNewHealthResponse(t *testing.T) {
+	hr := HealthReport{Latency: 1500 * time.Microsecond, Routes: map[string]HealthReport{"lab": {Latency: 2 * time.Second}}, Version: "7.16"}
+	r := newHealthResponse(hr)
+	if r.Latency != "1.5ms" || r.Version != "7.16" {
+		t.Errorf("response %+v, expected latency 1.5ms and version 7.16", r)
+	}
+	if r.Routes["lab"].Latency != "2s" {
+		t.Errorf("route lab latency %q, expected 2s", r.Routes["lab"].Latency)
+	}
+}
+
+func TestServerPort(t *testing.T) {
+	p := newStubProvider(t)
+	s, err := NewServer(&ServerOpts{Provider: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.port != 8888 {
+		t.Errorf("port %d, expected the default 8888", s.port)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := make(chan net.Addr, 1)
+	s, err = NewServer(&ServerOpts{Listener: ln, OnReady: func(a net.Addr) { ready <- a }, Provider: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := make(chan error, 1)
+	go func() { ec <- s.Run() }()
+	defer func() {
+		s.Close()
+		<-ec
+	}()
+	a := <-ready
+	if a.String() != ln.Addr().String() {
+		t.Errorf("ready address %s, expected the listener's address %s", a, ln.Addr())
+	}
+	rsp, err := http.Get("http://" + a.String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Errorf("status %d, expected %d", rsp.StatusCode, http.StatusOK)
+	}
+}
+
+// Runs a server (with the given options) on ephemeral webhook and internal listeners until the test completes.
+// Returns the address of each listener - keyed by 'webhook' and 'internal'.
+func runTestServer(t *testing.T, o *ServerOpts) map[string]string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	iln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := make(chan net.Addr, 1)
+	o.InternalListener = iln
+	o.Listener = ln
+	o.OnReady = func(a net.Addr) { ready <- a }
+	s, err := NewServer(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := make(chan error, 1)
+	go func() { ec <- s.Run() }()
+	t.Cleanup(func() {
+		s.Close()
+		<-ec
+	})
+	<-ready
+	return map[string]string{"webhook": ln.Addr().String(), "internal": iln.Addr().String()}
+}
+
+func TestServerInternal(t *testing.T) {
+	p := newStubProvider(t)
+	_, err := NewServer(&ServerOpts{EnablePprof: true, Provider: p})
+	if err == nil {
+		t.Error("pprof enabled without an internal port, expected an error")
+	}
+
+	as := runTestServer(t, &ServerOpts{EnablePprof: true, LogLevel: NewLogLevel(slog.LevelInfo), Provider: p})
+	tests := []struct {
+		listener string
+		path     string
+		expected int
+	}{
+		{listener: "webhook", path: "/admin/log-level", expected: http.StatusNotFound},
+		{listener: "webhook", path: "/debug/pprof/cmdline", expected: http.StatusNotFound},
+		{listener: "internal", path: "/admin/log-level", expected: http.StatusOK},
+		{listener: "internal", path: "/debug/pprof/cmdline", expected: http.StatusOK},
+		{listener: "internal", path: "/healthz", expected: http.StatusOK},
+		{listener: "webhook", path: "/metrics", expected: http.StatusNotFound},
+		{listener: "internal", path: "/metrics", expected: http.StatusOK},
+		{listener: "internal", path: "/records", expected: http.StatusNotFound},
+		{listener: "webhook", path: "/records/search", expected: http.StatusNotFound},
+		{listener: "internal", path: "/records/search", expected: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.listener+test.path, func(t *testing.T) {
+			rsp, err := http.Get("http://" + as[test.listener] + test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp.Body.Close()
+			if rsp.StatusCode != test.expected {
+				t.Errorf("status %d, expected %d", rsp.StatusCode, test.expected)
+			}
+		})
+	}
+}
+
+func TestServerStandby(t *testing.T) {
+	p := newStubProvider(t)
+	as := runTestServer(t, &ServerOpts{EnablePprof: true, LogLevel: NewLogLevel(slog.LevelInfo), Provider: p, Standby: true})
+
+	tests := []struct {
+		listener string
+		method   string
+		path     string
+		expected int
+	}{
+		{listener: "webhook", method: http.MethodGet, path: "/", expected: http.StatusServiceUnavailable},
+		{listener: "webhook", method: http.MethodGet, path: "/healthz", expected: http.StatusOK},
+		{listener: "webhook", method: http.MethodPost, path: "/maintenance", expected: http.StatusServiceUnavailable},
+		{listener: "webhook", method: http.MethodGet, path: "/records", expected: http.StatusServiceUnavailable},
+		{listener: "webhook", method: http.MethodPost, path: "/records", expected: http.StatusServiceUnavailable},
+		{listener: "webhook", method: http.MethodGet, path: "/stats", expected: http.StatusServiceUnavailable},
+		{listener: "webhook", method: http.MethodGet, path: "/status", expected: http.StatusServiceUnavailable},
+		{listener: "internal", method: http.MethodGet, path: "/admin/log-level", expected: http.StatusServiceUnavailable},
+		{listener: "internal", method: http.MethodGet, path: "/debug/pprof/cmdline", expected: http.StatusServiceUnavailable},
+		{listener: "internal", method: http.MethodGet, path: "/healthz", expected: http.StatusOK},
+		{listener: "internal", method: http.MethodGet, path: "/metrics", expected: http.StatusOK},
+		{listener: "internal", method: http.MethodGet, path: "/records/search", expected: http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.listener+" "+test.method+" "+test.path, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, "http://"+as[test.listener]+test.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp.Body.Close()
+			if rsp.StatusCode != test.expected {
+				t.Errorf("status %d, expected %d", rsp.StatusCode, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-routeros/routeros/v3"
+	"github.com/go-routeros/routeros/v3/proto"
+)
+
+// An in-memory routeros static dns menu.
+// Serves the commands produced by a [recordBackend] via [stubRouter.run] - a [commandRunner].
+type stubRouter struct {
+	commands int
+	ids      map[string]int
+	mutex    sync.Mutex
+	records  []map[string]string
+}
+
+// Creates a new, empty [stubRouter]
+func newStubRouter() *stubRouter {
+	return &stubRouter{ids: map[string]int{}}
+}
+
+// Returns a copy of the [stubRouter] - allowing benchmarks to reset its records between iterations
+func (sr *stubRouter) clone() *stubRouter {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	c := newStubRouter()
+	for _, r := range sr.records {
+		if r == nil {
+			continue
+		}
+		c.ids[r[".id"]] = len(c.records)
+		c.records = append(c.records, cloneAttributes(r))
+	}
+	return c
+}
+
+// Returns a copy of a record's attributes
+func cloneAttributes(r map[string]string) map[string]string {
+	c := make(map[string]string, len(r))
+	for k, v := range r {
+		c[k] = v
+	}
+	return c
+}
+
+// Parses the attribute words ('=<key>=<value>') of a command - as routeros does
+func parseAttributeWords(ws []string) map[string]string {
+	as := map[string]string{}
+	for _, w := range ws {
+		k, v, _ := strings.Cut(strings.TrimPrefix(w, "="), "=")
+		as[k] = v
+	}
+	return as
+}
+
+// Returns a [routeros.DeviceError] holding the given message
+func newStubDeviceError(m string) error {
+	return &routeros.DeviceError{Sentence: &proto.Sentence{Word: "!trap", Map: map[string]string{"message": m}}}
+}
+
+// Runs a command against the [stubRouter]'s records - implements [commandRunner].
+// Supports the 'add', 'set', 'remove' and 'print' commands of any menu.
+func (sr *stubRouter) run(cmd []string) (*routeros.Reply, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.commands++
+	as := parseAttributeWords(cmd[1:])
+	switch path.Base(cmd[0]) {
+	case "add":
+		id := fmt.Sprintf("*%X", len(sr.records)+1)
+		as[".id"] = id
+		sr.ids[id] = len(sr.records)
+		sr.records = append(sr.records, as)
+		return &routeros.Reply{Done: &proto.Sentence{Word: "!done", Map: map[string]string{"ret": id}}}, nil
+	case "set":
+		i, ok := sr.ids[as[".id"]]
+		if !ok {
+			return nil, newStubDeviceError("no such item")
+		}
+		for k, v := range as {
+			sr.records[i][k] = v
+		}
+		return &routeros.Reply{Done: &proto.Sentence{Word: "!done", Map: map[string]string{}}}, nil
+	case "remove":
+		for _, id := range strings.Split(as[".id"], ",") {
+			i, ok := sr.ids[id]
+			if !ok {
+				return nil, newStubDeviceError("no such item")
+			}
+			delete(sr.ids, id)
+			sr.records[i] = nil
+		}
+		return &routeros.Reply{Done: &proto.Sentence{Word: "!done", Map: map[string]string{}}}, nil
+	case "print":
+		rep := &routeros.Reply{Done: &proto.Sentence{Word: "!done", Map: map[string]string{}}}
+		_, co := as["count-only"]
+		if co {
+			rep.Done.Map["ret"] = fmt.Sprint(len(sr.ids))
+			return rep, nil
+		}
+		for _, r := range sr.records {
+			if r == nil {
+				continue
+			}
+			ks := []string{}
+			for k := range r {
+				ks = append(ks, k)
+			}
+			slices.Sort(ks)
+			s := &proto.Sentence{Word: "!re", Map: cloneAttributes(r)}
+			for _, k := range ks {
+				s.List = append(s.List, proto.Pair{Key: k, Value: r[k]})
+			}
+			rep.Re = append(rep.Re, s)
+		}
+		return rep, nil
+	}
+	return nil, newStubDeviceError("no such command")
+}
+
+// A [recordBackend] whose commands are run by a [stubRouter] rather than the [client]'s connection.
+// Wraps another backend (e.g., [staticBackend]) - exercising its command construction and parsing.
+type stubBackend struct {
+	recordBackend
+	router *stubRouter
+}
+
+func (b *stubBackend) Create(_ commandRunner, v map[string]string) (string, error) {
+	return b.recordBackend.Create(b.router.run, v)
+}
+
+func (b *stubBackend) Delete(_ commandRunner, ids ...string) error {
+	return b.recordBackend.Delete(b.router.run, ids...)
+}
+
+func (b *stubBackend) Update(_ commandRunner, id string, v map[string]string) error {
+	return b.recordBackend.Update(b.router.run, id, v)
+}
+
+func (b *stubBackend) List(_ commandRunner) ([]dnsRecord, error) {
+	return b.recordBackend.List(b.router.run)
+}
+
+func (b *stubBackend) Check(_ commandRunner) error {
+	return b.recordBackend.Check(b.router.run)
+}
+
+// Serves a minimal routeros api on a local listener - accepting any login and answering identity queries.
+// Returns the listener's address.
+func serveStubApi(tb testing.TB) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveStubApiConn(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+// Answers the sentences sent over a connection to the api served by [serveStubApi]
+func serveStubApiConn(conn net.Conn) {
+	defer conn.Close()
+	r := proto.NewReader(conn)
+	w := proto.NewWriter(conn)
+	for {
+		s, err := r.ReadSentence()
+		if err != nil {
+			return
+		}
+		if s.Word == "/system/identity/print" {
+			w.BeginSentence()
+			w.WriteWord("!re")
+			w.WriteWord("=name=stub")
+			if w.EndSentence() != nil {
+				return
+			}
+		}
+		w.BeginSentence()
+		w.WriteWord("!done")
+		if w.EndSentence() != nil {
+			return
+		}
+	}
+}
+
+// Creates a [client] connecting to the api served by [serveStubApi] - whose records are held by the given [stubRouter] (see [stubBackend])
+func newStubClient(tb testing.TB, o ClientOpts, sr *stubRouter) *client {
+	o.Address = serveStubApi(tb)
+	c, err := NewClient(&o)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	c.backend = &stubBackend{recordBackend: c.backend, router: sr}
+	return c
+}
+
+// Creates a [provider] whose client (see [newStubClient]) holds its records within a new [stubRouter]
+func newStubProvider(tb testing.TB) *provider {
+	p, err := NewProvider(&ProviderOpts{Client: newStubClient(tb, ClientOpts{}, newStubRouter())})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return p
+}
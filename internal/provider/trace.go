@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// Words of routeros api sentences holding credentials (e.g., those sent by '/login') - their values are redacted when traced
+var traceRedactedWords = []string{"=password=", "=response="}
+
+// A connection to routeros that logs the raw api sentences sent and received (see [ClientOpts.Trace]).
+// Sentences are decoded from copies of the bytes written to and read from the connection - the connection itself is unaffected.
+type traceConn struct {
+	net.Conn
+	rpw *io.PipeWriter
+	wpw *io.PipeWriter
+}
+
+// Wraps the connection in a [traceConn] logging sentences using the given logger
+func newTraceConn(c net.Conn, l *slog.Logger) *traceConn {
+	rpr, rpw := io.Pipe()
+	wpr, wpw := io.Pipe()
+	go traceSentences(rpr, l, "received")
+	go traceSentences(wpr, l, "sent")
+	return &traceConn{Conn: c, rpw: rpw, wpw: wpw}
+}
+
+// Reads from the connection - tracing the bytes read
+func (tc *traceConn) Read(b []byte) (int, error) {
+	n, err := tc.Conn.Read(b)
+	if n > 0 {
+		tc.rpw.Write(b[:n])
+	}
+	return n, err
+}
+
+// Writes to the connection - tracing the bytes written
+func (tc *traceConn) Write(b []byte) (int, error) {
+	n, err := tc.Conn.Write(b)
+	if n > 0 {
+		tc.wpw.Write(b[:n])
+	}
+	return n, err
+}
+
+// Closes the connection - stopping its tracing
+func (tc *traceConn) Close() error {
+	tc.rpw.Close()
+	tc.wpw.Close()
+	return tc.Conn.Close()
+}
+
+// Decodes the sentences within the stream of routeros api bytes - logging each sentence until the stream ends.
+// Should decoding fail, the rest of the stream is discarded (so that writers to the stream never block).
+func traceSentences(r io.Reader, l *slog.Logger, dir string) {
+	br := bufio.NewReader(r)
+	ws := []string{}
+	for {
+		w, err := readTraceWord(br)
+		if err != nil {
+			if err != io.EOF && err != io.ErrClosedPipe {
+				l.Warn(fmt.Sprintf("routeros trace stopped: %s", err.Error()))
+			}
+			io.Copy(io.Discard, br)
+			return
+		}
+		if w != "" {
+			for _, rw := range traceRedactedWords {
+				if strings.HasPrefix(w, rw) {
+					w = rw + "<redacted>"
+				}
+			}
+			ws = append(ws, w)
+			continue
+		}
+		// an empty word ends a sentence
+		l.Info(fmt.Sprintf("routeros sentence %s: %s", dir, strings.Join(ws, " ")))
+		ws = []string{}
+	}
+}
+
+// Reads a length-prefixed routeros api word.
+// See https://help.mikrotik.com/docs/display/ROS/API#API-APIwords.
+func readTraceWord(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	// the high bits of the first byte determine how many bytes follow it
+	var s int
+	var n uint32
+	switch {
+	case b&0x80 == 0x00:
+		n = uint32(b)
+	case b&0xC0 == 0x80:
+		s, n = 1, uint32(b&0x3F)
+	case b&0xE0 == 0xC0:
+		s, n = 2, uint32(b&0x1F)
+	case b&0xF0 == 0xE0:
+		s, n = 3, uint32(b&0x0F)
+	case b == 0xF0:
+		s = 4
+	default:
+		return "", fmt.Errorf("invalid word length byte %#x", b)
+	}
+	bs := make([]byte, 4)
+	_, err = io.ReadFull(r, bs[4-s:])
+	if err != nil {
+		return "", err
+	}
+	n = n<<(8*s) | binary.BigEndian.Uint32(bs)
+	w := make([]byte, n)
+	_, err = io.ReadFull(r, w)
+	if err != nil {
+		return "", err
+	}
+	return string(w), nil
+}
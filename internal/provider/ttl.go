@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Options controlling warnings for unusual record ttls requested by workloads - see [provider.checkTtl]
+type TtlOpts struct {
+	// Ttls above this duration are warned about (e.g., as they keep stale records cached for long) - disabled when zero
+	High time.Duration
+	// Ttls below this duration are warned about (e.g., as they stress the router's resolver) - disabled when zero
+	Low time.Duration
+}
+
+// Validates the [TtlOpts] - returning an error if the thresholds are invalid
+func (o TtlOpts) validate() error {
+	if o.Low != 0 && o.High != 0 && o.Low > o.High {
+		return fmt.Errorf("low ttl threshold %s above high ttl threshold %s", o.Low, o.High)
+	}
+	return nil
+}
+
+// Warns (and records a kubernetes event) if the given endpoint - about to be created or updated - requests a ttl outside of the configured thresholds (see [TtlOpts]).
+// Endpoints without a ttl use the router's default ttl and are never warned about.
+// Counts warnings - see [provider.TtlLowCount], [provider.TtlHighCount].
+func (p *provider) checkTtl(l *slog.Logger, e *endpoint.Endpoint) {
+	if !e.RecordTTL.IsConfigured() {
+		return
+	}
+	t := time.Duration(e.RecordTTL) * time.Second
+	m := ""
+	r := ""
+	if p.ttl.Low != 0 && t < p.ttl.Low {
+		m = fmt.Sprintf("ttl %s below %s", t, p.ttl.Low)
+		r = "EndpointTtlLow"
+		p.ttlLowCount.Add(1)
+	} else if p.ttl.High != 0 && t > p.ttl.High {
+		m = fmt.Sprintf("ttl %s above %s", t, p.ttl.High)
+		r = "EndpointTtlHigh"
+		p.ttlHighCount.Add(1)
+	} else {
+		return
+	}
+	l.Warn(fmt.Sprintf("record %s %s (resource %s): %s", e.RecordType, e.DNSName, e.Labels[endpoint.ResourceLabelKey], m))
+	if p.eventRecorder != nil {
+		p.eventRecorder.Record(e, EventTypeWarning, r, fmt.Sprintf("routeros dns record %s %s requests unusual ttl: %s", e.RecordType, e.DNSName, m))
+	}
+}
+
+// Returns the number of created or updated records requesting a ttl below the low threshold (see [TtlOpts.Low])
+func (p *provider) TtlLowCount() uint64 {
+	return p.ttlLowCount.Load()
+}
+
+// Returns the number of created or updated records requesting a ttl above the high threshold (see [TtlOpts.High])
+func (p *provider) TtlHighCount() uint64 {
+	return p.ttlHighCount.Load()
+}
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Returned when an endpoint target is invalid for the endpoint's record type - see [validateTarget]
+type InvalidTargetError struct {
+	RecordType string
+	Target     string
+	Reason     string
+}
+
+func (e InvalidTargetError) Error() string {
+	return fmt.Sprintf("invalid %s target %q: %s", e.RecordType, e.Target, e.Reason)
+}
+
+// Endpoint dns names are normalized (see [normalizeEndpoint]) - names are only validated when creating records (the default)
+const NamePolicyPermissive = "permissive"
+
+// Endpoints whose dns names are invalid (see [validateStrictDnsName]) are dropped - preventing their records from being created
+const NamePolicyStrict = "strict"
+
+// Known name policies - see [ProviderOpts.NamePolicy]
+var namePolicies = []string{NamePolicyPermissive, NamePolicyStrict}
+
+// Returned when an endpoint's dns name is refused by the strict name policy - see [validateStrictDnsName]
+type InvalidDnsNameError struct {
+	DNSName    string
+	RecordType string
+	Reason     string
+}
+
+func (e InvalidDnsNameError) Error() string {
+	return fmt.Sprintf("invalid %s dns name %q: %s", e.RecordType, e.DNSName, e.Reason)
+}
+
+// Record types whose names may contain underscores under the strict name policy - e.g., service records and records delegating acme challenges or dkim keys
+var underscoreRecordTypes = []string{"CNAME", "SRV", "TXT"}
+
+// Validates an endpoint's dns name under the strict name policy (see [NamePolicyStrict]).
+// Names must be lowercase and syntactically valid (see [validateDnsName]) - a leading '*' label is permitted.
+// Underscores are only permitted within the names of CNAME, SRV and TXT records (e.g., '_sip._tcp.example.com', '_acme-challenge.example.com', 'selector._domainkey.example.com').
+// Returns an [InvalidDnsNameError] describing why the name is invalid.
+func validateStrictDnsName(rt string, n string) error {
+	if n != strings.ToLower(n) {
+		return InvalidDnsNameError{DNSName: n, RecordType: rt, Reason: "not lowercase"}
+	}
+	vn := strings.TrimPrefix(strings.TrimSuffix(n, "."), "*.")
+	err := validateDnsName(vn)
+	if err != nil {
+		return InvalidDnsNameError{DNSName: n, RecordType: rt, Reason: err.Error()}
+	}
+	if !slices.Contains(underscoreRecordTypes, rt) && strings.Contains(vn, "_") {
+		return InvalidDnsNameError{DNSName: n, RecordType: rt, Reason: fmt.Sprintf("underscores only permitted within %s names", strings.Join(underscoreRecordTypes, ", "))}
+	}
+	return nil
+}
+
+// Validates each of an endpoint's targets - see [validateTarget]
+func validateEndpoint(e *endpoint.Endpoint) error {
+	for _, t := range e.Targets {
+		err := validateTarget(e.RecordType, t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Describes an invalid field within a webhook request body - see [validateChanges]
+type FieldError struct {
+	// The json path of the field (e.g., 'Create[0].dnsName')
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// Validates the endpoints within a webhook request's changes - returning a [FieldError] per invalid field.
+// Endpoints must have a dns name, a non-negative ttl and non-empty targets.
+// Created endpoints must additionally list at least one target - deleted endpoints without targets select all of their records (see [client.DeleteEndpoint]).
+func validateChanges(ch *plan.Changes) []FieldError {
+	fes := []FieldError{}
+	validate := func(f string, es []*endpoint.Endpoint, create bool) {
+		for i, e := range es {
+			p := fmt.Sprintf("%s[%d]", f, i)
+			if e == nil {
+				fes = append(fes, FieldError{Field: p, Reason: "endpoint null"})
+				continue
+			}
+			if strings.TrimSpace(e.DNSName) == "" {
+				fes = append(fes, FieldError{Field: p + ".dnsName", Reason: "dns name empty"})
+			}
+			if e.RecordTTL < 0 {
+				fes = append(fes, FieldError{Field: p + ".recordTTL", Reason: fmt.Sprintf("ttl %d negative", e.RecordTTL)})
+			}
+			if create && len(e.Targets) == 0 {
+				fes = append(fes, FieldError{Field: p + ".targets", Reason: "no targets"})
+			}
+			for j, t := range e.Targets {
+				if strings.TrimSpace(t) == "" {
+					fes = append(fes, FieldError{Field: fmt.Sprintf("%s.targets[%d]", p, j), Reason: "target empty"})
+				}
+			}
+		}
+	}
+	validate("Create", ch.Create, true)
+	validate("UpdateOld", ch.UpdateOld, false)
+	validate("UpdateNew", ch.UpdateNew, true)
+	validate("Delete", ch.Delete, false)
+	return fes
+}
+
+// Validates a target for the given record type prior to sending it to routeros (see [recordType]).
+// Returns an [InvalidTargetError] describing why the target is invalid.
+func validateTarget(rt string, t string) error {
+	rtd, ok := recordTypes[rt]
+	if !ok || rtd.validate == nil {
+		return nil
+	}
+	err := rtd.validate(t)
+	if err != nil {
+		return InvalidTargetError{RecordType: rt, Target: t, Reason: err.Error()}
+	}
+	return nil
+}
+
+// Validates that a value is an integer within [0, 65535]
+func validateUint16(v string) error {
+	_, err := strconv.ParseUint(v, 10, 16)
+	if err != nil {
+		return fmt.Errorf("%q not an integer between 0 and 65535", v)
+	}
+	return nil
+}
+
+// Validates the syntax of a (optionally fully qualified) dns name.
+// Labels may contain letters, digits, hyphens and underscores (e.g., '_sip._tcp') - but may not start or end with a hyphen.
+func validateDnsName(n string) error {
+	n = strings.TrimSuffix(n, ".")
+	if n == "" {
+		return fmt.Errorf("dns name empty")
+	}
+	if len(n) > 253 {
+		return fmt.Errorf("dns name %q longer than 253 characters", n)
+	}
+	for _, l := range strings.Split(n, ".") {
+		if l == "" {
+			return fmt.Errorf("dns name %q has an empty label", n)
+		}
+		if len(l) > 63 {
+			return fmt.Errorf("dns name %q has label %q longer than 63 characters", n, l)
+		}
+		if strings.HasPrefix(l, "-") || strings.HasSuffix(l, "-") {
+			return fmt.Errorf("dns name %q has label %q starting or ending with a hyphen", n, l)
+		}
+		for _, r := range l {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return fmt.Errorf("dns name %q has label %q containing invalid character %q", n, l, r)
+			}
+		}
+	}
+	return nil
+}
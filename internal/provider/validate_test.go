@@ -0,0 +1,33 @@
+package provider
+
+import "testing"
+
+func TestValidateStrictDnsName(t *testing.T) {
+	tests := []struct {
+		recordType string
+		name       string
+		err        bool
+	}{
+		{recordType: "A", name: "host.example.com"},
+		{recordType: "A", name: "*.example.com"},
+		{recordType: "A", name: "host.example.com."},
+		{recordType: "A", name: "Host.example.com", err: true},
+		{recordType: "A", name: "-host.example.com", err: true},
+		{recordType: "A", name: "host_1.example.com", err: true},
+		{recordType: "AAAA", name: "host_1.example.com", err: true},
+		{recordType: "MX", name: "_mail.example.com", err: true},
+		{recordType: "CNAME", name: "_acme-challenge.example.com"},
+		{recordType: "CNAME", name: "selector._domainkey.example.com"},
+		{recordType: "SRV", name: "_sip._tcp.example.com"},
+		{recordType: "TXT", name: "_dmarc.example.com"},
+		{recordType: "TXT", name: "_dmarc..example.com", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.recordType+" "+test.name, func(t *testing.T) {
+			err := validateStrictDnsName(test.recordType, test.name)
+			if test.err != (err != nil) {
+				t.Errorf("validateStrictDnsName(%q, %q) = %v, expected error %t", test.recordType, test.name, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Checks the [Opts] (overridden by the config file, if set - see [FileConfig]) without connecting to routeros - e.g., before deploying a configuration change.
+// If instances are provided (see [InstanceConfig]), the options of each instance are checked instead.
+// Unlike [New] (which fails on the first problem), every check runs - returning all problems found.
+// Checks include regex compilation, conflicting filters, file readability and interactions between options (e.g., regex filters overriding plain filters).
+func ValidateConfig(o Opts, ics []InstanceConfig) []error {
+	if len(ics) == 0 {
+		return validateOpts(o)
+	}
+	errs := []error{}
+	ns := []string{}
+	ps := map[uint]string{}
+	for _, ic := range ics {
+		switch {
+		case ic.Name == "":
+			errs = append(errs, fmt.Errorf("instance name unset"))
+		case slices.Contains(ns, ic.Name):
+			errs = append(errs, fmt.Errorf("instance %s defined multiple times", ic.Name))
+		}
+		ns = append(ns, ic.Name)
+		io, err := ic.apply(o)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("instance %s invalid: %w", ic.Name, err))
+			continue
+		}
+		for _, p := range serverPorts(io) {
+			pn, ok := ps[p]
+			if ok {
+				errs = append(errs, fmt.Errorf("instances %s and %s share server port %d", pn, ic.Name, p))
+			}
+			ps[p] = ic.Name
+		}
+		for _, err := range validateOpts(io) {
+			errs = append(errs, fmt.Errorf("instance %s: %w", ic.Name, err))
+		}
+	}
+	return errs
+}
+
+// Checks a single set of [Opts] - see [ValidateConfig]
+func validateOpts(o Opts) []error {
+	errs := []error{}
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if o.ConfigFile != "" {
+		fc, _, err := ReadFileConfig(o.ConfigFile)
+		add(err)
+		if err == nil {
+			for n, re := range map[string]string{"filterRegexExclude": fc.FilterRegexExclude, "filterRegexInclude": fc.FilterRegexInclude} {
+				_, err := regexp.Compile(re)
+				if err != nil {
+					add(fmt.Errorf("config file %s %s invalid: %w", o.ConfigFile, n, err))
+				}
+			}
+			fo, err := fc.apply(o)
+			if err == nil {
+				o = fo
+			}
+		}
+	}
+
+	// routes
+	ns := []string{}
+	for _, rc := range o.Routes {
+		switch {
+		case rc.Name == "":
+			add(fmt.Errorf("route name unset"))
+		case rc.Name == "default":
+			add(fmt.Errorf("route name %s reserved", rc.Name))
+		case slices.Contains(ns, rc.Name):
+			add(fmt.Errorf("route %s defined multiple times", rc.Name))
+		}
+		ns = append(ns, rc.Name)
+	}
+
+	// filters
+	errs = append(errs, validateFilters("", o.FilterInclude, o.FilterExclude, o.FilterRegexInclude != nil || o.FilterRegexExclude != nil)...)
+	for _, rc := range o.Routes {
+		p := fmt.Sprintf("route %s: ", rc.Name)
+		errs = append(errs, validateFilters(p, rc.FilterInclude, rc.FilterExclude, rc.FilterRegexInclude != "" || rc.FilterRegexExclude != "")...)
+		for _, re := range []string{rc.FilterRegexExclude, rc.FilterRegexInclude} {
+			_, err := regexp.Compile(re)
+			if err != nil {
+				add(fmt.Errorf("%sfilter regex %s invalid: %w", p, re, err))
+			}
+		}
+	}
+
+	// routeros
+	errs = append(errs, validateRouterSettings("", o.RouterOSAddress, o.RouterOSFallbackIP, o.RouterOSCertFingerprint)...)
+	for _, rc := range o.Routes {
+		errs = append(errs, validateRouterSettings(fmt.Sprintf("route %s: ", rc.Name), rc.RouterOSAddress, rc.RouterOSFallbackIP, rc.RouterOSCertFingerprint)...)
+	}
+	_, err := newResolver(o.RouterOSResolver)
+	add(err)
+	if o.Backend != "" {
+		_, ok := recordBackends[o.Backend]
+		if !ok {
+			add(fmt.Errorf("unrecognized backend %s", o.Backend))
+		}
+	}
+	ms := o.MetadataStore
+	if ms == "" {
+		ms = MetadataStoreComment
+	}
+	if ms != MetadataStoreComment && ms != MetadataStoreTxt {
+		add(fmt.Errorf("unrecognized metadata store %s", ms))
+	}
+	if o.ConflictPolicy != "" && !slices.Contains(conflictPolicies, o.ConflictPolicy) {
+		add(fmt.Errorf("unrecognized conflict policy %s", o.ConflictPolicy))
+	}
+	if ms != MetadataStoreComment {
+		if o.AdoptExisting {
+			add(fmt.Errorf("adopting existing records requires metadata store %s", MetadataStoreComment))
+		}
+		if o.ConflictPolicy != "" && o.ConflictPolicy != ConflictPolicyIgnore {
+			add(fmt.Errorf("conflict policy %s requires metadata store %s", o.ConflictPolicy, MetadataStoreComment))
+		}
+		if o.TxtMaxLength > 0 {
+			add(fmt.Errorf("txt max length requires metadata store %s", MetadataStoreComment))
+		}
+	}
+
+	// provider
+	if o.NamePolicy != "" && !slices.Contains(namePolicies, o.NamePolicy) {
+		add(fmt.Errorf("unrecognized name policy %s", o.NamePolicy))
+	}
+	for _, n := range o.ProtectedNames {
+		err := validateDnsName(normalizeDnsName(n))
+		if err != nil {
+			add(fmt.Errorf("protected name %s invalid: %w", n, err))
+		}
+	}
+	add(TtlOpts{High: o.TtlHigh, Low: o.TtlLow}.validate())
+	if o.QuotaLabelMax > 0 && o.QuotaLabel == "" {
+		add(fmt.Errorf("quota label max set without a quota label - the quota is ignored"))
+	}
+	if o.VerifyAddress != "" {
+		_, _, err := net.SplitHostPort(o.VerifyAddress)
+		if err != nil {
+			add(fmt.Errorf("verify address %s not <host>:<port> format: %w", o.VerifyAddress, err))
+		}
+	}
+
+	// server
+	if o.EnablePprof && o.InternalServerPort == 0 {
+		add(fmt.Errorf("pprof enabled without an internal server port"))
+	}
+
+	// metrics
+	if o.MetricsStatsdAddress != "" {
+		_, _, err := net.SplitHostPort(o.MetricsStatsdAddress)
+		if err != nil {
+			add(fmt.Errorf("statsd address %s not <host>:<port> format: %w", o.MetricsStatsdAddress, err))
+		}
+	}
+	if o.MetricsOtlpEndpoint != "" {
+		u, err := url.Parse(o.MetricsOtlpEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add(fmt.Errorf("otlp endpoint %s not an http(s) url", o.MetricsOtlpEndpoint))
+		}
+		_, err = newOtlpExporter(o.MetricsOtlpEndpoint, o.MetricsOtlpProxy)
+		if err != nil {
+			add(err)
+		}
+	}
+
+	// files written by the provider
+	for n, p := range map[string]string{"heartbeat file": o.HeartbeatFile, "intent log": o.IntentLog, "ready file": o.ReadyFile} {
+		if p == "" {
+			continue
+		}
+		d := filepath.Dir(p)
+		st, err := os.Stat(d)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && !st.IsDir()) {
+			add(fmt.Errorf("%s %s directory %s missing", n, p, d))
+		} else if err != nil {
+			add(fmt.Errorf("%s %s directory %s unreadable: %w", n, p, d, err))
+		}
+	}
+
+	slices.SortStableFunc(errs, func(a error, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+	return errs
+}
+
+// Checks domain filters - returning the conflicts found.
+// Plain filters are ignored when regex filters are set (see [Opts.domainFilter]) - and names both included and excluded are ambiguous.
+func validateFilters(p string, is []string, es []string, re bool) []error {
+	errs := []error{}
+	if re && (len(is) > 0 || len(es) > 0) {
+		errs = append(errs, fmt.Errorf("%sregex filters take precedence - plain filters %s are ignored", p, append(slices.Clone(is), es...)))
+	}
+	for _, i := range is {
+		if slices.Contains(es, i) {
+			errs = append(errs, fmt.Errorf("%sfilter %s both included and excluded", p, i))
+		}
+	}
+	return errs
+}
+
+// Checks the settings used to connect to a routeros device - returning the problems found
+func validateRouterSettings(p string, a string, fip string, cfp string) []error {
+	errs := []error{}
+	if a == "" {
+		errs = append(errs, fmt.Errorf("%srouteros address unset", p))
+	} else {
+		_, _, err := parseRouterAddress(a)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%w", p, err))
+		}
+	}
+	if fip != "" && net.ParseIP(fip) == nil {
+		errs = append(errs, fmt.Errorf("%sfallback ip %s not an ip address", p, fip))
+	}
+	if cfp != "" {
+		_, err := parseCertFingerprint(cfp)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%w", p, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Options controlling verification that created records resolve via routeros' dns server after a sync (see [verifier])
+type VerifyOpts struct {
+	// The dns server (<host>:<port>) queried
+	Address string
+	// How long verification is retried before records are considered unverified - verification is disabled when 0
+	Window time.Duration
+}
+
+// The interval between verification attempts
+const verifyInterval = time.Second
+
+// The timeout of a single verification query
+const verifyTimeout = 5 * time.Second
+
+// Verifies that records resolve as expected by querying a dns server.
+// Only A, AAAA, MX, NS and TXT records are verified - other record types are assumed verified.
+type verifier struct {
+	logger   *slog.Logger
+	resolver *net.Resolver
+	window   time.Duration
+}
+
+// Creates a new [verifier] querying the dns server within [VerifyOpts]
+func newVerifier(o VerifyOpts, l *slog.Logger) *verifier {
+	d := net.Dialer{}
+	return &verifier{
+		logger: l,
+		resolver: &net.Resolver{
+			PreferGo: true,
+			Dial: func(c context.Context, nw string, _ string) (net.Conn, error) {
+				return d.DialContext(c, nw, o.Address)
+			},
+		},
+		window: o.Window,
+	}
+}
+
+// Checks that the endpoint's targets are all resolved.
+// Returns an error if the query fails or if a target is not resolved.
+func (v *verifier) check(c context.Context, e *endpoint.Endpoint) error {
+	// queries for fully-qualified names skip resolv.conf search domains
+	n := fmt.Sprintf("%s.", normalizeDnsName(e.DNSName))
+	rts := []string{}
+	switch e.RecordType {
+	case "A", "AAAA":
+		ips, err := v.resolver.LookupIP(c, "ip", n)
+		if err != nil {
+			return err
+		}
+		for _, ip := range ips {
+			rts = append(rts, ip.String())
+		}
+	case "MX":
+		mxs, err := v.resolver.LookupMX(c, n)
+		if err != nil {
+			return err
+		}
+		for _, mx := range mxs {
+			rts = append(rts, fmt.Sprintf("%d %s", mx.Pref, normalizeDnsName(mx.Host)))
+		}
+	case "NS":
+		nss, err := v.resolver.LookupNS(c, n)
+		if err != nil {
+			return err
+		}
+		for _, ns := range nss {
+			rts = append(rts, normalizeDnsName(ns.Host))
+		}
+	case "TXT":
+		txts, err := v.resolver.LookupTXT(c, n)
+		if err != nil {
+			return err
+		}
+		rts = txts
+	default:
+		return nil
+	}
+	ts := []string{}
+	for _, t := range e.Targets {
+		if !slices.Contains(rts, normalizeTarget(e.RecordType, t)) {
+			ts = append(ts, t)
+		}
+	}
+	if len(ts) != 0 {
+		return fmt.Errorf("targets %s not resolved (resolved: %s)", strings.Join(ts, ","), strings.Join(rts, ","))
+	}
+	return nil
+}
+
+// Checks the given endpoints (see [verifier.check]) - retrying failing endpoints until the verification window elapses.
+// Returns the endpoints that failed verification alongside their most recent failure.
+func (v *verifier) Verify(es []*endpoint.Endpoint) map[*endpoint.Endpoint]error {
+	d := time.Now().Add(v.window)
+	fs := map[*endpoint.Endpoint]error{}
+	for _, e := range es {
+		fs[e] = nil
+	}
+	for {
+		for e := range fs {
+			c, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+			err := v.check(c, e)
+			cancel()
+			if err != nil {
+				v.logger.Debug(fmt.Sprintf("record %s %s not yet verified: %s", e.RecordType, e.DNSName, err.Error()))
+				fs[e] = err
+				continue
+			}
+			delete(fs, e)
+		}
+		if len(fs) == 0 || time.Now().After(d) {
+			return fs
+		}
+		time.Sleep(verifyInterval)
+	}
+}
@@ -0,0 +1,173 @@
+// Package routeros exposes the RouterOS provider for use as a library - e.g., within custom controllers embedding external-dns.
+//
+// The provider returned by [NewProvider] satisfies [sigs.k8s.io/external-dns/provider.Provider] and can be used wherever external-dns expects an in-tree provider.
+//
+// # Compatibility
+//
+// The exported API of this package follows semantic versioning alongside the webhook's releases: once the project reaches v1, exported identifiers are only removed or changed incompatibly within a new major version.
+// Prior to v1, incompatible changes may occur within minor versions and are noted within release notes.
+// Packages beneath `internal` (and the behavior of methods beyond those documented here) are not covered by these guarantees.
+package routeros
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/benfiola/external-dns-routeros-provider/internal/provider"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// A RouterOS provider - extending [sigs.k8s.io/external-dns/provider.Provider] with health checks, router info, change simulation and sync status
+type Provider = provider.Provider
+
+// Details describing the connected routeros device - see [Provider.Info]
+type RouterInfo = provider.RouterInfo
+
+// The outcome of a health check - see [Provider.Health]
+type HealthReport = provider.HealthReport
+
+// The outcome of the most recent health check - see [Provider.HealthStatus]
+type HealthStatus = provider.HealthStatus
+
+// The outcome of a simulated sync - see [Provider.Simulate]
+type SimulateResult = provider.SimulateResult
+
+// The outcome of the most recent sync - see [Provider.Status]
+type ApplyStatus = provider.ApplyStatus
+
+// The domain filter, record types and provider-specific properties supported by a provider - see [Provider.Capabilities]
+type Capabilities = provider.Capabilities
+
+// The load a provider has imposed on a router - see [Provider.Stats]
+type ClientStats = provider.ClientStats
+
+// A point-in-time measurement reported by a provider - see [Provider.Metrics]
+type Metric = provider.Metric
+
+// Errors returned by a provider - matched via [errors.Is]
+var (
+	// Matches errors describing routeros records not managed by external-dns
+	ErrNotManaged = provider.ErrNotManaged
+	// Matches errors describing record types unsupported by the provider
+	ErrUnsupportedType = provider.ErrUnsupportedType
+)
+
+// Returned when creating a record conflicts with an unmanaged record - matched via [errors.As]
+type ConflictingRecordError = provider.ConflictingRecordError
+
+// Returned when an endpoint target is invalid for the endpoint's record type - matched via [errors.As]
+type InvalidTargetError = provider.InvalidTargetError
+
+// Returned when the routeros menu holding dns records is unavailable - matched via [errors.As]
+type MenuUnavailableError = provider.MenuUnavailableError
+
+// Returned when the routeros address doesn't serve the routeros api (e.g., when pointed at winbox) - matched via [errors.As]
+type ProtocolMismatchError = provider.ProtocolMismatchError
+
+// Returned when a record's metadata exceeds the length of a routeros comment - matched via [errors.As]
+type RecordCommentTooLongError = provider.RecordCommentTooLongError
+
+// The record metadata stores supported by [Opts.MetadataStore]
+const (
+	MetadataStoreComment = provider.MetadataStoreComment
+	MetadataStoreTxt     = provider.MetadataStoreTxt
+)
+
+// Options used when constructing a provider via [NewProvider]
+type Opts struct {
+	// Routeros api address (<host>:<port>) - ipv6 hosts are bracketed (e.g., '[fd00::1]:8728')
+	Address string
+	// When set, connects via api-ssl - trusting only the routeros certificate with this sha256 fingerprint
+	CertFingerprint string
+	// Number of record names synced concurrently - defaults to 1
+	Concurrency uint
+	// Restricts the records managed by the provider
+	DomainFilter endpoint.DomainFilter
+	// Used when the address' hostname fails to resolve
+	FallbackIP string
+	// Defaults to discarding all logs
+	Logger *slog.Logger
+	// Routeros api menu holding dns records - defaults to '/ip/dns/static'
+	Menu string
+	// Where record metadata is stored - defaults to [MetadataStoreComment]
+	MetadataStore string
+	// Routeros password
+	Password string
+	// When set, record listings are cached for this duration - defaults to no caching
+	RecordsCache time.Duration
+	// Dns server (<ip>:<port>) resolving the address' hostname - defaults to the system resolver
+	Resolver string
+	// Rolls back all changes of a sync when any of its changes fail
+	RollbackOnFailure bool
+	// Routeros username
+	Username string
+}
+
+// Configures the [Opts] provided to [NewProvider] - a forward-compatible alternative to setting fields of [Opts] directly
+type Option func(o *Opts)
+
+// Caches record listings for the given duration (see [Opts.RecordsCache])
+func WithCache(d time.Duration) Option {
+	return func(o *Opts) {
+		o.RecordsCache = d
+	}
+}
+
+// Logs using the given logger (see [Opts.Logger])
+func WithLogger(l *slog.Logger) Option {
+	return func(o *Opts) {
+		o.Logger = l
+	}
+}
+
+// Connects via api-ssl - trusting only the routeros certificate with the given sha256 fingerprint (see [Opts.CertFingerprint])
+func WithTLS(fp string) Option {
+	return func(o *Opts) {
+		o.CertFingerprint = fp
+	}
+}
+
+// Creates a new [Provider] connecting to the routeros device described by [Opts].
+// Options (see [Option]) are applied to a copy of [Opts] - which may be nil.
+// Returns an error if the options are invalid.
+func NewProvider(o *Opts, fos ...Option) (Provider, error) {
+	co := Opts{}
+	if o != nil {
+		co = *o
+	}
+	for _, fo := range fos {
+		fo(&co)
+	}
+	o = &co
+	l := o.Logger
+	if l == nil {
+		l = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	c, err := provider.NewClient(&provider.ClientOpts{
+		Address:         o.Address,
+		CertFingerprint: o.CertFingerprint,
+		FallbackIP:      o.FallbackIP,
+		Logger:          l.With("name", "client"),
+		Menu:            o.Menu,
+		MetadataStore:   o.MetadataStore,
+		Password:        o.Password,
+		Resolver:        o.Resolver,
+		Username:        o.Username,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p, err := provider.NewProvider(&provider.ProviderOpts{
+		Client:            c,
+		Concurrency:       o.Concurrency,
+		DomainFilter:      o.DomainFilter,
+		Logger:            l.With("name", "provider"),
+		RecordsCache:      o.RecordsCache,
+		RollbackOnFailure: o.RollbackOnFailure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}